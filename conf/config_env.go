@@ -1,6 +1,8 @@
 package conf
 
 import (
+	"time"
+
 	"github.com/caarlos0/env/v6"
 )
 
@@ -18,6 +20,43 @@ type AppConfig struct {
 
 	MSConsumer string `env:"MS_CONSUMER" envDefault:"http://ms-consumer"`
 	FinanBusiness string `env:"FINAN_BUSINESS" envDefault:"http://finan-business"`
+
+	// Elasticsearch, secrets can also be mounted as files through the *_FILE variables
+	ESAddresses        []string      `env:"ES_ADDRESSES" envSeparator:"," envDefault:"http://localhost:9200"`
+	ESUsername         string        `env:"ES_USERNAME"`
+	ESPassword         string        `env:"ES_PASSWORD"`
+	ESPasswordFile     string        `env:"ES_PASSWORD_FILE"`
+	ESAPIKey           string        `env:"ES_API_KEY"`
+	ESAPIKeyFile       string        `env:"ES_API_KEY_FILE"`
+	ESServiceToken     string        `env:"ES_SERVICE_TOKEN"`
+	ESServiceTokenFile string        `env:"ES_SERVICE_TOKEN_FILE"`
+	ESCACert           string        `env:"ES_CA_CERT"`
+	ESClientCert       string        `env:"ES_CLIENT_CERT"`
+	ESClientKey        string        `env:"ES_CLIENT_KEY"`
+	ESCertFingerprint  string        `env:"ES_CERT_FINGERPRINT"`
+	ESSniffOnStart     bool          `env:"ES_SNIFF_ON_START" envDefault:"false"`
+	ESSniffInterval    time.Duration `env:"ES_SNIFF_INTERVAL" envDefault:"0s"`
+	ESIndexPrefix      string        `env:"ES_INDEX_PREFIX"`
+	ESMaxRetries       int           `env:"ES_MAX_RETRIES" envDefault:"3"`     // 0 turns retries off
+	ESMappingCheck     string        `env:"ES_MAPPING_CHECK" envDefault:"warn"` // fail, warn or off
+
+	// Outbox relay, events also go to OUTBOX_HTTP_URL when it is set
+	OutboxPollInterval time.Duration `env:"OUTBOX_POLL_INTERVAL" envDefault:"2s"`
+	OutboxBatchSize    int           `env:"OUTBOX_BATCH_SIZE" envDefault:"20"`
+	OutboxMaxAttempts  int           `env:"OUTBOX_MAX_ATTEMPTS" envDefault:"10"`
+	OutboxHTTPURL      string        `env:"OUTBOX_HTTP_URL"`
+	OutboxHTTPTimeout  time.Duration `env:"OUTBOX_HTTP_TIMEOUT" envDefault:"5s"`
+
+	// Search analytics, searches are buffered and written in batches
+	SearchLogBuffer        int           `env:"SEARCH_LOG_BUFFER" envDefault:"1000"`
+	SearchLogBatchSize     int           `env:"SEARCH_LOG_BATCH_SIZE" envDefault:"100"`
+	SearchLogFlushInterval time.Duration `env:"SEARCH_LOG_FLUSH_INTERVAL" envDefault:"2s"`
+	SearchReportTimeZone   string        `env:"SEARCH_REPORT_TIME_ZONE" envDefault:"UTC"` // IANA name, report days start at its midnight
+
+	// Ranking profiles of the full-text search, RANKING_PROFILES_FILE is a json
+	// array of profiles. Profiles stored through the API override them by name.
+	RankingProfilesFile   string `env:"RANKING_PROFILES_FILE"`
+	RankingDefaultProfile string `env:"RANKING_DEFAULT_PROFILE"`
 }
 
 var config AppConfig
@@ -39,9 +39,18 @@ func main() {
 	utils.LoadMessageError()
 	app := route.NewService()
 	ctx := context.Background()
+	// maintenance commands, e.g. `business es-mapping check`
+	if len(os.Args) > 1 {
+		if err := app.RunCommand(ctx, os.Args[1:]); err != nil {
+			logger.Tag("main").Error(err)
+			os.Exit(1)
+		}
+		return
+	}
 	err := app.Start(ctx)
 	if err != nil {
 		logger.Tag("main").Error(err)
+		os.Exit(1)
 	}
 	os.Clearenv()
 }
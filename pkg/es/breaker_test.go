@@ -0,0 +1,119 @@
+package es
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestBreakerTransport(t *testing.T) {
+	refused := errors.New("connection refused")
+	canceled := fmt.Errorf("request aborted: %w", context.Canceled)
+
+	// every step sends one request after moving the clock by advance; the
+	// cluster answers status, or err when set
+	steps := []struct {
+		name        string
+		advance     time.Duration
+		status      int
+		err         error
+		wantSent    bool
+		wantOthers  bool // whether a concurrent request would be let through
+		wantState   breakerState
+		wantErrOpen bool
+	}{
+		{name: "first failure", status: http.StatusServiceUnavailable, wantSent: true, wantOthers: true, wantState: breakerClosed},
+		{name: "success resets the count", status: http.StatusOK, wantSent: true, wantOthers: true, wantState: breakerClosed},
+		{name: "failure after the reset", status: http.StatusGatewayTimeout, wantSent: true, wantOthers: true, wantState: breakerClosed},
+		{name: "threshold opens", err: refused, wantSent: true, wantOthers: true, wantState: breakerOpen},
+		{name: "open rejects", wantState: breakerOpen, wantErrOpen: true},
+		{name: "still open before the timeout", advance: 9 * time.Second, wantState: breakerOpen, wantErrOpen: true},
+		{name: "failed trial opens again", advance: time.Second, status: http.StatusBadGateway, wantSent: true, wantState: breakerOpen},
+		{name: "timeout restarts on reopen", advance: 5 * time.Second, wantState: breakerOpen, wantErrOpen: true},
+		{name: "canceled trial says nothing", advance: 5 * time.Second, err: canceled, wantSent: true, wantState: breakerHalfOpen},
+		{name: "throttled trial closes", status: http.StatusTooManyRequests, wantSent: true, wantState: breakerClosed},
+		{name: "closed again", status: http.StatusServiceUnavailable, wantSent: true, wantOthers: true, wantState: breakerClosed},
+	}
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	breaker := newCircuitBreaker(2, 10*time.Second)
+	breaker.now = func() time.Time { return now }
+
+	for _, step := range steps {
+		now = now.Add(step.advance)
+
+		sent, others := false, false
+		transport := &breakerTransport{
+			breaker: breaker,
+			next: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+				sent = true
+				others = breaker.allow()
+				if step.err != nil {
+					return nil, step.err
+				}
+				return &http.Response{StatusCode: step.status, Body: io.NopCloser(strings.NewReader(""))}, nil
+			}),
+		}
+		req, _ := http.NewRequest(http.MethodGet, "http://localhost:9200/", nil)
+		res, err := transport.RoundTrip(req)
+		if res != nil {
+			res.Body.Close()
+		}
+
+		if sent != step.wantSent {
+			t.Errorf("%s: sent = %v, want %v", step.name, sent, step.wantSent)
+		}
+		if sent && others != step.wantOthers {
+			t.Errorf("%s: concurrent request allowed = %v, want %v", step.name, others, step.wantOthers)
+		}
+		if got := errors.Is(err, ErrSearchUnavailable); got != step.wantErrOpen {
+			t.Errorf("%s: err = %v, want ErrSearchUnavailable %v", step.name, err, step.wantErrOpen)
+		}
+		if breaker.state != step.wantState {
+			t.Errorf("%s: state = %d, want %d", step.name, breaker.state, step.wantState)
+		}
+	}
+}
+
+func TestClientRetries(t *testing.T) {
+	tests := []struct {
+		name       string
+		maxRetries int
+		wantSent   int32
+	}{
+		{name: "default", maxRetries: 0, wantSent: 1 + defaultTransportRetries},
+		{name: "configured", maxRetries: 1, wantSent: 2},
+		{name: "off", maxRetries: -1, wantSent: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sent int32
+			client := newTestClient(t, Config{MaxRetries: tt.maxRetries}, func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt32(&sent, 1)
+				w.WriteHeader(http.StatusServiceUnavailable)
+				_, _ = w.Write([]byte(`{"error": {"type": "unavailable_shards_exception", "reason": "primary shard is not active"}, "status": 503}`))
+			})
+
+			_, err := client.Search(context.Background(), "", map[string]interface{}{"size": 1})
+			if err == nil {
+				t.Fatal("got no error from a failing cluster")
+			}
+			if got := atomic.LoadInt32(&sent); got != tt.wantSent {
+				t.Errorf("cluster got %d requests, want %d", got, tt.wantSent)
+			}
+		})
+	}
+}
@@ -0,0 +1,300 @@
+package es
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"runtime"
+	"sync"
+	"time"
+
+	"gitlab.com/goxp/cloud0/logger"
+)
+
+const (
+	defaultFlushDocs     = 500
+	defaultFlushBytes    = 5 * 1024 * 1024
+	defaultFlushInterval = time.Second
+	defaultMaxRetries    = 3
+	defaultRetryBackoff  = 200 * time.Millisecond
+)
+
+// BulkIndexer streams documents to the _bulk API in batches
+type BulkIndexer interface {
+	// Add queues a document, it blocks while every worker is busy
+	Add(ctx context.Context, doc BulkDocument) error
+	// Close flushes what is left, waits for the workers and returns the report
+	Close(ctx context.Context) (*BulkReport, error)
+}
+
+type bulkItem struct {
+	id   string
+	body []byte // action line and source line, both newline terminated
+}
+
+type bulkIndexer struct {
+	client *esClient
+	cfg    BulkIndexerConfig
+	queue  chan bulkItem
+	wg     sync.WaitGroup
+
+	mu     sync.Mutex
+	report BulkReport
+}
+
+type bulkResponse struct {
+	Errors bool                          `json:"errors"`
+	Items  []map[string]bulkResponseItem `json:"items"`
+}
+
+type bulkResponseItem struct {
+	ID     string `json:"_id"`
+	Status int    `json:"status"`
+	Error  *struct {
+		Type   string `json:"type"`
+		Reason string `json:"reason"`
+	} `json:"error,omitempty"`
+}
+
+// NewBulkIndexer starts the workers of a bulk indexer writing into cfg.Index
+func (c *esClient) NewBulkIndexer(ctx context.Context, cfg BulkIndexerConfig) (BulkIndexer, error) {
+	if cfg.Index == "" {
+		return nil, fmt.Errorf("bulk indexer: index is required")
+	}
+	cfg.Index = c.index(cfg.Index)
+	if cfg.Workers <= 0 {
+		cfg.Workers = runtime.NumCPU()
+	}
+	if cfg.FlushDocs <= 0 {
+		cfg.FlushDocs = defaultFlushDocs
+	}
+	if cfg.FlushBytes <= 0 {
+		cfg.FlushBytes = defaultFlushBytes
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = defaultFlushInterval
+	}
+	if cfg.MaxRetries < 0 {
+		cfg.MaxRetries = 0
+	} else if cfg.MaxRetries == 0 {
+		cfg.MaxRetries = defaultMaxRetries
+	}
+	if cfg.RetryBackoff <= 0 {
+		cfg.RetryBackoff = defaultRetryBackoff
+	}
+	if cfg.Refresh == "" {
+		cfg.Refresh = "false"
+	}
+
+	bi := &bulkIndexer{
+		client: c,
+		cfg:    cfg,
+		queue:  make(chan bulkItem, cfg.Workers*cfg.FlushDocs),
+	}
+	for w := 0; w < cfg.Workers; w++ {
+		bi.wg.Add(1)
+		go bi.worker(ctx)
+	}
+
+	return bi, nil
+}
+
+func (bi *bulkIndexer) Add(ctx context.Context, doc BulkDocument) error {
+	meta, err := json.Marshal(map[string]interface{}{
+		"index": map[string]interface{}{
+			"_index": bi.cfg.Index,
+			"_id":    doc.ID,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal meta: %w", err)
+	}
+	data, err := json.Marshal(doc.Data)
+	if err != nil {
+		return fmt.Errorf("failed to marshal document: %w", err)
+	}
+
+	body := make([]byte, 0, len(meta)+len(data)+2)
+	body = append(body, meta...)
+	body = append(body, '\n')
+	body = append(body, data...)
+	body = append(body, '\n')
+
+	select {
+	case bi.queue <- bulkItem{id: doc.ID, body: body}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (bi *bulkIndexer) Close(ctx context.Context) (*BulkReport, error) {
+	close(bi.queue)
+
+	done := make(chan struct{})
+	go func() {
+		bi.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	bi.mu.Lock()
+	defer bi.mu.Unlock()
+	report := bi.report
+	return &report, nil
+}
+
+func (bi *bulkIndexer) worker(ctx context.Context) {
+	defer bi.wg.Done()
+
+	ticker := time.NewTicker(bi.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]bulkItem, 0, bi.cfg.FlushDocs)
+	size := 0
+	for {
+		select {
+		case item, ok := <-bi.queue:
+			if !ok {
+				bi.flush(ctx, batch)
+				return
+			}
+			batch = append(batch, item)
+			size += len(item.body)
+			if len(batch) >= bi.cfg.FlushDocs || size >= bi.cfg.FlushBytes {
+				bi.flush(ctx, batch)
+				batch = batch[:0]
+				size = 0
+			}
+		case <-ticker.C:
+			if len(batch) > 0 {
+				bi.flush(ctx, batch)
+				batch = batch[:0]
+				size = 0
+			}
+		}
+	}
+}
+
+// flush sends a batch and retries the items ES throttled with 429
+func (bi *bulkIndexer) flush(ctx context.Context, batch []bulkItem) {
+	if len(batch) == 0 {
+		return
+	}
+	log := logger.WithCtx(ctx, "BulkIndexer.flush")
+
+	pending := batch
+	backoff := bi.cfg.RetryBackoff
+	for attempt := 0; ; attempt++ {
+		retry, err := bi.send(ctx, pending)
+		if err != nil {
+			log.WithError(err).Errorf("bulk request for %d documents failed", len(pending))
+			bi.fail(pending, 0, "bulk_request_error", err.Error())
+			return
+		}
+		if len(retry) == 0 {
+			return
+		}
+		if attempt >= bi.cfg.MaxRetries {
+			bi.fail(retry, http.StatusTooManyRequests, "es_rejected_execution_exception", "retries exhausted")
+			return
+		}
+
+		log.Warnf("%d documents throttled, retrying in %s", len(retry), backoff)
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			bi.fail(retry, http.StatusTooManyRequests, "context_canceled", ctx.Err().Error())
+			return
+		}
+		backoff *= 2
+		pending = retry
+	}
+}
+
+// send performs one _bulk call and returns the items to retry
+func (bi *bulkIndexer) send(ctx context.Context, items []bulkItem) ([]bulkItem, error) {
+	ctx, cancel := withTimeout(ctx, bi.client.cfg.BulkTimeout)
+	defer cancel()
+
+	var buf bytes.Buffer
+	for _, item := range items {
+		buf.Write(item.body)
+	}
+
+	res, err := bi.client.client.Bulk(
+		bytes.NewReader(buf.Bytes()),
+		bi.client.client.Bulk.WithContext(ctx),
+		bi.client.client.Bulk.WithRefresh(bi.cfg.Refresh),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("bulk request failed: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusTooManyRequests {
+		return items, nil
+	}
+	if res.IsError() {
+		return nil, fmt.Errorf("bulk error: %s", res.String())
+	}
+
+	var body bulkResponse
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed to decode bulk response: %w", err)
+	}
+	if len(body.Items) != len(items) {
+		return nil, fmt.Errorf("bulk response has %d items, sent %d", len(body.Items), len(items))
+	}
+
+	var retry []bulkItem
+	var indexed int64
+	var failures []BulkItemFailure
+	for i, entry := range body.Items {
+		// every entry holds exactly one action key, "index" here
+		for _, item := range entry {
+			switch {
+			case item.Status == http.StatusTooManyRequests:
+				retry = append(retry, items[i])
+			case item.Error != nil || item.Status >= 300:
+				failure := BulkItemFailure{ID: items[i].id, Status: item.Status}
+				if item.Error != nil {
+					failure.Type = item.Error.Type
+					failure.Reason = item.Error.Reason
+				}
+				failures = append(failures, failure)
+			default:
+				indexed++
+			}
+		}
+	}
+
+	bi.mu.Lock()
+	bi.report.Indexed += indexed
+	bi.report.Failed += int64(len(failures))
+	bi.report.Failures = append(bi.report.Failures, failures...)
+	bi.mu.Unlock()
+
+	return retry, nil
+}
+
+func (bi *bulkIndexer) fail(items []bulkItem, status int, errType, reason string) {
+	bi.mu.Lock()
+	defer bi.mu.Unlock()
+	for _, item := range items {
+		bi.report.Failures = append(bi.report.Failures, BulkItemFailure{
+			ID:     item.id,
+			Status: status,
+			Type:   errType,
+			Reason: reason,
+		})
+	}
+	bi.report.Failed += int64(len(items))
+}
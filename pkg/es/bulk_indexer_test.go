@@ -0,0 +1,100 @@
+package es
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBulkIndexerRetriesThrottledItems(t *testing.T) {
+	// ok-* are indexed, bad-* are rejected, throttled-* get a 429 on their
+	// first attempt and busy-* on every attempt
+	var mu sync.Mutex
+	sent := map[string]int{}
+	client := newTestClient(t, Config{}, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || !strings.HasSuffix(r.URL.Path, "/_bulk") {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		var items []map[string]interface{}
+		scanner := bufio.NewScanner(r.Body)
+		for scanner.Scan() {
+			var action map[string]struct {
+				ID string `json:"_id"`
+			}
+			if err := json.Unmarshal(scanner.Bytes(), &action); err != nil {
+				t.Errorf("bad action line %q: %v", scanner.Text(), err)
+				return
+			}
+			scanner.Scan() // the document
+			id := action["index"].ID
+			sent[id]++
+
+			item := map[string]interface{}{"_index": "business", "_id": id, "status": http.StatusCreated}
+			switch {
+			case strings.HasPrefix(id, "bad-"):
+				item["status"] = http.StatusBadRequest
+				item["error"] = map[string]string{"type": "mapper_parsing_exception", "reason": "failed to parse field [rating] of " + id}
+			case strings.HasPrefix(id, "busy-"), strings.HasPrefix(id, "throttled-") && sent[id] == 1:
+				item["status"] = http.StatusTooManyRequests
+				item["error"] = map[string]string{"type": "es_rejected_execution_exception", "reason": "rejected execution"}
+			}
+			items = append(items, map[string]interface{}{"index": item})
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"errors": true, "items": items})
+	})
+
+	ctx := context.Background()
+	indexer, err := client.NewBulkIndexer(ctx, BulkIndexerConfig{
+		Index:        "business",
+		Workers:      1,
+		MaxRetries:   2,
+		RetryBackoff: time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("NewBulkIndexer: %v", err)
+	}
+	for _, id := range []string{"ok-1", "throttled-1", "bad-1", "ok-2", "busy-1", "throttled-2", "bad-2"} {
+		if err := indexer.Add(ctx, BulkDocument{ID: id, Data: map[string]string{"name": id}}); err != nil {
+			t.Fatalf("Add %s: %v", id, err)
+		}
+	}
+	report, err := indexer.Close(ctx)
+	if err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	wantSent := map[string]int{
+		"ok-1": 1, "ok-2": 1, "bad-1": 1, "bad-2": 1,
+		"throttled-1": 2, "throttled-2": 2,
+		"busy-1": 3, // the first attempt and MaxRetries retries
+	}
+	if !reflect.DeepEqual(sent, wantSent) {
+		t.Errorf("sent %v, want %v", sent, wantSent)
+	}
+
+	if report.Indexed != 4 || report.Failed != 3 {
+		t.Errorf("indexed %d failed %d, want 4 and 3", report.Indexed, report.Failed)
+	}
+	failures := report.Failures
+	sort.Slice(failures, func(i, j int) bool { return failures[i].ID < failures[j].ID })
+	wantFailures := []BulkItemFailure{
+		{ID: "bad-1", Status: http.StatusBadRequest, Type: "mapper_parsing_exception", Reason: "failed to parse field [rating] of bad-1"},
+		{ID: "bad-2", Status: http.StatusBadRequest, Type: "mapper_parsing_exception", Reason: "failed to parse field [rating] of bad-2"},
+		{ID: "busy-1", Status: http.StatusTooManyRequests, Type: "es_rejected_execution_exception", Reason: "retries exhausted"},
+	}
+	if !reflect.DeepEqual(failures, wantFailures) {
+		t.Errorf("failures %+v, want %+v", failures, wantFailures)
+	}
+}
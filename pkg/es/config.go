@@ -0,0 +1,168 @@
+package es
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// Validate reports every problem of the configuration at once
+func (cfg Config) Validate() error {
+	var problems []string
+
+	if len(cfg.Addresses) == 0 {
+		problems = append(problems, "at least one address is required")
+	}
+	for _, address := range cfg.Addresses {
+		u, err := url.Parse(address)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			problems = append(problems, fmt.Sprintf("address %q must be an http or https url", address))
+		}
+	}
+
+	auth := 0
+	if cfg.Username != "" || cfg.Password != "" {
+		auth++
+		if cfg.Username == "" || cfg.Password == "" {
+			problems = append(problems, "basic auth needs both username and password")
+		}
+	}
+	if cfg.APIKey != "" {
+		auth++
+	}
+	if cfg.ServiceToken != "" {
+		auth++
+	}
+	if auth > 1 {
+		problems = append(problems, "only one of basic auth, api key or service token may be set")
+	}
+
+	if (cfg.ClientCertPath == "") != (cfg.ClientKeyPath == "") {
+		problems = append(problems, "client certificate and key must be set together")
+	}
+	for _, path := range []string{cfg.CACertPath, cfg.ClientCertPath, cfg.ClientKeyPath} {
+		if path == "" {
+			continue
+		}
+		if _, err := os.Stat(path); err != nil {
+			problems = append(problems, fmt.Sprintf("cannot read %s: %v", path, err))
+		}
+	}
+	if cfg.CertificateFingerprint != "" {
+		if fp, err := hex.DecodeString(cfg.CertificateFingerprint); err != nil || len(fp) != sha256.Size {
+			problems = append(problems, "certificate fingerprint must be a hex encoded SHA256 digest")
+		}
+	}
+
+	if cfg.DiscoverNodesInterval < 0 {
+		problems = append(problems, "discover nodes interval can't be negative")
+	}
+	if cfg.IndexPrefix != "" {
+		if cfg.IndexPrefix != strings.ToLower(cfg.IndexPrefix) ||
+			strings.ContainsAny(cfg.IndexPrefix, ` ,"*\/<>|?#:`) ||
+			strings.HasPrefix(cfg.IndexPrefix, "_") || strings.HasPrefix(cfg.IndexPrefix, "-") ||
+			strings.HasPrefix(cfg.IndexPrefix, "+") {
+			problems = append(problems, fmt.Sprintf("index prefix %q is not a valid index name start", cfg.IndexPrefix))
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid elasticsearch config: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// newHTTPTransport builds the transport under the circuit breaker. The
+// elastic transport only applies TLS settings to a bare *http.Transport,
+// so they are set up here.
+func newHTTPTransport(cfg Config) (*http.Transport, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if transport.TLSClientConfig == nil {
+		transport.TLSClientConfig = &tls.Config{}
+	}
+
+	if cfg.CACertPath != "" {
+		pem, err := os.ReadFile(cfg.CACertPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA certificate: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate found in %s", cfg.CACertPath)
+		}
+		transport.TLSClientConfig.RootCAs = pool
+	}
+
+	if cfg.ClientCertPath != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.ClientCertPath, cfg.ClientKeyPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		transport.TLSClientConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	if cfg.CertificateFingerprint != "" {
+		fingerprint, _ := hex.DecodeString(cfg.CertificateFingerprint)
+		tlsConfig := transport.TLSClientConfig.Clone()
+		// the chain is trusted through the pinned fingerprint instead of a CA
+		tlsConfig.InsecureSkipVerify = true
+		transport.DialTLSContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			conf := tlsConfig.Clone()
+			if conf.ServerName == "" {
+				conf.ServerName, _, _ = net.SplitHostPort(addr)
+			}
+			dialer := &tls.Dialer{Config: conf}
+			conn, err := dialer.DialContext(ctx, network, addr)
+			if err != nil {
+				return nil, err
+			}
+			for _, cert := range conn.(*tls.Conn).ConnectionState().PeerCertificates {
+				digest := sha256.Sum256(cert.Raw)
+				if bytes.Equal(digest[:], fingerprint) {
+					return conn, nil
+				}
+			}
+			conn.Close()
+			return nil, errors.New("elasticsearch certificate fingerprint mismatch")
+		}
+	}
+
+	return transport, nil
+}
+
+// index prepends the configured prefix to an index, alias or pattern name,
+// comma separated lists included. Callers always pass unprefixed names, a
+// name that merely starts like the prefix is prefixed too.
+func (c *esClient) index(name string) string {
+	if c.cfg.IndexPrefix == "" || name == "" {
+		return name
+	}
+	parts := strings.Split(name, ",")
+	for i, part := range parts {
+		parts[i] = c.cfg.IndexPrefix + part
+	}
+	return strings.Join(parts, ",")
+}
+
+// trimIndex removes the configured prefix from a name returned by the cluster,
+// so it can be passed back to index
+func (c *esClient) trimIndex(name string) string {
+	return strings.TrimPrefix(name, c.cfg.IndexPrefix)
+}
+
+func (c *esClient) aliasTarget(target *AliasTarget) *AliasTarget {
+	if target == nil {
+		return nil
+	}
+	return &AliasTarget{Index: c.index(target.Index), Alias: c.index(target.Alias)}
+}
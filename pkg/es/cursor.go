@@ -0,0 +1,47 @@
+package es
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+)
+
+// Cursor is the state needed to fetch the next page with search_after
+type Cursor struct {
+	PitID       string            `json:"pit_id"`
+	SearchAfter []json.RawMessage `json:"search_after"`
+	// Fuzzy keeps later pages on the fuzziness the first page used
+	Fuzzy *FuzzySpec `json:"fuzzy,omitempty"`
+	// RankingProfile keeps later pages on the ranking of the first page
+	RankingProfile string `json:"ranking_profile,omitempty"`
+	// Sort keeps later pages on the sort of the first page, search_after
+	// holds one value per sort field
+	Sort SortSpec `json:"sort,omitempty"`
+}
+
+// EncodeCursor turns a cursor into an opaque token for clients
+func EncodeCursor(c Cursor) (string, error) {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal cursor: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// DecodeCursor parses a token produced by EncodeCursor
+func DecodeCursor(token string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor: %w", err)
+	}
+
+	var c Cursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("invalid cursor: %w", err)
+	}
+	if c.PitID == "" || len(c.SearchAfter) == 0 {
+		return nil, fmt.Errorf("invalid cursor: missing pit id or search_after")
+	}
+
+	return &c, nil
+}
@@ -0,0 +1,277 @@
+package es
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Kinds of MappingDiff
+const (
+	DiffMissing    = "missing"    // declared but absent from the live index
+	DiffUndeclared = "undeclared" // present in the live index but not declared
+	DiffChanged    = "changed"    // type or parameter differs
+)
+
+// MappingDiff is one field or setting where a live index and its definition disagree
+type MappingDiff struct {
+	Field   string      `json:"field"`
+	Kind    string      `json:"kind"`
+	Reason  string      `json:"reason"`
+	Desired interface{} `json:"desired,omitempty"`
+	Live    interface{} `json:"live,omitempty"`
+
+	path []string // keys from the mapping properties down to the field
+}
+
+// DiffMapping compares desired mapping properties with live ones. Only the
+// parameters set in desired are compared, defaults the cluster reports
+// back don't count as drift.
+func DiffMapping(desired, live map[string]interface{}) []MappingDiff {
+	var diffs []MappingDiff
+	diffProperties("", nil, desired, live, &diffs)
+	sort.Slice(diffs, func(i, j int) bool { return diffs[i].Field < diffs[j].Field })
+	return diffs
+}
+
+func diffProperties(prefix string, path []string, desired, live map[string]interface{}, diffs *[]MappingDiff) {
+	for name, d := range desired {
+		field := prefix + name
+		fieldPath := append(append([]string{}, path...), name)
+		dp, _ := d.(map[string]interface{})
+		lp, ok := live[name].(map[string]interface{})
+		if !ok {
+			*diffs = append(*diffs, MappingDiff{
+				Field: field, Kind: DiffMissing, Reason: "missing in live mapping", Desired: dp, path: fieldPath,
+			})
+			continue
+		}
+		diffProperty(field, fieldPath, dp, lp, diffs)
+	}
+	for name, l := range live {
+		if _, ok := desired[name]; !ok {
+			*diffs = append(*diffs, MappingDiff{
+				Field: prefix + name, Kind: DiffUndeclared, Reason: "not declared in definition", Live: l,
+			})
+		}
+	}
+}
+
+func diffProperty(field string, path []string, desired, live map[string]interface{}, diffs *[]MappingDiff) {
+	if dt, lt := typeOf(desired), typeOf(live); dt != lt {
+		*diffs = append(*diffs, MappingDiff{
+			Field: field, Kind: DiffChanged, Reason: "type differs", Desired: dt, Live: lt, path: path,
+		})
+		return
+	}
+	for key, d := range desired {
+		switch key {
+		case "type":
+		case "properties", "fields":
+			dm, _ := d.(map[string]interface{})
+			lm, _ := live[key].(map[string]interface{})
+			diffProperties(field+".", append(append([]string{}, path...), key), dm, lm, diffs)
+		default:
+			if fmt.Sprint(d) != fmt.Sprint(live[key]) {
+				*diffs = append(*diffs, MappingDiff{
+					Field: field, Kind: DiffChanged, Reason: key + " differs", Desired: d, Live: live[key], path: path,
+				})
+			}
+		}
+	}
+}
+
+func typeOf(property map[string]interface{}) string {
+	if t, ok := property["type"].(string); ok {
+		return t
+	}
+	return "object"
+}
+
+// diffSettings compares the declared index settings with the live ones,
+// which the cluster returns as strings under "index"
+func diffSettings(prefix string, desired, live interface{}, diffs *[]MappingDiff) {
+	switch d := desired.(type) {
+	case map[string]interface{}:
+		l, _ := live.(map[string]interface{})
+		for key, value := range d {
+			lv, ok := l[key]
+			if !ok {
+				*diffs = append(*diffs, MappingDiff{
+					Field: prefix + key, Kind: DiffMissing, Reason: "missing in live settings", Desired: value,
+				})
+				continue
+			}
+			diffSettings(prefix+key+".", value, lv, diffs)
+		}
+	default:
+		if settingString(desired) != settingString(live) {
+			*diffs = append(*diffs, MappingDiff{
+				Field: strings.TrimSuffix(prefix, "."), Kind: DiffChanged, Reason: "setting differs",
+				Desired: desired, Live: live,
+			})
+		}
+	}
+}
+
+func settingString(v interface{}) string {
+	if list, ok := v.([]interface{}); ok {
+		items := make([]string, 0, len(list))
+		for _, item := range list {
+			items = append(items, fmt.Sprint(item))
+		}
+		return "[" + strings.Join(items, ",") + "]"
+	}
+	return fmt.Sprint(v)
+}
+
+// MappingPlan is what it takes to bring a live index in line with its
+// definition. Additive changes are new fields and subfields that put-mapping
+// applies in place, breaking ones need a reindex into a new index.
+type MappingPlan struct {
+	Index    string        `json:"index"`
+	Exists   bool          `json:"exists"`
+	Additive []MappingDiff `json:"additive"`
+	Breaking []MappingDiff `json:"breaking"`
+}
+
+// UpToDate reports whether the index exists and matches its definition
+func (p *MappingPlan) UpToDate() bool {
+	return p.Exists && len(p.Additive) == 0 && len(p.Breaking) == 0
+}
+
+// PlanMapping fetches the live mapping and settings of def.Name and sorts
+// their differences with the definition into additive and breaking changes
+func PlanMapping(ctx context.Context, client Client, def IndexDefinition) (*MappingPlan, error) {
+	desired, err := MappingOf(def.Model)
+	if err != nil {
+		return nil, err
+	}
+	plan := &MappingPlan{Index: def.Name}
+
+	live, err := client.GetMapping(ctx, def.Name)
+	if errors.Is(err, ErrNotFound) {
+		return plan, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	plan.Exists = true
+
+	liveProperties, _ := live["properties"].(map[string]interface{})
+	for _, diff := range DiffMapping(desired, liveProperties) {
+		if diff.Kind == DiffMissing {
+			plan.Additive = append(plan.Additive, diff)
+		} else {
+			plan.Breaking = append(plan.Breaking, diff)
+		}
+	}
+
+	if len(def.Settings) > 0 {
+		liveSettings, err := client.GetSettings(ctx, def.Name)
+		if err != nil {
+			return nil, err
+		}
+		var declared map[string]interface{}
+		if err := roundTripJSON(def.Settings, &declared); err != nil {
+			return nil, fmt.Errorf("failed to marshal settings: %w", err)
+		}
+		// analysis can only change on a closed index, treat every settings drift as breaking
+		var diffs []MappingDiff
+		diffSettings("settings.", declared, liveSettings, &diffs)
+		sort.Slice(diffs, func(i, j int) bool { return diffs[i].Field < diffs[j].Field })
+		plan.Breaking = append(plan.Breaking, diffs...)
+	}
+
+	return plan, nil
+}
+
+// ApplyAdditive puts the additive changes of a plan into the live mapping
+func ApplyAdditive(ctx context.Context, client Client, def IndexDefinition, plan *MappingPlan) error {
+	if !plan.Exists || len(plan.Additive) == 0 {
+		return nil
+	}
+	desired, err := MappingOf(def.Model)
+	if err != nil {
+		return err
+	}
+
+	properties := map[string]interface{}{}
+	for _, diff := range plan.Additive {
+		addMappingPath(properties, desired, diff.path)
+	}
+	return client.PutMapping(ctx, def.Name, map[string]interface{}{"properties": properties})
+}
+
+// addMappingPath copies the field at path from src into dst. Parents are
+// copied with their own parameters, put-mapping needs the type of a field
+// to add a subfield to it.
+func addMappingPath(dst, src map[string]interface{}, path []string) {
+	if len(path) == 0 {
+		return
+	}
+	name := path[0]
+	def, _ := src[name].(map[string]interface{})
+	if len(path) == 1 {
+		dst[name] = def
+		return
+	}
+
+	node, ok := dst[name].(map[string]interface{})
+	if !ok {
+		node = map[string]interface{}{}
+		for key, value := range def {
+			if key != "properties" && key != "fields" {
+				node[key] = value
+			}
+		}
+		dst[name] = node
+	}
+	child := path[1]
+	sub, ok := node[child].(map[string]interface{})
+	if !ok {
+		sub = map[string]interface{}{}
+		node[child] = sub
+	}
+	srcChild, _ := def[child].(map[string]interface{})
+	addMappingPath(sub, srcChild, path[2:])
+}
+
+// MappingDriftError lists the differences found by CheckMapping
+type MappingDriftError struct {
+	Index string
+	Diffs []MappingDiff
+}
+
+func (e *MappingDriftError) Error() string {
+	fields := make([]string, 0, len(e.Diffs))
+	for _, d := range e.Diffs {
+		fields = append(fields, d.Field+": "+d.Reason)
+	}
+	return fmt.Sprintf("mapping of %s drifted from its definition: %s", e.Index, strings.Join(fields, "; "))
+}
+
+// CheckMapping compares the live mapping and settings of def.Name with the
+// definition and returns a *MappingDriftError when they disagree. A missing
+// index is fine, it will be created from the definition.
+func CheckMapping(ctx context.Context, client Client, def IndexDefinition) error {
+	plan, err := PlanMapping(ctx, client, def)
+	if err != nil {
+		return err
+	}
+	if !plan.Exists || plan.UpToDate() {
+		return nil
+	}
+	return &MappingDriftError{Index: def.Name, Diffs: append(plan.Additive, plan.Breaking...)}
+}
+
+func roundTripJSON(in, out interface{}) error {
+	data, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
@@ -0,0 +1,242 @@
+package es
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+type driftStaff struct {
+	Role string `json:"role"`
+}
+
+type driftDoc struct {
+	ID     uuid.UUID    `json:"id"`
+	Name   string       `json:"name" es:"text,analyzer=standard,field=raw:keyword"`
+	Staffs []driftStaff `json:"staffs" es:"nested"`
+}
+
+const driftLive = `{"properties": {
+	"id": {"type": "keyword"},
+	"name": {"type": "text", "analyzer": "standard", "fields": {"raw": {"type": "keyword"}}},
+	"staffs": {"type": "nested", "properties": {"role": {"type": "keyword"}}}
+}}`
+
+// mappingClient serves one live mapping and its settings, a nil mapping is
+// a missing index. Other methods panic on the nil embedded interface.
+type mappingClient struct {
+	Client
+	mapping  map[string]interface{}
+	settings map[string]interface{}
+	put      map[string]interface{}
+}
+
+func (c *mappingClient) GetMapping(ctx context.Context, indexName string) (map[string]interface{}, error) {
+	if c.mapping == nil {
+		return nil, &ResponseError{Op: "get mapping", StatusCode: 404, Type: "index_not_found_exception"}
+	}
+	return c.mapping, nil
+}
+
+func (c *mappingClient) GetSettings(ctx context.Context, indexName string) (map[string]interface{}, error) {
+	return c.settings, nil
+}
+
+func (c *mappingClient) PutMapping(ctx context.Context, indexName string, mapping interface{}) error {
+	return roundTripJSON(mapping, &c.put)
+}
+
+func decodeJSON(t *testing.T, doc string) map[string]interface{} {
+	t.Helper()
+	if doc == "" {
+		return nil
+	}
+	var v map[string]interface{}
+	if err := json.Unmarshal([]byte(doc), &v); err != nil {
+		t.Fatalf("bad JSON: %v", err)
+	}
+	return v
+}
+
+func TestPlanMapping(t *testing.T) {
+	tests := []struct {
+		name     string
+		live     string
+		settings map[string]interface{}
+		// live settings, as the cluster returns them under "index"
+		liveSettings string
+		wantExists   bool
+		wantAdditive []string
+		wantBreaking []string
+		// the put-mapping body ApplyAdditive sends
+		wantPut string
+	}{
+		{name: "missing index", live: ""},
+		{name: "up to date", live: driftLive, wantExists: true},
+		{
+			name: "defaults reported by the cluster",
+			live: `{"properties": {
+				"id": {"type": "keyword", "ignore_above": 256},
+				"name": {"type": "text", "analyzer": "standard", "index": true, "fields": {"raw": {"type": "keyword"}}},
+				"staffs": {"type": "nested", "properties": {"role": {"type": "keyword"}}}
+			}}`,
+			wantExists: true,
+		},
+		{
+			name: "new field",
+			live: `{"properties": {
+				"name": {"type": "text", "analyzer": "standard", "fields": {"raw": {"type": "keyword"}}},
+				"staffs": {"type": "nested", "properties": {"role": {"type": "keyword"}}}
+			}}`,
+			wantExists:   true,
+			wantAdditive: []string{"id"},
+			wantPut:      `{"properties": {"id": {"type": "keyword"}}}`,
+		},
+		{
+			name: "new subfield",
+			live: `{"properties": {
+				"id": {"type": "keyword"},
+				"name": {"type": "text", "analyzer": "standard"},
+				"staffs": {"type": "nested", "properties": {"role": {"type": "keyword"}}}
+			}}`,
+			wantExists:   true,
+			wantAdditive: []string{"name.raw"},
+			wantPut:      `{"properties": {"name": {"type": "text", "analyzer": "standard", "fields": {"raw": {"type": "keyword"}}}}}`,
+		},
+		{
+			name: "new nested field",
+			live: `{"properties": {
+				"id": {"type": "keyword"},
+				"name": {"type": "text", "analyzer": "standard", "fields": {"raw": {"type": "keyword"}}},
+				"staffs": {"type": "nested", "properties": {}}
+			}}`,
+			wantExists:   true,
+			wantAdditive: []string{"staffs.role"},
+			wantPut:      `{"properties": {"staffs": {"type": "nested", "properties": {"role": {"type": "keyword"}}}}}`,
+		},
+		{
+			name: "type changed",
+			live: `{"properties": {
+				"id": {"type": "text"},
+				"name": {"type": "text", "analyzer": "standard", "fields": {"raw": {"type": "keyword"}}},
+				"staffs": {"type": "nested", "properties": {"role": {"type": "keyword"}}}
+			}}`,
+			wantExists:   true,
+			wantBreaking: []string{"id"},
+		},
+		{
+			name: "nested became object",
+			live: `{"properties": {
+				"id": {"type": "keyword"},
+				"name": {"type": "text", "analyzer": "standard", "fields": {"raw": {"type": "keyword"}}},
+				"staffs": {"properties": {"role": {"type": "keyword"}}}
+			}}`,
+			wantExists:   true,
+			wantBreaking: []string{"staffs"},
+		},
+		{
+			name: "analyzer changed",
+			live: `{"properties": {
+				"id": {"type": "keyword"},
+				"name": {"type": "text", "analyzer": "simple", "fields": {"raw": {"type": "keyword"}}},
+				"staffs": {"type": "nested", "properties": {"role": {"type": "keyword"}}}
+			}}`,
+			wantExists:   true,
+			wantBreaking: []string{"name"},
+		},
+		{
+			name: "undeclared field",
+			live: `{"properties": {
+				"id": {"type": "keyword"},
+				"name": {"type": "text", "analyzer": "standard", "fields": {"raw": {"type": "keyword"}}},
+				"staffs": {"type": "nested", "properties": {"role": {"type": "keyword"}}},
+				"legacy": {"type": "keyword"}
+			}}`,
+			wantExists:   true,
+			wantBreaking: []string{"legacy"},
+		},
+		{
+			name: "additive and breaking together",
+			live: `{"properties": {
+				"id": {"type": "long"},
+				"name": {"type": "text", "analyzer": "standard"},
+				"staffs": {"type": "nested", "properties": {"role": {"type": "keyword"}}}
+			}}`,
+			wantExists:   true,
+			wantAdditive: []string{"name.raw"},
+			wantBreaking: []string{"id"},
+			wantPut:      `{"properties": {"name": {"type": "text", "analyzer": "standard", "fields": {"raw": {"type": "keyword"}}}}}`,
+		},
+		{
+			name:         "settings read back as strings",
+			live:         driftLive,
+			settings:     map[string]interface{}{"number_of_shards": 1, "analysis": map[string]interface{}{"filter": []string{"lowercase", "asciifolding"}}},
+			liveSettings: `{"number_of_shards": "1", "analysis": {"filter": ["lowercase", "asciifolding"]}}`,
+			wantExists:   true,
+		},
+		{
+			name:         "settings changed",
+			live:         driftLive,
+			settings:     map[string]interface{}{"number_of_shards": 1, "analysis": map[string]interface{}{"filter": []string{"lowercase", "asciifolding"}}},
+			liveSettings: `{"number_of_shards": "2", "analysis": {}}`,
+			wantExists:   true,
+			wantBreaking: []string{"settings.analysis.filter", "settings.number_of_shards"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			client := &mappingClient{mapping: decodeJSON(t, tt.live), settings: decodeJSON(t, tt.liveSettings)}
+			def := IndexDefinition{Name: "drift", Model: driftDoc{}, Settings: tt.settings}
+
+			plan, err := PlanMapping(ctx, client, def)
+			if err != nil {
+				t.Fatalf("err = %v", err)
+			}
+			if plan.Exists != tt.wantExists {
+				t.Errorf("exists = %v, want %v", plan.Exists, tt.wantExists)
+			}
+			if got := diffFields(plan.Additive); !slices.Equal(got, tt.wantAdditive) {
+				t.Errorf("additive = %v, want %v", got, tt.wantAdditive)
+			}
+			if got := diffFields(plan.Breaking); !slices.Equal(got, tt.wantBreaking) {
+				t.Errorf("breaking = %v, want %v", got, tt.wantBreaking)
+			}
+
+			if err := ApplyAdditive(ctx, client, def, plan); err != nil {
+				t.Fatalf("ApplyAdditive: %v", err)
+			}
+			if tt.wantPut == "" {
+				if client.put != nil {
+					t.Errorf("put %v, want no put-mapping", client.put)
+				}
+			} else {
+				assertSameJSON(t, client.put, tt.wantPut)
+			}
+
+			// a missing index is created from the definition, only drift fails
+			err = CheckMapping(ctx, client, def)
+			var drift *MappingDriftError
+			wantDrift := len(tt.wantAdditive)+len(tt.wantBreaking) > 0
+			if errors.As(err, &drift) != wantDrift || (!wantDrift && err != nil) {
+				t.Errorf("CheckMapping = %v, want drift %v", err, wantDrift)
+			}
+			if drift != nil && len(drift.Diffs) != len(tt.wantAdditive)+len(tt.wantBreaking) {
+				t.Errorf("drift lists %d diffs, want %d", len(drift.Diffs), len(tt.wantAdditive)+len(tt.wantBreaking))
+			}
+		})
+	}
+}
+
+func diffFields(diffs []MappingDiff) []string {
+	var fields []string
+	for _, d := range diffs {
+		fields = append(fields, d.Field)
+	}
+	return fields
+}
@@ -2,16 +2,74 @@ package es
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"math/rand"
+	"net/http"
+	"time"
+
 	"github.com/elastic/go-elasticsearch/v8"
 )
 
+const (
+	defaultTransportRetries = 3
+	defaultRetryBackoffMin  = 100 * time.Millisecond
+	defaultRetryBackoffMax  = 2 * time.Second
+	defaultSearchTimeout    = 5 * time.Second
+	defaultDocumentTimeout  = 5 * time.Second
+	defaultBulkTimeout      = time.Minute
+	defaultAdminTimeout     = 30 * time.Second
+	defaultBreakerThreshold = 5
+	defaultBreakerTimeout   = 30 * time.Second
+)
+
 func NewClient(cfg Config) (Client, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	cfg = cfg.withDefaults()
+
+	transport, err := newHTTPTransport(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create elasticsearch transport: %w", err)
+	}
+	breaker := newCircuitBreaker(cfg.BreakerFailureThreshold, cfg.BreakerOpenTimeout)
+
+	// the transport makes MaxRetries+1 attempts even with retries disabled,
+	// a negative count would send nothing
+	maxRetries := cfg.MaxRetries
+	if maxRetries < 0 {
+		maxRetries = 0
+	}
 
 	esCfg := elasticsearch.Config{
-		Addresses: cfg.Addresses,
-		Username:  cfg.Username,
-		Password:  cfg.Password,
+		Addresses:    cfg.Addresses,
+		Username:     cfg.Username,
+		Password:     cfg.Password,
+		APIKey:       cfg.APIKey,
+		ServiceToken: cfg.ServiceToken,
+
+		DiscoverNodesOnStart:  cfg.DiscoverNodesOnStart,
+		DiscoverNodesInterval: cfg.DiscoverNodesInterval,
+
+		Transport: &breakerTransport{
+			next:    transport,
+			breaker: breaker,
+		},
+		RetryOnStatus: []int{
+			http.StatusTooManyRequests,
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout,
+		},
+		RetryOnError: func(req *http.Request, err error) bool {
+			// no point in retrying against an open breaker or a finished request
+			return !errors.Is(err, ErrSearchUnavailable) && req.Context().Err() == nil
+		},
+		MaxRetries:   maxRetries,
+		DisableRetry: cfg.MaxRetries < 0,
+		RetryBackoff: retryBackoff(cfg.RetryBackoffMin, cfg.RetryBackoffMax),
 	}
 
 	client, err := elasticsearch.NewClient(esCfg)
@@ -19,22 +77,109 @@ func NewClient(cfg Config) (Client, error) {
 		return nil, fmt.Errorf("failed to create elasticsearch client: %w", err)
 	}
 
-	return &esClient{client: client}, nil
+	return &esClient{client: client, cfg: cfg}, nil
+}
+
+func (cfg Config) withDefaults() Config {
+	if cfg.MaxRetries == 0 {
+		cfg.MaxRetries = defaultTransportRetries
+	}
+	if cfg.RetryBackoffMin <= 0 {
+		cfg.RetryBackoffMin = defaultRetryBackoffMin
+	}
+	if cfg.RetryBackoffMax < cfg.RetryBackoffMin {
+		cfg.RetryBackoffMax = defaultRetryBackoffMax
+		if cfg.RetryBackoffMax < cfg.RetryBackoffMin {
+			cfg.RetryBackoffMax = cfg.RetryBackoffMin
+		}
+	}
+	if cfg.SearchTimeout <= 0 {
+		cfg.SearchTimeout = defaultSearchTimeout
+	}
+	if cfg.DocumentTimeout <= 0 {
+		cfg.DocumentTimeout = defaultDocumentTimeout
+	}
+	if cfg.BulkTimeout <= 0 {
+		cfg.BulkTimeout = defaultBulkTimeout
+	}
+	if cfg.AdminTimeout <= 0 {
+		cfg.AdminTimeout = defaultAdminTimeout
+	}
+	if cfg.BreakerFailureThreshold <= 0 {
+		cfg.BreakerFailureThreshold = defaultBreakerThreshold
+	}
+	if cfg.BreakerOpenTimeout <= 0 {
+		cfg.BreakerOpenTimeout = defaultBreakerTimeout
+	}
+	return cfg
+}
+
+// retryBackoff doubles the delay on every attempt up to max, keeping half of
+// it fixed and randomizing the other half so clients don't retry in lockstep
+func retryBackoff(min, max time.Duration) func(attempt int) time.Duration {
+	return func(attempt int) time.Duration {
+		d := max
+		if attempt < 32 {
+			if exp := min << uint(attempt-1); exp > 0 && exp < max {
+				d = exp
+			}
+		}
+		half := d / 2
+		return half + time.Duration(rand.Int63n(int64(half)+1))
+	}
+}
+
+// withTimeout bounds one call to the cluster by its operation timeout
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, timeout)
 }
 
 type Client interface {
 	// Index operations
 	CreateIndex(ctx context.Context, indexName string, mapping interface{}) error
 	IndexExists(ctx context.Context, indexName string) (bool, error)
+	DeleteIndex(ctx context.Context, indexName string) error
+	RefreshIndex(ctx context.Context, indexName string) error
+	Count(ctx context.Context, indexName string) (int64, error)
+	ListIndices(ctx context.Context, pattern string) ([]string, error)
+	GetMapping(ctx context.Context, indexName string) (map[string]interface{}, error)
+	GetSettings(ctx context.Context, indexName string) (map[string]interface{}, error)
+	PutMapping(ctx context.Context, indexName string, mapping interface{}) error
+	ReloadSearchAnalyzers(ctx context.Context, indexName string) error
+
+	// Alias operations
+	GetAlias(ctx context.Context, alias string) ([]string, error)
+	UpdateAliases(ctx context.Context, actions []AliasAction) error
 	
 	// Document operations
 	IndexDocument(ctx context.Context, indexName, docID string, doc interface{}) error
+	GetDocument(ctx context.Context, indexName, docID string) (*Document, error)
+	UpdateDocument(ctx context.Context, indexName, docID string, update DocumentUpdate) error
+	DeleteDocument(ctx context.Context, indexName, docID string) error
+	DeleteByQuery(ctx context.Context, indexName string, query interface{}) (*ByQueryResult, error)
+	UpdateByQuery(ctx context.Context, indexName string, body interface{}) (*ByQueryResult, error)
 	
 	// Bulk operations
 	BulkIndex(ctx context.Context, indexName string, docs []BulkDocument) error
+	NewBulkIndexer(ctx context.Context, cfg BulkIndexerConfig) (BulkIndexer, error)
 	
+	// Synonyms operations
+	PutSynonymsSet(ctx context.Context, setID string, rules []SynonymRule) error
+
+	// Search template operations
+	PutSearchTemplate(ctx context.Context, templateID string, source string) error
+	DeleteSearchTemplate(ctx context.Context, templateID string) error
+	RenderSearchTemplate(ctx context.Context, templateID string, params map[string]interface{}) (json.RawMessage, error)
+	SearchTemplate(ctx context.Context, indexName, templateID string, params map[string]interface{}) (*SearchResult, error)
+
 	// Search operations
 	Search(ctx context.Context, indexName string, query interface{}) (*SearchResult, error)
+	SearchIndices(ctx context.Context, indices []IndexBoost, query interface{}) (*SearchResult, error)
+	OpenPointInTime(ctx context.Context, indexName string, keepAlive string) (string, error)
+	ClosePointInTime(ctx context.Context, pitID string) error
 	
 	// Health check
 	Ping(ctx context.Context) error
@@ -42,5 +187,6 @@ type Client interface {
 
 type esClient struct {
 	client *elasticsearch.Client
+	cfg    Config
 }
 
@@ -0,0 +1,75 @@
+package es
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+)
+
+var (
+	// ErrNotFound matches responses for a missing document or index
+	ErrNotFound = errors.New("elasticsearch: not found")
+	// ErrVersionConflict matches responses rejected by optimistic concurrency control
+	ErrVersionConflict = errors.New("elasticsearch: version conflict")
+	// ErrSearchUnavailable is returned without calling the cluster while the circuit breaker is open
+	ErrSearchUnavailable = errors.New("elasticsearch: search unavailable")
+)
+
+// ResponseError is an error response of Elasticsearch, test it with
+// errors.Is against ErrNotFound or ErrVersionConflict
+type ResponseError struct {
+	Op         string
+	StatusCode int
+	Type       string
+	Reason     string
+}
+
+func (e *ResponseError) Error() string {
+	if e.Type == "" {
+		return fmt.Sprintf("%s error: status %d", e.Op, e.StatusCode)
+	}
+	return fmt.Sprintf("%s error: status %d %s: %s", e.Op, e.StatusCode, e.Type, e.Reason)
+}
+
+func (e *ResponseError) Is(target error) bool {
+	switch target {
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrVersionConflict:
+		return e.StatusCode == http.StatusConflict
+	}
+	return false
+}
+
+// newResponseError reads the error body of a failed response
+func newResponseError(op string, res *esapi.Response) error {
+	e := &ResponseError{Op: op, StatusCode: res.StatusCode}
+
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		return e
+	}
+	var body struct {
+		Error json.RawMessage `json:"error"`
+	}
+	if err := json.Unmarshal(data, &body); err != nil || len(body.Error) == 0 {
+		return e
+	}
+
+	var cause struct {
+		Type   string `json:"type"`
+		Reason string `json:"reason"`
+	}
+	if err := json.Unmarshal(body.Error, &cause); err == nil {
+		e.Type = cause.Type
+		e.Reason = cause.Reason
+	} else {
+		// some APIs answer with a plain string error
+		_ = json.Unmarshal(body.Error, &e.Reason)
+	}
+	return e
+}
@@ -0,0 +1,688 @@
+// Package esfake is an in-memory es.Client for tests that must run without
+// a cluster. Documents are kept per index and searched with the subset of
+// the query DSL that the services build, see search.go for what is supported.
+package esfake
+
+import (
+	"business/pkg/es"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Client implements es.Client in memory, the zero value is not usable, use New
+type Client struct {
+	mu      sync.Mutex
+	indices map[string]*index
+	aliases map[string]map[string]bool // alias -> indices
+	pits    map[string][]string        // point in time id -> indices
+	// synonyms holds the synonyms sets, they are not applied to searches
+	synonyms map[string][]es.SynonymRule
+	// templates holds the mustache source of the stored search templates
+	templates map[string]string
+	nextPit   int
+	nextDoc   int64
+}
+
+var _ es.Client = (*Client)(nil)
+
+type index struct {
+	name    string
+	mapping map[string]interface{}
+	docs    map[string]*document
+	seqNo   int64
+}
+
+type document struct {
+	id      string
+	source  map[string]interface{}
+	version int64
+	seqNo   int64
+	order   int64 // insertion order, stands in for _doc and _shard_doc
+}
+
+func New() *Client {
+	return &Client{
+		indices:   map[string]*index{},
+		aliases:   map[string]map[string]bool{},
+		pits:      map[string][]string{},
+		synonyms:  map[string][]es.SynonymRule{},
+		templates: map[string]string{},
+	}
+}
+
+func (c *Client) Ping(ctx context.Context) error {
+	return nil
+}
+
+// CreateIndex stores the mapping, which decides how text and keyword fields
+// are matched. Like the real client an existing index is not an error.
+func (c *Client) CreateIndex(ctx context.Context, indexName string, mapping interface{}) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.indices[indexName]; ok {
+		return nil
+	}
+	var m map[string]interface{}
+	if mapping != nil {
+		if err := roundTrip(mapping, &m); err != nil {
+			return fmt.Errorf("failed to marshal mapping: %w", err)
+		}
+	}
+	if set := c.missingSynonymsSet(m); set != "" {
+		return &es.ResponseError{
+			Op: "create index", StatusCode: http.StatusNotFound,
+			Type: "resource_not_found_exception", Reason: "synonyms set [" + set + "] not found",
+		}
+	}
+	c.indices[indexName] = &index{name: indexName, mapping: m, docs: map[string]*document{}}
+	return nil
+}
+
+// missingSynonymsSet returns a synonyms set the analysis filters of a create
+// index body refer to and that doesn't exist, like Elasticsearch refuses them
+func (c *Client) missingSynonymsSet(body map[string]interface{}) string {
+	settings, _ := body["settings"].(map[string]interface{})
+	if index, ok := settings["index"].(map[string]interface{}); ok {
+		settings = index
+	}
+	analysis, _ := settings["analysis"].(map[string]interface{})
+	filters, _ := analysis["filter"].(map[string]interface{})
+	for _, filter := range filters {
+		def, _ := filter.(map[string]interface{})
+		if set, ok := def["synonyms_set"].(string); ok {
+			if _, exists := c.synonyms[set]; !exists {
+				return set
+			}
+		}
+	}
+	return ""
+}
+
+func (c *Client) PutSynonymsSet(ctx context.Context, setID string, rules []es.SynonymRule) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.synonyms[setID] = append([]es.SynonymRule{}, rules...)
+	return nil
+}
+
+// SynonymsSet returns the rules of a synonyms set
+func (c *Client) SynonymsSet(setID string) []es.SynonymRule {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return append([]es.SynonymRule(nil), c.synonyms[setID]...)
+}
+
+// ReloadSearchAnalyzers only checks the index exists, synonyms are not applied
+func (c *Client) ReloadSearchAnalyzers(ctx context.Context, indexName string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	_, err := c.resolve(indexName)
+	return err
+}
+
+func (c *Client) IndexExists(ctx context.Context, indexName string) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	_, err := c.resolve(indexName)
+	return err == nil, nil
+}
+
+func (c *Client) DeleteIndex(ctx context.Context, indexName string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.indices[indexName]; !ok {
+		return indexNotFound("delete index", indexName)
+	}
+	delete(c.indices, indexName)
+	for alias, indices := range c.aliases {
+		delete(indices, indexName)
+		if len(indices) == 0 {
+			delete(c.aliases, alias)
+		}
+	}
+	return nil
+}
+
+// RefreshIndex does nothing, writes are visible at once
+func (c *Client) RefreshIndex(ctx context.Context, indexName string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	_, err := c.resolve(indexName)
+	return err
+}
+
+func (c *Client) Count(ctx context.Context, indexName string) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	indices, err := c.resolve(indexName)
+	if err != nil {
+		return 0, err
+	}
+	var count int64
+	for _, idx := range indices {
+		count += int64(len(idx.docs))
+	}
+	return count, nil
+}
+
+func (c *Client) ListIndices(ctx context.Context, pattern string) ([]string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var names []string
+	for name := range c.indices {
+		if ok, _ := path.Match(pattern, name); ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// GetMapping returns the mapping given to CreateIndex, the fake doesn't add dynamic fields
+func (c *Client) GetMapping(ctx context.Context, indexName string) (map[string]interface{}, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	idx, err := c.readIndex("get mapping", indexName)
+	if err != nil {
+		return nil, err
+	}
+	mappings, _ := idx.mapping["mappings"].(map[string]interface{})
+	if mappings == nil {
+		mappings = map[string]interface{}{}
+	}
+	var out map[string]interface{}
+	if err := roundTrip(mappings, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetSettings returns the settings given to CreateIndex
+func (c *Client) GetSettings(ctx context.Context, indexName string) (map[string]interface{}, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	idx, err := c.readIndex("get settings", indexName)
+	if err != nil {
+		return nil, err
+	}
+	settings, _ := idx.mapping["settings"].(map[string]interface{})
+	if index, ok := settings["index"].(map[string]interface{}); ok {
+		settings = merge(settings, index)
+		delete(settings, "index")
+	}
+	var out map[string]interface{}
+	if err := roundTrip(settings, &out); err != nil {
+		return nil, err
+	}
+	if out == nil {
+		out = map[string]interface{}{}
+	}
+	return out, nil
+}
+
+// PutMapping merges new fields into the stored mapping of every index behind indexName
+func (c *Client) PutMapping(ctx context.Context, indexName string, mapping interface{}) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var m map[string]interface{}
+	if err := roundTrip(mapping, &m); err != nil {
+		return fmt.Errorf("failed to marshal mapping: %w", err)
+	}
+	indices, err := c.resolve(indexName)
+	if err != nil {
+		return err
+	}
+	for _, idx := range indices {
+		if idx.mapping == nil {
+			idx.mapping = map[string]interface{}{}
+		}
+		mappings, _ := idx.mapping["mappings"].(map[string]interface{})
+		idx.mapping["mappings"] = merge(mappings, m)
+	}
+	return nil
+}
+
+func (c *Client) GetAlias(ctx context.Context, alias string) ([]string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var names []string
+	for name := range c.aliases[alias] {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// UpdateAliases validates every action before applying any, like the atomic _aliases API
+func (c *Client) UpdateAliases(ctx context.Context, actions []es.AliasAction) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, action := range actions {
+		for _, target := range []*es.AliasTarget{action.Add, action.Remove, action.RemoveIndex} {
+			if target == nil {
+				continue
+			}
+			if _, ok := c.indices[target.Index]; !ok {
+				return indexNotFound("update aliases", target.Index)
+			}
+		}
+		if action.Remove != nil && !c.aliases[action.Remove.Alias][action.Remove.Index] {
+			return &es.ResponseError{
+				Op: "update aliases", StatusCode: http.StatusNotFound,
+				Type: "aliases_not_found_exception", Reason: fmt.Sprintf("aliases [%s] missing", action.Remove.Alias),
+			}
+		}
+	}
+
+	for _, action := range actions {
+		switch {
+		case action.Add != nil:
+			if c.aliases[action.Add.Alias] == nil {
+				c.aliases[action.Add.Alias] = map[string]bool{}
+			}
+			c.aliases[action.Add.Alias][action.Add.Index] = true
+		case action.Remove != nil:
+			delete(c.aliases[action.Remove.Alias], action.Remove.Index)
+			if len(c.aliases[action.Remove.Alias]) == 0 {
+				delete(c.aliases, action.Remove.Alias)
+			}
+		case action.RemoveIndex != nil:
+			delete(c.indices, action.RemoveIndex.Index)
+		}
+	}
+	return nil
+}
+
+func (c *Client) IndexDocument(ctx context.Context, indexName, docID string, doc interface{}) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var source map[string]interface{}
+	if err := roundTrip(doc, &source); err != nil {
+		return fmt.Errorf("failed to marshal document: %w", err)
+	}
+	idx, err := c.writeIndex("index document", indexName)
+	if err != nil {
+		return err
+	}
+	c.put(idx, docID, source)
+	return nil
+}
+
+func (c *Client) GetDocument(ctx context.Context, indexName, docID string) (*es.Document, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	idx, err := c.readIndex("get document", indexName)
+	if err != nil {
+		return nil, err
+	}
+	doc, ok := idx.docs[docID]
+	if !ok {
+		return nil, &es.ResponseError{Op: "get document", StatusCode: http.StatusNotFound}
+	}
+	source, err := json.Marshal(doc.source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return &es.Document{
+		Index:       idx.name,
+		ID:          doc.id,
+		Version:     doc.version,
+		SeqNo:       doc.seqNo,
+		PrimaryTerm: 1,
+		Found:       true,
+		Source:      source,
+	}, nil
+}
+
+// UpdateDocument merges Doc into the stored document. Scripts can't be run
+// in memory and are rejected.
+func (c *Client) UpdateDocument(ctx context.Context, indexName, docID string, update es.DocumentUpdate) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if update.Script != nil {
+		return unsupported("update document", "scripted updates")
+	}
+	idx, err := c.writeIndex("update document", indexName)
+	if err != nil {
+		return err
+	}
+
+	doc, ok := idx.docs[docID]
+	if update.IfSeqNo != nil && (!ok || doc.seqNo != int64(*update.IfSeqNo)) {
+		return &es.ResponseError{
+			Op: "update document", StatusCode: http.StatusConflict,
+			Type: "version_conflict_engine_exception", Reason: fmt.Sprintf("[%s]: version conflict", docID),
+		}
+	}
+
+	var partial map[string]interface{}
+	if update.Doc != nil {
+		if err := roundTrip(update.Doc, &partial); err != nil {
+			return fmt.Errorf("failed to marshal update: %w", err)
+		}
+	}
+
+	if !ok {
+		switch {
+		case update.Upsert != nil:
+			var upsert map[string]interface{}
+			if err := roundTrip(update.Upsert, &upsert); err != nil {
+				return fmt.Errorf("failed to marshal update: %w", err)
+			}
+			c.put(idx, docID, upsert)
+		case update.DocAsUpsert:
+			c.put(idx, docID, partial)
+		default:
+			return &es.ResponseError{
+				Op: "update document", StatusCode: http.StatusNotFound,
+				Type: "document_missing_exception", Reason: fmt.Sprintf("[%s]: document missing", docID),
+			}
+		}
+		return nil
+	}
+
+	c.put(idx, docID, merge(doc.source, partial))
+	return nil
+}
+
+func (c *Client) DeleteDocument(ctx context.Context, indexName, docID string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	idx, err := c.writeIndex("delete document", indexName)
+	if err != nil {
+		return err
+	}
+	if _, ok := idx.docs[docID]; !ok {
+		return &es.ResponseError{Op: "delete document", StatusCode: http.StatusNotFound}
+	}
+	delete(idx.docs, docID)
+	idx.seqNo++
+	return nil
+}
+
+// DeleteByQuery takes a body with a "query", like the real client
+func (c *Client) DeleteByQuery(ctx context.Context, indexName string, query interface{}) (*es.ByQueryResult, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	matched, err := c.matchByQuery("delete by query", indexName, query)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range matched {
+		delete(m.idx.docs, m.doc.id)
+		m.idx.seqNo++
+	}
+	n := int64(len(matched))
+	return &es.ByQueryResult{Total: n, Deleted: n}, nil
+}
+
+// UpdateByQuery rewrites every matched document unchanged, scripts are rejected
+func (c *Client) UpdateByQuery(ctx context.Context, indexName string, body interface{}) (*es.ByQueryResult, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var b map[string]interface{}
+	if err := roundTrip(body, &b); err != nil {
+		return nil, fmt.Errorf("failed to encode body: %w", err)
+	}
+	if _, ok := b["script"]; ok {
+		return nil, unsupported("update by query", "scripts")
+	}
+	matched, err := c.matchByQuery("update by query", indexName, b)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range matched {
+		c.put(m.idx, m.doc.id, m.doc.source)
+	}
+	n := int64(len(matched))
+	return &es.ByQueryResult{Total: n, Updated: n}, nil
+}
+
+func (c *Client) BulkIndex(ctx context.Context, indexName string, docs []es.BulkDocument) error {
+	indexer, err := c.NewBulkIndexer(ctx, es.BulkIndexerConfig{Index: indexName})
+	if err != nil {
+		return err
+	}
+	for _, doc := range docs {
+		if err := indexer.Add(ctx, doc); err != nil {
+			_, _ = indexer.Close(ctx)
+			return err
+		}
+	}
+	_, err = indexer.Close(ctx)
+	return err
+}
+
+func (c *Client) NewBulkIndexer(ctx context.Context, cfg es.BulkIndexerConfig) (es.BulkIndexer, error) {
+	if cfg.Index == "" {
+		return nil, fmt.Errorf("bulk indexer: index is required")
+	}
+	return &bulkIndexer{client: c, index: cfg.Index, report: &es.BulkReport{}}, nil
+}
+
+func (c *Client) OpenPointInTime(ctx context.Context, indexName string, keepAlive string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	indices, err := c.resolve(indexName)
+	if err != nil {
+		return "", err
+	}
+	c.nextPit++
+	id := "fake-pit-" + strconv.Itoa(c.nextPit)
+	for _, idx := range indices {
+		c.pits[id] = append(c.pits[id], idx.name)
+	}
+	return id, nil
+}
+
+func (c *Client) ClosePointInTime(ctx context.Context, pitID string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.pits, pitID)
+	return nil
+}
+
+// bulkIndexer writes every added document at once, so Close only reports
+type bulkIndexer struct {
+	client *Client
+	index  string
+	report *es.BulkReport
+}
+
+func (bi *bulkIndexer) Add(ctx context.Context, doc es.BulkDocument) error {
+	err := bi.client.IndexDocument(ctx, bi.index, doc.ID, doc.Data)
+	if err != nil {
+		bi.report.Failed++
+		bi.report.Failures = append(bi.report.Failures, es.BulkItemFailure{
+			ID: doc.ID, Status: http.StatusBadRequest, Type: "mapper_parsing_exception", Reason: err.Error(),
+		})
+		return nil
+	}
+	bi.report.Indexed++
+	return nil
+}
+
+func (bi *bulkIndexer) Close(ctx context.Context) (*es.BulkReport, error) {
+	return bi.report, nil
+}
+
+// resolve expands comma separated index names, aliases and wildcards
+func (c *Client) resolve(name string) ([]*index, error) {
+	seen := map[string]bool{}
+	var indices []*index
+	add := func(n string) {
+		if idx, ok := c.indices[n]; ok && !seen[n] {
+			seen[n] = true
+			indices = append(indices, idx)
+		}
+	}
+
+	for _, part := range strings.Split(name, ",") {
+		switch {
+		case strings.ContainsAny(part, "*?"):
+			for n := range c.indices {
+				if ok, _ := path.Match(part, n); ok {
+					add(n)
+				}
+			}
+			for alias, targets := range c.aliases {
+				if ok, _ := path.Match(part, alias); ok {
+					for n := range targets {
+						add(n)
+					}
+				}
+			}
+		case c.aliases[part] != nil:
+			for n := range c.aliases[part] {
+				add(n)
+			}
+		default:
+			if _, ok := c.indices[part]; !ok {
+				return nil, indexNotFound("resolve index", part)
+			}
+			add(part)
+		}
+	}
+
+	sort.Slice(indices, func(i, j int) bool { return indices[i].name < indices[j].name })
+	return indices, nil
+}
+
+// readIndex resolves a name that must point to exactly one index
+func (c *Client) readIndex(op, name string) (*index, error) {
+	indices, err := c.resolve(name)
+	if err != nil {
+		return nil, err
+	}
+	if len(indices) != 1 {
+		return nil, &es.ResponseError{
+			Op: op, StatusCode: http.StatusBadRequest, Type: "illegal_argument_exception",
+			Reason: fmt.Sprintf("%s points to %d indices", name, len(indices)),
+		}
+	}
+	return indices[0], nil
+}
+
+// writeIndex is readIndex that creates a missing index, like dynamic index creation
+func (c *Client) writeIndex(op, name string) (*index, error) {
+	if _, ok := c.indices[name]; !ok && c.aliases[name] == nil {
+		c.indices[name] = &index{name: name, docs: map[string]*document{}}
+	}
+	return c.readIndex(op, name)
+}
+
+func (c *Client) put(idx *index, id string, source map[string]interface{}) {
+	idx.seqNo++
+	doc, ok := idx.docs[id]
+	if !ok {
+		c.nextDoc++
+		doc = &document{id: id, order: c.nextDoc}
+		idx.docs[id] = doc
+	}
+	doc.source = source
+	doc.version++
+	doc.seqNo = idx.seqNo
+}
+
+type match struct {
+	idx *index
+	doc *document
+}
+
+func (c *Client) matchByQuery(op, indexName string, body interface{}) ([]match, error) {
+	var b map[string]interface{}
+	if err := roundTrip(body, &b); err != nil {
+		return nil, fmt.Errorf("failed to encode query: %w", err)
+	}
+	indices, err := c.resolve(indexName)
+	if err != nil {
+		return nil, err
+	}
+
+	var matched []match
+	for _, idx := range indices {
+		for _, doc := range idx.docs {
+			ok := true
+			if q, has := b["query"]; has {
+				ok, _, err = evaluate(idx, q, doc.source)
+				if err != nil {
+					return nil, &es.ResponseError{Op: op, StatusCode: http.StatusBadRequest, Type: "parsing_exception", Reason: err.Error()}
+				}
+			}
+			if ok {
+				matched = append(matched, match{idx: idx, doc: doc})
+			}
+		}
+	}
+	return matched, nil
+}
+
+func indexNotFound(op, name string) error {
+	return &es.ResponseError{
+		Op: op, StatusCode: http.StatusNotFound,
+		Type: "index_not_found_exception", Reason: "no such index [" + name + "]",
+	}
+}
+
+func unsupported(op, what string) error {
+	return &es.ResponseError{
+		Op: op, StatusCode: http.StatusBadRequest,
+		Type: "illegal_argument_exception", Reason: "esfake does not support " + what,
+	}
+}
+
+// roundTrip converts any JSON serializable value to its decoded form
+func roundTrip(in interface{}, out interface{}) error {
+	data, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
+
+// merge applies a partial document the way a doc update does, objects are merged recursively
+func merge(dst, src map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(dst)+len(src))
+	for k, v := range dst {
+		out[k] = v
+	}
+	for k, v := range src {
+		if sub, ok := v.(map[string]interface{}); ok {
+			if cur, ok := out[k].(map[string]interface{}); ok {
+				out[k] = merge(cur, sub)
+				continue
+			}
+		}
+		out[k] = v
+	}
+	return out
+}
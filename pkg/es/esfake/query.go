@@ -0,0 +1,649 @@
+package esfake
+
+import (
+	"business/pkg/utils"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// evaluate matches one document against a query clause and scores it.
+// Scores only need to order hits sensibly: every matched query token
+// counts once, multiplied by field and query boosts.
+func evaluate(idx *index, raw interface{}, src map[string]interface{}) (bool, float64, error) {
+	clause, ok := raw.(map[string]interface{})
+	if !ok || len(clause) != 1 {
+		return false, 0, fmt.Errorf("query must be an object with a single clause, got %v", raw)
+	}
+
+	for kind, body := range clause {
+		switch kind {
+		case "match_all":
+			return true, boostOf(body), nil
+		case "match_none":
+			return false, 0, nil
+		case "bool":
+			return evaluateBool(idx, body, src)
+		case "match":
+			return evaluateMatch(idx, body, src)
+		case "multi_match":
+			return evaluateMultiMatch(idx, body, src)
+		case "term", "prefix":
+			return evaluateTerm(idx, kind, body, src)
+		case "terms":
+			return evaluateTerms(idx, body, src)
+		case "range":
+			return evaluateRange(body, src)
+		case "exists":
+			opts, _ := body.(map[string]interface{})
+			field, _ := opts["field"].(string)
+			return len(scalars(src, field)) > 0, 1, nil
+		case "nested":
+			return evaluateNested(idx, body, src)
+		case "constant_score":
+			opts, _ := body.(map[string]interface{})
+			ok, _, err := evaluate(idx, opts["filter"], src)
+			return ok, boostOf(body), err
+		case "function_score":
+			return evaluateFunctionScore(idx, body, src)
+		default:
+			return false, 0, fmt.Errorf("esfake does not support %s queries", kind)
+		}
+	}
+	return false, 0, nil
+}
+
+func evaluateBool(idx *index, raw interface{}, src map[string]interface{}) (bool, float64, error) {
+	opts, _ := raw.(map[string]interface{})
+	score := 0.0
+
+	for _, q := range toList(opts["must"]) {
+		ok, s, err := evaluate(idx, q, src)
+		if err != nil || !ok {
+			return false, 0, err
+		}
+		score += s
+	}
+	for _, q := range toList(opts["filter"]) {
+		ok, _, err := evaluate(idx, q, src)
+		if err != nil || !ok {
+			return false, 0, err
+		}
+	}
+	for _, q := range toList(opts["must_not"]) {
+		ok, _, err := evaluate(idx, q, src)
+		if err != nil || ok {
+			return false, 0, err
+		}
+	}
+
+	should := toList(opts["should"])
+	minimum := 0
+	if len(should) > 0 && len(toList(opts["must"])) == 0 && len(toList(opts["filter"])) == 0 {
+		minimum = 1
+	}
+	if m, ok := opts["minimum_should_match"]; ok {
+		minimum = intOf(m, minimum)
+	}
+	matched := 0
+	for _, q := range should {
+		ok, s, err := evaluate(idx, q, src)
+		if err != nil {
+			return false, 0, err
+		}
+		if ok {
+			matched++
+			score += s
+		}
+	}
+	if matched < minimum {
+		return false, 0, nil
+	}
+
+	return true, score * boostOf(raw), nil
+}
+
+type textOptions struct {
+	operator      string
+	fuzziness     string
+	prefixLength  int
+	lastAsPrefix  bool
+	minimumTokens int
+}
+
+func evaluateMatch(idx *index, raw interface{}, src map[string]interface{}) (bool, float64, error) {
+	body, _ := raw.(map[string]interface{})
+	for field, params := range body {
+		query, opts, boost := textParams(params)
+		score := matchField(idx, field, query, opts, src)
+		return score > 0, score * boost, nil
+	}
+	return false, 0, nil
+}
+
+func evaluateMultiMatch(idx *index, raw interface{}, src map[string]interface{}) (bool, float64, error) {
+	body, _ := raw.(map[string]interface{})
+	query, opts, boost := textParams(body)
+	kind, _ := body["type"].(string)
+	if kind == "bool_prefix" || kind == "phrase_prefix" {
+		opts.lastAsPrefix = true
+	}
+
+	fields := toStrings(toList(body["fields"]))
+	if len(fields) == 0 {
+		fields = []string{"*"}
+	}
+
+	best, sum := 0.0, 0.0
+	for _, f := range fields {
+		name, fieldBoost := splitBoost(f)
+		var score float64
+		if name == "*" {
+			for key := range src {
+				score = math.Max(score, matchField(idx, key, query, opts, src))
+			}
+		} else {
+			score = matchField(idx, name, query, opts, src)
+		}
+		score *= fieldBoost
+		best = math.Max(best, score)
+		sum += score
+	}
+
+	if kind == "most_fields" || kind == "cross_fields" {
+		return sum > 0, sum * boost, nil
+	}
+	return best > 0, best * boost, nil
+}
+
+// textParams reads the query string and options of a match or multi_match
+func textParams(raw interface{}) (string, textOptions, float64) {
+	opts := textOptions{operator: "or"}
+	params, ok := raw.(map[string]interface{})
+	if !ok {
+		return fmt.Sprint(raw), opts, 1
+	}
+	query := fmt.Sprint(params["query"])
+	if op, ok := params["operator"].(string); ok {
+		opts.operator = strings.ToLower(op)
+	}
+	if f, ok := params["fuzziness"]; ok {
+		opts.fuzziness = fmt.Sprint(f)
+	}
+	opts.prefixLength = intOf(params["prefix_length"], 0)
+	if m, ok := params["minimum_should_match"]; ok {
+		opts.minimumTokens = intOf(m, 0)
+	}
+	return query, opts, boostOf(params)
+}
+
+// matchField scores a full-text query against a field, keyword fields must
+// equal the whole query like they do in Elasticsearch
+func matchField(idx *index, field, query string, opts textOptions, src map[string]interface{}) float64 {
+	values := scalars(src, field)
+	if len(values) == 0 {
+		return 0
+	}
+
+	if idx.fieldType(field) == "keyword" {
+		for _, v := range values {
+			if fmt.Sprint(v) == query {
+				return 1
+			}
+		}
+		return 0
+	}
+
+	var tokens []string
+	for _, v := range values {
+		tokens = append(tokens, analyze(fmt.Sprint(v))...)
+	}
+	queryTokens := analyze(query)
+	if len(queryTokens) == 0 {
+		return 0
+	}
+
+	matched := 0
+	for i, qt := range queryTokens {
+		prefix := opts.lastAsPrefix && i == len(queryTokens)-1
+		for _, t := range tokens {
+			if tokenMatches(qt, t, opts, prefix) {
+				matched++
+				break
+			}
+		}
+	}
+
+	switch {
+	case opts.operator == "and" && matched < len(queryTokens):
+		return 0
+	case matched < opts.minimumTokens:
+		return 0
+	}
+	return float64(matched)
+}
+
+func tokenMatches(query, token string, opts textOptions, prefix bool) bool {
+	if prefix && strings.HasPrefix(token, query) {
+		return true
+	}
+	if query == token {
+		return true
+	}
+	distance := fuzzyDistance(opts.fuzziness, query)
+	if distance == 0 {
+		return false
+	}
+	q, t := []rune(query), []rune(token)
+	if opts.prefixLength > 0 {
+		if len(q) < opts.prefixLength || len(t) < opts.prefixLength ||
+			string(q[:opts.prefixLength]) != string(t[:opts.prefixLength]) {
+			return false
+		}
+	}
+	if prefix && len(t) > len(q) {
+		t = t[:len(q)]
+	}
+	return levenshtein(q, t) <= distance
+}
+
+// fuzzyDistance turns a fuzziness value into the allowed edit distance
+func fuzzyDistance(fuzziness, term string) int {
+	switch {
+	case fuzziness == "":
+		return 0
+	case strings.HasPrefix(strings.ToUpper(fuzziness), "AUTO"):
+		low, high := 3, 6
+		if parts := strings.Split(strings.TrimPrefix(strings.ToUpper(fuzziness), "AUTO:"), ","); len(parts) == 2 {
+			low, high = intOf(parts[0], low), intOf(parts[1], high)
+		}
+		n := len([]rune(term))
+		switch {
+		case n < low:
+			return 0
+		case n < high:
+			return 1
+		}
+		return 2
+	}
+	return intOf(fuzziness, 0)
+}
+
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = minInt(minInt(prev[j]+1, cur[j-1]+1), prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+// analyze folds Vietnamese diacritics and lowercases, close to the vi_folded analyzer
+func analyze(text string) []string {
+	folded := utils.TransformString(text, false)
+	return strings.FieldsFunc(folded, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+func evaluateTerm(idx *index, kind string, raw interface{}, src map[string]interface{}) (bool, float64, error) {
+	body, _ := raw.(map[string]interface{})
+	for field, params := range body {
+		value, boost := params, 1.0
+		if p, ok := params.(map[string]interface{}); ok {
+			value, boost = p["value"], boostOf(p)
+		}
+		for _, v := range termValues(idx, field, src) {
+			want := fmt.Sprint(value)
+			if (kind == "term" && v == want) || (kind == "prefix" && strings.HasPrefix(v, want)) {
+				return true, boost, nil
+			}
+		}
+		return false, 0, nil
+	}
+	return false, 0, nil
+}
+
+func evaluateTerms(idx *index, raw interface{}, src map[string]interface{}) (bool, float64, error) {
+	body, _ := raw.(map[string]interface{})
+	boost := boostOf(body)
+	for field, values := range body {
+		if field == "boost" {
+			continue
+		}
+		have := termValues(idx, field, src)
+		for _, want := range toList(values) {
+			for _, v := range have {
+				if v == fmt.Sprint(want) {
+					return true, boost, nil
+				}
+			}
+		}
+		return false, 0, nil
+	}
+	return false, 0, nil
+}
+
+// termValues are the indexed terms of a field: tokens for text fields, whole values otherwise
+func termValues(idx *index, field string, src map[string]interface{}) []string {
+	var out []string
+	text := idx.fieldType(field) == "text"
+	for _, v := range scalars(src, field) {
+		if text {
+			out = append(out, analyze(fmt.Sprint(v))...)
+		} else {
+			out = append(out, fmt.Sprint(v))
+		}
+	}
+	return out
+}
+
+func evaluateRange(raw interface{}, src map[string]interface{}) (bool, float64, error) {
+	body, _ := raw.(map[string]interface{})
+	for field, params := range body {
+		bounds, _ := params.(map[string]interface{})
+		for _, v := range scalars(src, field) {
+			if inRange(v, bounds) {
+				return true, boostOf(bounds), nil
+			}
+		}
+		return false, 0, nil
+	}
+	return false, 0, nil
+}
+
+func inRange(v interface{}, bounds map[string]interface{}) bool {
+	for op, bound := range bounds {
+		if bound == nil {
+			continue
+		}
+		var ok bool
+		switch c := compareValues(v, bound); op {
+		case "gt":
+			ok = c > 0
+		case "gte":
+			ok = c >= 0
+		case "lt":
+			ok = c < 0
+		case "lte":
+			ok = c <= 0
+		default:
+			continue
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// evaluateNested matches the inner query against each nested object on its
+// own, so conditions on two fields must hold for the same object
+func evaluateNested(idx *index, raw interface{}, src map[string]interface{}) (bool, float64, error) {
+	body, _ := raw.(map[string]interface{})
+	nestedPath, _ := body["path"].(string)
+
+	best := 0.0
+	found := false
+	for _, obj := range objects(src, nestedPath) {
+		single := withPath(src, nestedPath, obj)
+		ok, score, err := evaluate(idx, body["query"], single)
+		if err != nil {
+			return false, 0, err
+		}
+		if ok {
+			found = true
+			best = math.Max(best, score)
+		}
+	}
+	return found, best * boostOf(body), nil
+}
+
+// evaluateFunctionScore applies weight functions, decay and field value
+// factor functions can't be reproduced faithfully and leave the score as is
+func evaluateFunctionScore(idx *index, raw interface{}, src map[string]interface{}) (bool, float64, error) {
+	body, _ := raw.(map[string]interface{})
+	ok, score := true, 1.0
+	if q, has := body["query"]; has {
+		var err error
+		if ok, score, err = evaluate(idx, q, src); err != nil || !ok {
+			return false, 0, err
+		}
+	}
+	for _, f := range toList(body["functions"]) {
+		fn, _ := f.(map[string]interface{})
+		weight, has := fn["weight"].(float64)
+		if !has {
+			continue
+		}
+		if filter, has := fn["filter"]; has {
+			matched, _, err := evaluate(idx, filter, src)
+			if err != nil {
+				return false, 0, err
+			}
+			if !matched {
+				continue
+			}
+		}
+		score *= weight
+	}
+	return ok, score * boostOf(body), nil
+}
+
+// scalars collects the leaf values of a dotted field, fanning out over
+// arrays. A subfield such as name.exact falls back to its parent field.
+func scalars(src map[string]interface{}, field string) []interface{} {
+	for f := field; f != ""; {
+		var out []interface{}
+		collect(src, strings.Split(f, "."), &out)
+		if len(out) > 0 {
+			return out
+		}
+		i := strings.LastIndex(f, ".")
+		if i < 0 {
+			break
+		}
+		f = f[:i]
+	}
+	return nil
+}
+
+func collect(v interface{}, segments []string, out *[]interface{}) {
+	switch t := v.(type) {
+	case []interface{}:
+		for _, item := range t {
+			collect(item, segments, out)
+		}
+	case map[string]interface{}:
+		if len(segments) > 0 {
+			collect(t[segments[0]], segments[1:], out)
+		}
+	case nil:
+	default:
+		if len(segments) == 0 {
+			*out = append(*out, t)
+		}
+	}
+}
+
+// objects returns the objects stored under a nested path
+func objects(src map[string]interface{}, nestedPath string) []map[string]interface{} {
+	var cur []interface{} = []interface{}{src}
+	for _, seg := range strings.Split(nestedPath, ".") {
+		var next []interface{}
+		for _, c := range cur {
+			m, ok := c.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			switch v := m[seg].(type) {
+			case []interface{}:
+				next = append(next, v...)
+			case nil:
+			default:
+				next = append(next, v)
+			}
+		}
+		cur = next
+	}
+	var out []map[string]interface{}
+	for _, c := range cur {
+		if m, ok := c.(map[string]interface{}); ok {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+// withPath returns a shallow copy of src holding only obj under nestedPath
+func withPath(src map[string]interface{}, nestedPath string, obj map[string]interface{}) map[string]interface{} {
+	segments := strings.SplitN(nestedPath, ".", 2)
+	out := make(map[string]interface{}, len(src))
+	for k, v := range src {
+		out[k] = v
+	}
+	if len(segments) == 1 {
+		out[segments[0]] = obj
+		return out
+	}
+	child, _ := src[segments[0]].(map[string]interface{})
+	out[segments[0]] = withPath(child, segments[1], obj)
+	return out
+}
+
+// fieldType looks a dotted field up in the index mapping, multi-fields
+// included. Unknown fields are treated as text.
+func (idx *index) fieldType(field string) string {
+	props, _ := idx.mapping["mappings"].(map[string]interface{})
+	if props == nil {
+		props = idx.mapping
+	}
+	props, _ = props["properties"].(map[string]interface{})
+
+	segments := strings.Split(field, ".")
+	for i, seg := range segments {
+		def, ok := props[seg].(map[string]interface{})
+		if !ok {
+			return "text"
+		}
+		if i == len(segments)-1 {
+			if t, ok := def["type"].(string); ok {
+				return t
+			}
+			return "object"
+		}
+		if sub, ok := def["properties"].(map[string]interface{}); ok {
+			props = sub
+			continue
+		}
+		if fields, ok := def["fields"].(map[string]interface{}); ok {
+			props = fields
+			continue
+		}
+		return "text"
+	}
+	return "text"
+}
+
+// compareValues orders numbers numerically, RFC 3339 dates chronologically
+// and anything else as strings
+func compareValues(a, b interface{}) int {
+	if fa, ok := number(a); ok {
+		if fb, ok := number(b); ok {
+			switch {
+			case fa < fb:
+				return -1
+			case fa > fb:
+				return 1
+			}
+			return 0
+		}
+	}
+	sa, sb := fmt.Sprint(a), fmt.Sprint(b)
+	if ta, err := time.Parse(time.RFC3339Nano, sa); err == nil {
+		if tb, err := time.Parse(time.RFC3339Nano, sb); err == nil {
+			return ta.Compare(tb)
+		}
+	}
+	return strings.Compare(sa, sb)
+}
+
+func number(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case bool:
+		return 0, false
+	}
+	return 0, false
+}
+
+func boostOf(raw interface{}) float64 {
+	if m, ok := raw.(map[string]interface{}); ok {
+		if b, ok := m["boost"].(float64); ok {
+			return b
+		}
+	}
+	return 1
+}
+
+func splitBoost(field string) (string, float64) {
+	name, boost, found := strings.Cut(field, "^")
+	if !found {
+		return field, 1
+	}
+	b, err := strconv.ParseFloat(boost, 64)
+	if err != nil {
+		return name, 1
+	}
+	return name, b
+}
+
+// toList accepts a single value or a list of them
+func toList(raw interface{}) []interface{} {
+	switch v := raw.(type) {
+	case nil:
+		return nil
+	case []interface{}:
+		return v
+	default:
+		return []interface{}{v}
+	}
+}
+
+func intOf(raw interface{}, def int) int {
+	switch v := raw.(type) {
+	case float64:
+		return int(v)
+	case string:
+		if n, err := strconv.Atoi(strings.TrimSpace(v)); err == nil {
+			return n
+		}
+	}
+	return def
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,461 @@
+package esfake
+
+import (
+	"business/pkg/es"
+	"context"
+	"fmt"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+)
+
+const defaultSize = 10
+
+// Search runs a search body against the indices of indexName, or of the
+// point in time in the body when indexName is empty. Supported: query,
+// post_filter, from/size, sort with search_after, _source filtering and
+// pit and indices_boost. Aggregations and highlight are accepted but left empty.
+func (c *Client) Search(ctx context.Context, indexName string, query interface{}) (*es.SearchResult, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var body map[string]interface{}
+	if err := roundTrip(query, &body); err != nil {
+		return nil, fmt.Errorf("failed to encode query: %w", err)
+	}
+
+	var pitID string
+	if pit, ok := body["pit"].(map[string]interface{}); ok {
+		pitID, _ = pit["id"].(string)
+	}
+	indices, err := c.searchIndices(indexName, pitID)
+	if err != nil {
+		return nil, err
+	}
+
+	sorts, err := parseSort(body["sort"])
+	if err != nil {
+		return nil, badRequest(err)
+	}
+	boosts, err := c.indexBoosts(body["indices_boost"])
+	if err != nil {
+		return nil, err
+	}
+
+	var hits []*hit
+	for _, idx := range indices {
+		for _, doc := range idx.docs {
+			ok, score := true, 1.0
+			if q, has := body["query"]; has {
+				if ok, score, err = evaluate(idx, q, doc.source); err != nil {
+					return nil, badRequest(err)
+				}
+			}
+			if ok {
+				if f, has := body["post_filter"]; has {
+					if ok, _, err = evaluate(idx, f, doc.source); err != nil {
+						return nil, badRequest(err)
+					}
+				}
+			}
+			if ok {
+				if boost, has := boosts[idx.name]; has {
+					score *= boost
+				}
+				hits = append(hits, &hit{index: idx.name, doc: doc, score: score})
+			}
+		}
+	}
+
+	for _, h := range hits {
+		h.sort = sortValues(sorts, h)
+	}
+	sort.SliceStable(hits, func(i, j int) bool {
+		return compareSort(sorts, hits[i].sort, hits[j].sort) < 0
+	})
+	total := len(hits)
+
+	if after, ok := body["search_after"].([]interface{}); ok {
+		if len(after) != len(sorts) {
+			return nil, badRequest(fmt.Errorf("search_after has %d values but sort has %d", len(after), len(sorts)))
+		}
+		start := sort.Search(len(hits), func(i int) bool {
+			return compareSort(sorts, hits[i].sort, after) > 0
+		})
+		hits = hits[start:]
+	}
+
+	from, size := intOf(body["from"], 0), intOf(body["size"], defaultSize)
+	if from > len(hits) {
+		from = len(hits)
+	}
+	if end := from + size; end < len(hits) {
+		hits = hits[from:end]
+	} else {
+		hits = hits[from:]
+	}
+
+	response := map[string]interface{}{
+		"hits": map[string]interface{}{
+			"total": map[string]interface{}{"value": total, "relation": "eq"},
+			"hits":  renderHits(hits, body["_source"], body["sort"] != nil),
+		},
+	}
+	if pitID != "" {
+		response["pit_id"] = pitID
+	}
+
+	var result es.SearchResult
+	if err := roundTrip(response, &result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return &result, nil
+}
+
+// SearchIndices runs Search over all the indices with their indices_boost,
+// then once more per index with size 0 to fill IndexTotals. Missing indices
+// are skipped, like ignore_unavailable does.
+func (c *Client) SearchIndices(ctx context.Context, indices []es.IndexBoost, query interface{}) (*es.SearchResult, error) {
+	if len(indices) == 0 {
+		return nil, badRequest(fmt.Errorf("no index to search"))
+	}
+	var body map[string]interface{}
+	if err := roundTrip(query, &body); err != nil {
+		return nil, fmt.Errorf("failed to encode query: %w", err)
+	}
+
+	totals := make(map[string]int64, len(indices))
+	available := make([]es.IndexBoost, 0, len(indices))
+	c.mu.Lock()
+	for _, idx := range indices {
+		totals[idx.Index] = 0
+		if found, err := c.resolve(idx.Index); err == nil && len(found) > 0 {
+			available = append(available, idx)
+		}
+	}
+	c.mu.Unlock()
+	if len(available) == 0 {
+		return &es.SearchResult{IndexTotals: totals}, nil
+	}
+	indices = available
+
+	names := make([]string, 0, len(indices))
+	boosts := make([]interface{}, 0, len(indices))
+	for _, idx := range indices {
+		names = append(names, idx.Index)
+		if idx.Boost > 0 {
+			boosts = append(boosts, map[string]interface{}{idx.Index: idx.Boost})
+		}
+	}
+	body["indices_boost"] = boosts
+
+	result, err := c.Search(ctx, strings.Join(names, ","), body)
+	if err != nil {
+		return nil, err
+	}
+
+	count := map[string]interface{}{"size": 0}
+	for _, key := range []string{"query", "post_filter"} {
+		if v, ok := body[key]; ok {
+			count[key] = v
+		}
+	}
+	result.IndexTotals = totals
+	for _, idx := range indices {
+		r, err := c.Search(ctx, idx.Index, count)
+		if err != nil {
+			return nil, err
+		}
+		result.IndexTotals[idx.Index] = r.Hits.Total.Value
+	}
+	return result, nil
+}
+
+// indexBoosts resolves indices_boost to a boost per index, the first entry
+// naming an index wins like in Elasticsearch
+func (c *Client) indexBoosts(raw interface{}) (map[string]float64, error) {
+	boosts := map[string]float64{}
+	for _, entry := range toList(raw) {
+		m, ok := entry.(map[string]interface{})
+		if !ok {
+			return nil, badRequest(fmt.Errorf("unsupported indices_boost %v", entry))
+		}
+		for name, v := range m {
+			boost, ok := number(v)
+			if !ok {
+				return nil, badRequest(fmt.Errorf("indices_boost of %s is not a number", name))
+			}
+			indices, err := c.resolve(name)
+			if err != nil {
+				return nil, err
+			}
+			for _, idx := range indices {
+				if _, seen := boosts[idx.name]; !seen {
+					boosts[idx.name] = boost
+				}
+			}
+		}
+	}
+	return boosts, nil
+}
+
+func (c *Client) searchIndices(indexName, pitID string) ([]*index, error) {
+	if indexName != "" {
+		return c.resolve(indexName)
+	}
+	names, ok := c.pits[pitID]
+	if !ok {
+		return nil, &es.ResponseError{
+			Op: "search", StatusCode: http.StatusNotFound,
+			Type: "search_context_missing_exception", Reason: "no search context found for id [" + pitID + "]",
+		}
+	}
+	var indices []*index
+	for _, name := range names {
+		if idx, ok := c.indices[name]; ok {
+			indices = append(indices, idx)
+		}
+	}
+	return indices, nil
+}
+
+type hit struct {
+	index string
+	doc   *document
+	score float64
+	sort  []interface{}
+}
+
+func renderHits(hits []*hit, source interface{}, withSort bool) []interface{} {
+	out := make([]interface{}, 0, len(hits))
+	for _, h := range hits {
+		rendered := map[string]interface{}{
+			"_index": h.index,
+			"_id":    h.doc.id,
+			"_score": h.score,
+		}
+		if src := filterSource(h.doc.source, source); src != nil {
+			rendered["_source"] = src
+		}
+		if withSort {
+			rendered["sort"] = h.sort
+		}
+		out = append(out, rendered)
+	}
+	return out
+}
+
+type sortField struct {
+	field   string
+	desc    bool
+	missing string // _first or _last
+	mode    string
+}
+
+// parseSort reads the sort clause, without one hits are ordered by score
+// and then by insertion order
+func parseSort(raw interface{}) ([]sortField, error) {
+	if raw == nil {
+		return []sortField{{field: "_score", desc: true}, {field: "_doc"}}, nil
+	}
+	list, ok := raw.([]interface{})
+	if !ok {
+		list = []interface{}{raw}
+	}
+
+	var sorts []sortField
+	for _, item := range list {
+		switch s := item.(type) {
+		case string:
+			sorts = append(sorts, sortField{field: s, desc: s == "_score"})
+		case map[string]interface{}:
+			for field, opts := range s {
+				sf := sortField{field: field, desc: field == "_score"}
+				switch o := opts.(type) {
+				case string:
+					sf.desc = o == "desc"
+				case map[string]interface{}:
+					if order, ok := o["order"].(string); ok {
+						sf.desc = order == "desc"
+					}
+					if missing, ok := o["missing"].(string); ok {
+						sf.missing = missing
+					}
+					if mode, ok := o["mode"].(string); ok {
+						sf.mode = mode
+					}
+				}
+				sorts = append(sorts, sf)
+			}
+		default:
+			return nil, fmt.Errorf("unsupported sort %v", item)
+		}
+	}
+	return sorts, nil
+}
+
+func sortValues(sorts []sortField, h *hit) []interface{} {
+	values := make([]interface{}, 0, len(sorts))
+	for _, s := range sorts {
+		switch s.field {
+		case "_score":
+			values = append(values, h.score)
+		case "_doc", "_shard_doc":
+			values = append(values, float64(h.doc.order))
+		default:
+			values = append(values, pickSortValue(scalars(h.doc.source, s.field), s))
+		}
+	}
+	return values
+}
+
+// pickSortValue reduces a multi-valued field, min for ascending and max for
+// descending sorts unless a mode is set
+func pickSortValue(values []interface{}, s sortField) interface{} {
+	if len(values) == 0 {
+		return nil
+	}
+	mode := s.mode
+	if mode == "" {
+		mode = "min"
+		if s.desc {
+			mode = "max"
+		}
+	}
+	picked := values[0]
+	for _, v := range values[1:] {
+		c := compareValues(v, picked)
+		if (mode == "min" && c < 0) || (mode == "max" && c > 0) {
+			picked = v
+		}
+	}
+	return picked
+}
+
+func compareSort(sorts []sortField, a, b []interface{}) int {
+	for i, s := range sorts {
+		if i >= len(a) || i >= len(b) {
+			break
+		}
+		va, vb := a[i], b[i]
+		if va == nil || vb == nil {
+			if va == nil && vb == nil {
+				continue
+			}
+			// missing values go last unless asked otherwise, whatever the order
+			first := s.missing == "_first"
+			if (va == nil) == first {
+				return -1
+			}
+			return 1
+		}
+		c := compareValues(va, vb)
+		if s.desc {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// filterSource applies a _source parameter: false, a list of fields or an
+// object of includes and excludes, wildcards allowed
+func filterSource(src map[string]interface{}, param interface{}) map[string]interface{} {
+	var includes, excludes []string
+	switch p := param.(type) {
+	case nil:
+		return src
+	case bool:
+		if !p {
+			return nil
+		}
+		return src
+	case string:
+		includes = []string{p}
+	case []interface{}:
+		includes = toStrings(p)
+	case map[string]interface{}:
+		if in, ok := p["includes"].([]interface{}); ok {
+			includes = toStrings(in)
+		}
+		if ex, ok := p["excludes"].([]interface{}); ok {
+			excludes = toStrings(ex)
+		}
+	}
+	return filterObject(src, "", includes, excludes)
+}
+
+func filterObject(obj map[string]interface{}, prefix string, includes, excludes []string) map[string]interface{} {
+	out := map[string]interface{}{}
+	for key, value := range obj {
+		full := key
+		if prefix != "" {
+			full = prefix + "." + key
+		}
+		if matchesAny(excludes, full) {
+			continue
+		}
+		if len(includes) == 0 || matchesAny(includes, full) {
+			out[key] = value
+			continue
+		}
+		// keep walking when an include targets a field below this one
+		if !hasIncludeBelow(includes, full) {
+			continue
+		}
+		switch v := value.(type) {
+		case map[string]interface{}:
+			if sub := filterObject(v, full, includes, excludes); len(sub) > 0 {
+				out[key] = sub
+			}
+		case []interface{}:
+			var items []interface{}
+			for _, item := range v {
+				if m, ok := item.(map[string]interface{}); ok {
+					if sub := filterObject(m, full, includes, excludes); len(sub) > 0 {
+						items = append(items, sub)
+					}
+				}
+			}
+			if len(items) > 0 {
+				out[key] = items
+			}
+		}
+	}
+	return out
+}
+
+func matchesAny(patterns []string, field string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, field); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func hasIncludeBelow(includes []string, field string) bool {
+	for _, p := range includes {
+		if strings.HasPrefix(p, field+".") || strings.HasPrefix(p, "*") {
+			return true
+		}
+	}
+	return false
+}
+
+func toStrings(values []interface{}) []string {
+	out := make([]string, 0, len(values))
+	for _, v := range values {
+		if s, ok := v.(string); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+func badRequest(err error) error {
+	return &es.ResponseError{Op: "search", StatusCode: http.StatusBadRequest, Type: "parsing_exception", Reason: err.Error()}
+}
@@ -0,0 +1,183 @@
+package esfake
+
+import (
+	"business/pkg/es"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// PutSearchTemplate stores the mustache source of a template
+func (c *Client) PutSearchTemplate(ctx context.Context, templateID string, source string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.templates[templateID] = source
+	return nil
+}
+
+func (c *Client) DeleteSearchTemplate(ctx context.Context, templateID string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.templates[templateID]; !ok {
+		return templateNotFound("delete search template", templateID)
+	}
+	delete(c.templates, templateID)
+	return nil
+}
+
+func (c *Client) RenderSearchTemplate(ctx context.Context, templateID string, params map[string]interface{}) (json.RawMessage, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	body, err := c.renderTemplate("render search template", templateID, params)
+	if err != nil {
+		return nil, err
+	}
+	return json.RawMessage(body), nil
+}
+
+// SearchTemplate renders the template and runs it like Search does
+func (c *Client) SearchTemplate(ctx context.Context, indexName, templateID string, params map[string]interface{}) (*es.SearchResult, error) {
+	c.mu.Lock()
+	body, err := c.renderTemplate("search template", templateID, params)
+	c.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	return c.Search(ctx, indexName, json.RawMessage(body))
+}
+
+// SearchTemplateSource returns the source of a stored template, empty when it is missing
+func (c *Client) SearchTemplateSource(templateID string) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.templates[templateID]
+}
+
+func (c *Client) renderTemplate(op, templateID string, params map[string]interface{}) (string, error) {
+	source, ok := c.templates[templateID]
+	if !ok {
+		return "", templateNotFound(op, templateID)
+	}
+	out, err := renderMustache(source, params)
+	if err != nil {
+		return "", &es.ResponseError{Op: op, StatusCode: http.StatusBadRequest, Type: "general_script_exception", Reason: err.Error()}
+	}
+	if !json.Valid([]byte(out)) {
+		return "", &es.ResponseError{Op: op, StatusCode: http.StatusBadRequest, Type: "x_content_parse_exception", Reason: "rendered template is not json"}
+	}
+	return out, nil
+}
+
+// renderMustache supports the mustache Elasticsearch templates use the most:
+// {{var}} escaped for json, {{{var}}} raw, {{#toJson}}var{{/toJson}},
+// {{#join}}var{{/join}} and {{#var}}..{{/var}} or {{^var}}..{{/var}}
+// sections, which are only conditionals here
+func renderMustache(source string, params map[string]interface{}) (string, error) {
+	var out strings.Builder
+	for {
+		start := strings.Index(source, "{{")
+		if start < 0 {
+			out.WriteString(source)
+			return out.String(), nil
+		}
+		out.WriteString(source[:start])
+		source = source[start:]
+
+		if strings.HasPrefix(source, "{{{") {
+			end := strings.Index(source, "}}}")
+			if end < 0 {
+				return "", fmt.Errorf("unclosed tag")
+			}
+			out.WriteString(fmt.Sprint(params[strings.TrimSpace(source[3:end])]))
+			source = source[end+3:]
+			continue
+		}
+
+		end := strings.Index(source, "}}")
+		if end < 0 {
+			return "", fmt.Errorf("unclosed tag")
+		}
+		tag := strings.TrimSpace(source[2:end])
+		source = source[end+2:]
+
+		switch {
+		case strings.HasPrefix(tag, "#") || strings.HasPrefix(tag, "^"):
+			name := strings.TrimSpace(tag[1:])
+			closing := "{{/" + name + "}}"
+			inner, rest, found := strings.Cut(source, closing)
+			if !found {
+				return "", fmt.Errorf("section %s is not closed", name)
+			}
+			source = rest
+
+			switch name {
+			case "toJson":
+				data, err := json.Marshal(params[strings.TrimSpace(inner)])
+				if err != nil {
+					return "", err
+				}
+				out.Write(data)
+				continue
+			case "join":
+				values, _ := params[strings.TrimSpace(inner)].([]interface{})
+				parts := make([]string, 0, len(values))
+				for _, v := range values {
+					parts = append(parts, fmt.Sprint(v))
+				}
+				out.WriteString(strings.Join(parts, ","))
+				continue
+			}
+			if truthy(params[name]) == (tag[0] == '#') {
+				rendered, err := renderMustache(inner, params)
+				if err != nil {
+					return "", err
+				}
+				out.WriteString(rendered)
+			}
+		case strings.HasPrefix(tag, "/"):
+			return "", fmt.Errorf("unexpected closing tag %s", tag)
+		default:
+			value, ok := params[tag]
+			if !ok {
+				continue
+			}
+			if s, isString := value.(string); isString {
+				data, _ := json.Marshal(s)
+				out.Write(data[1 : len(data)-1])
+				continue
+			}
+			data, err := json.Marshal(value)
+			if err != nil {
+				return "", err
+			}
+			out.Write(data)
+		}
+	}
+}
+
+func truthy(v interface{}) bool {
+	switch v := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case string:
+		return v != ""
+	case []interface{}:
+		return len(v) > 0
+	}
+	return true
+}
+
+func templateNotFound(op, id string) error {
+	return &es.ResponseError{
+		Op: op, StatusCode: http.StatusNotFound,
+		Type: "resource_not_found_exception", Reason: "unable to find script [" + id + "] in cluster state",
+	}
+}
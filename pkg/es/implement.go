@@ -4,13 +4,19 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"strings"
+
 	"github.com/elastic/go-elasticsearch/v8/esapi"
 	"gitlab.com/goxp/cloud0/logger"
 )
 
 // Ping checks connection to ElasticSearch
 func (c *esClient) Ping(ctx context.Context) error {
+	ctx, cancel := withTimeout(ctx, c.cfg.AdminTimeout)
+	defer cancel()
+
 	res, err := c.client.Ping(c.client.Ping.WithContext(ctx))
 	if err != nil {
 		return fmt.Errorf("ping failed: %w", err)
@@ -26,6 +32,10 @@ func (c *esClient) Ping(ctx context.Context) error {
 
 // CreateIndex creates a new index with mapping
 func (c *esClient) CreateIndex(ctx context.Context, indexName string, mapping interface{}) error {
+	ctx, cancel := withTimeout(ctx, c.cfg.AdminTimeout)
+	defer cancel()
+	indexName = c.index(indexName)
+
 	log := logger.WithCtx(ctx, "EsService.CreateIndex")
 	var body []byte
 	var err error
@@ -36,6 +46,18 @@ func (c *esClient) CreateIndex(ctx context.Context, indexName string, mapping in
 			return fmt.Errorf("failed to marshal mapping: %w", err)
 		}
 	}
+	if c.cfg.IndexPrefix != "" && body != nil {
+		// synonyms sets are prefixed like indices, see PutSynonymsSet
+		var decoded map[string]interface{}
+		if err := json.Unmarshal(body, &decoded); err == nil {
+			if settings, ok := decoded["settings"].(map[string]interface{}); ok {
+				renameSynonymsSets(settings, c.index)
+				if body, err = json.Marshal(decoded); err != nil {
+					return fmt.Errorf("failed to marshal mapping: %w", err)
+				}
+			}
+		}
+	}
 
 	res, err := c.client.Indices.Create(
 		indexName,
@@ -49,12 +71,15 @@ func (c *esClient) CreateIndex(ctx context.Context, indexName string, mapping in
 	defer res.Body.Close()
 
 	if res.IsError() {
-		// If index already exists, it's not an error we should panic on
-		if res.StatusCode == 400 {
-			log.Warnf("Index %s may already exist or mapping conflict", indexName)
+		err := newResponseError("create index", res)
+		// An existing index is fine, its mapping is checked with PlanMapping.
+		// Any other 400 is a mapping the cluster rejected.
+		var resErr *ResponseError
+		if errors.As(err, &resErr) && resErr.Type == "resource_already_exists_exception" {
+			log.Infof("Index %s already exists", indexName)
 			return nil
 		}
-		return fmt.Errorf("create index error: %s", res.String())
+		return err
 	}
 
 	return nil
@@ -62,6 +87,10 @@ func (c *esClient) CreateIndex(ctx context.Context, indexName string, mapping in
 
 // DeleteIndex deletes an index
 func (c *esClient) DeleteIndex(ctx context.Context, indexName string) error {
+	ctx, cancel := withTimeout(ctx, c.cfg.AdminTimeout)
+	defer cancel()
+	indexName = c.index(indexName)
+
 	res, err := c.client.Indices.Delete(
 		[]string{indexName},
 		c.client.Indices.Delete.WithContext(ctx),
@@ -72,7 +101,7 @@ func (c *esClient) DeleteIndex(ctx context.Context, indexName string) error {
 	defer res.Body.Close()
 
 	if res.IsError() {
-		return fmt.Errorf("delete index error: %s", res.String())
+		return newResponseError("delete index", res)
 	}
 
 	return nil
@@ -80,6 +109,10 @@ func (c *esClient) DeleteIndex(ctx context.Context, indexName string) error {
 
 // IndexExists checks if an index exists
 func (c *esClient) IndexExists(ctx context.Context, indexName string) (bool, error) {
+	ctx, cancel := withTimeout(ctx, c.cfg.AdminTimeout)
+	defer cancel()
+	indexName = c.index(indexName)
+
 	res, err := c.client.Indices.Exists(
 		[]string{indexName},
 		c.client.Indices.Exists.WithContext(ctx),
@@ -94,6 +127,10 @@ func (c *esClient) IndexExists(ctx context.Context, indexName string) (bool, err
 
 // IndexDocument indexes a single document
 func (c *esClient) IndexDocument(ctx context.Context, indexName, docID string, doc interface{}) error {
+	ctx, cancel := withTimeout(ctx, c.cfg.DocumentTimeout)
+	defer cancel()
+	indexName = c.index(indexName)
+
 	data, err := json.Marshal(doc)
 	if err != nil {
 		return fmt.Errorf("failed to marshal document: %w", err)
@@ -119,51 +156,189 @@ func (c *esClient) IndexDocument(ctx context.Context, indexName, docID string, d
 	return nil
 }
 
-// BulkIndex performs bulk indexing
-func (c *esClient) BulkIndex(ctx context.Context, indexName string, docs []BulkDocument) error {
-	if len(docs) == 0 {
-		return nil
+// GetDocument reads a document by id, errors.Is(err, ErrNotFound) when missing
+func (c *esClient) GetDocument(ctx context.Context, indexName, docID string) (*Document, error) {
+	ctx, cancel := withTimeout(ctx, c.cfg.DocumentTimeout)
+	defer cancel()
+	indexName = c.index(indexName)
+
+	req := esapi.GetRequest{
+		Index:      indexName,
+		DocumentID: docID,
+	}
+
+	res, err := req.Do(ctx, c.client)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get document: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return nil, newResponseError("get document", res)
+	}
+
+	var doc Document
+	if err := json.NewDecoder(res.Body).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	doc.Index = c.trimIndex(doc.Index)
+
+	return &doc, nil
+}
+
+// UpdateDocument applies a partial or scripted update
+func (c *esClient) UpdateDocument(ctx context.Context, indexName, docID string, update DocumentUpdate) error {
+	ctx, cancel := withTimeout(ctx, c.cfg.DocumentTimeout)
+	defer cancel()
+	indexName = c.index(indexName)
+
+	if update.Doc == nil && update.Script == nil {
+		return fmt.Errorf("update document: doc or script is required")
+	}
+
+	data, err := json.Marshal(update)
+	if err != nil {
+		return fmt.Errorf("failed to marshal update: %w", err)
+	}
+
+	req := esapi.UpdateRequest{
+		Index:           indexName,
+		DocumentID:      docID,
+		Body:            bytes.NewReader(data),
+		IfSeqNo:         update.IfSeqNo,
+		IfPrimaryTerm:   update.IfPrimaryTerm,
+		RetryOnConflict: update.RetryOnConflict,
+	}
+
+	res, err := req.Do(ctx, c.client)
+	if err != nil {
+		return fmt.Errorf("failed to update document: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return newResponseError("update document", res)
 	}
 
+	return nil
+}
+
+// DeleteDocument removes a document by id, errors.Is(err, ErrNotFound) when missing
+func (c *esClient) DeleteDocument(ctx context.Context, indexName, docID string) error {
+	ctx, cancel := withTimeout(ctx, c.cfg.DocumentTimeout)
+	defer cancel()
+	indexName = c.index(indexName)
+
+	req := esapi.DeleteRequest{
+		Index:      indexName,
+		DocumentID: docID,
+	}
+
+	res, err := req.Do(ctx, c.client)
+	if err != nil {
+		return fmt.Errorf("failed to delete document: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return newResponseError("delete document", res)
+	}
+
+	return nil
+}
+
+// DeleteByQuery deletes every document matched by the query body
+func (c *esClient) DeleteByQuery(ctx context.Context, indexName string, query interface{}) (*ByQueryResult, error) {
+	ctx, cancel := withTimeout(ctx, c.cfg.BulkTimeout)
+	defer cancel()
+	indexName = c.index(indexName)
+
 	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(query); err != nil {
+		return nil, fmt.Errorf("failed to encode query: %w", err)
+	}
 
-	for _, doc := range docs {
-		meta := map[string]interface{}{
-			"index": map[string]interface{}{
-				"_index": indexName,
-				"_id":    doc.ID,
-			},
-		}
+	res, err := c.client.DeleteByQuery(
+		[]string{indexName},
+		&buf,
+		c.client.DeleteByQuery.WithContext(ctx),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("delete by query failed: %w", err)
+	}
+	defer res.Body.Close()
 
-		metaJSON, err := json.Marshal(meta)
-		if err != nil {
-			return fmt.Errorf("failed to marshal meta: %w", err)
-		}
+	if res.IsError() {
+		return nil, newResponseError("delete by query", res)
+	}
 
-		buf.Write(metaJSON)
-		buf.WriteByte('\n')
+	var result ByQueryResult
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
 
-		docJSON, err := json.Marshal(doc.Data)
-		if err != nil {
-			return fmt.Errorf("failed to marshal document: %w", err)
-		}
+	return &result, nil
+}
 
-		buf.Write(docJSON)
-		buf.WriteByte('\n')
+// UpdateByQuery runs the script of body on every document its query matches
+func (c *esClient) UpdateByQuery(ctx context.Context, indexName string, body interface{}) (*ByQueryResult, error) {
+	ctx, cancel := withTimeout(ctx, c.cfg.BulkTimeout)
+	defer cancel()
+	indexName = c.index(indexName)
+
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(body); err != nil {
+		return nil, fmt.Errorf("failed to encode body: %w", err)
 	}
 
-	res, err := c.client.Bulk(
-		bytes.NewReader(buf.Bytes()),
-		c.client.Bulk.WithContext(ctx),
-		c.client.Bulk.WithRefresh("true"),
+	res, err := c.client.UpdateByQuery(
+		[]string{indexName},
+		c.client.UpdateByQuery.WithContext(ctx),
+		c.client.UpdateByQuery.WithBody(&buf),
 	)
 	if err != nil {
-		return fmt.Errorf("bulk request failed: %w", err)
+		return nil, fmt.Errorf("update by query failed: %w", err)
 	}
 	defer res.Body.Close()
 
 	if res.IsError() {
-		return fmt.Errorf("bulk error: %s", res.String())
+		return nil, newResponseError("update by query", res)
+	}
+
+	var result ByQueryResult
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return &result, nil
+}
+
+// BulkIndex performs bulk indexing and fails if any document was rejected
+func (c *esClient) BulkIndex(ctx context.Context, indexName string, docs []BulkDocument) error {
+	if len(docs) == 0 {
+		return nil
+	}
+
+	indexer, err := c.NewBulkIndexer(ctx, BulkIndexerConfig{Index: indexName})
+	if err != nil {
+		return err
+	}
+
+	for _, doc := range docs {
+		if err := indexer.Add(ctx, doc); err != nil {
+			_, _ = indexer.Close(ctx)
+			return fmt.Errorf("failed to queue document %s: %w", doc.ID, err)
+		}
+	}
+
+	report, err := indexer.Close(ctx)
+	if err != nil {
+		return fmt.Errorf("bulk index failed: %w", err)
+	}
+	if report.Failed > 0 {
+		first := report.Failures[0]
+		return fmt.Errorf("bulk index error: %d of %d documents failed, first %s: %s",
+			report.Failed, len(docs), first.ID, first.Reason)
 	}
 
 	return nil
@@ -171,29 +346,428 @@ func (c *esClient) BulkIndex(ctx context.Context, indexName string, docs []BulkD
 
 // Search performs a search query
 func (c *esClient) Search(ctx context.Context, indexName string, query interface{}) (*SearchResult, error) {
+	return c.search(ctx, indexName, query)
+}
+
+func (c *esClient) search(ctx context.Context, indexName string, query interface{}, extra ...func(*esapi.SearchRequest)) (*SearchResult, error) {
+	ctx, cancel := withTimeout(ctx, c.cfg.SearchTimeout)
+	defer cancel()
+	indexName = c.index(indexName)
+
 	var buf bytes.Buffer
 	if err := json.NewEncoder(&buf).Encode(query); err != nil {
 		return nil, fmt.Errorf("failed to encode query: %w", err)
 	}
 
-	res, err := c.client.Search(
+	opts := []func(*esapi.SearchRequest){
 		c.client.Search.WithContext(ctx),
-		c.client.Search.WithIndex(indexName),
 		c.client.Search.WithBody(&buf),
-	)
+	}
+	// Searches against a point in time must not name an index
+	if indexName != "" {
+		opts = append(opts, c.client.Search.WithIndex(indexName))
+	}
+	opts = append(opts, extra...)
+
+	res, err := c.client.Search(opts...)
 	if err != nil {
 		return nil, fmt.Errorf("search request failed: %w", err)
 	}
 	defer res.Body.Close()
 
 	if res.IsError() {
-		return nil, fmt.Errorf("search error: %s", res.String())
+		return nil, newResponseError("search", res)
 	}
 
 	var result SearchResult
 	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
 		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
+	for i := range result.Hits.Hits {
+		result.Hits.Hits[i].Index = c.trimIndex(result.Hits.Hits[i].Index)
+	}
 
 	return &result, nil
-}
\ No newline at end of file
+}
+
+// indexTotalsAgg is the aggregation SearchIndices adds to count the hits of each index
+const indexTotalsAgg = "_index_totals"
+
+// SearchIndices runs one search over several indices. Each index gets its
+// indices_boost and a filter bucket on _index, which also matches aliases,
+// to fill IndexTotals. An index that doesn't exist yet is skipped and counts
+// no hits.
+func (c *esClient) SearchIndices(ctx context.Context, indices []IndexBoost, query interface{}) (*SearchResult, error) {
+	if len(indices) == 0 {
+		return nil, errors.New("no index to search")
+	}
+
+	data, err := json.Marshal(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode query: %w", err)
+	}
+	body := map[string]interface{}{}
+	if err := json.Unmarshal(data, &body); err != nil {
+		return nil, fmt.Errorf("failed to encode query: %w", err)
+	}
+
+	// Search prefixes the names it is given, the body needs them prefixed here
+	names := make([]string, 0, len(indices))
+	boosts := make([]map[string]float64, 0, len(indices))
+	filters := make(map[string]interface{}, len(indices))
+	for _, idx := range indices {
+		name := c.index(idx.Index)
+		names = append(names, idx.Index)
+		if idx.Boost > 0 {
+			boosts = append(boosts, map[string]float64{name: idx.Boost})
+		}
+		filters[idx.Index] = map[string]interface{}{"term": map[string]interface{}{"_index": name}}
+	}
+	if len(boosts) > 0 {
+		body["indices_boost"] = boosts
+	}
+	aggs, _ := body["aggs"].(map[string]interface{})
+	if aggs == nil {
+		aggs = map[string]interface{}{}
+	}
+	aggs[indexTotalsAgg] = map[string]interface{}{"filters": map[string]interface{}{"filters": filters}}
+	body["aggs"] = aggs
+
+	result, err := c.search(ctx, strings.Join(names, ","), body, c.client.Search.WithIgnoreUnavailable(true))
+	if err != nil {
+		return nil, err
+	}
+
+	var totals struct {
+		Buckets map[string]struct {
+			DocCount int64 `json:"doc_count"`
+		} `json:"buckets"`
+	}
+	if raw, ok := result.Aggregations[indexTotalsAgg]; ok {
+		if err := json.Unmarshal(raw, &totals); err != nil {
+			return nil, fmt.Errorf("failed to decode index totals: %w", err)
+		}
+		delete(result.Aggregations, indexTotalsAgg)
+	}
+	result.IndexTotals = make(map[string]int64, len(indices))
+	for _, idx := range indices {
+		result.IndexTotals[idx.Index] = totals.Buckets[idx.Index].DocCount
+	}
+	return result, nil
+}
+
+// OpenPointInTime opens a point in time on an index and returns its id
+func (c *esClient) OpenPointInTime(ctx context.Context, indexName string, keepAlive string) (string, error) {
+	ctx, cancel := withTimeout(ctx, c.cfg.SearchTimeout)
+	defer cancel()
+	indexName = c.index(indexName)
+
+	res, err := c.client.OpenPointInTime(
+		[]string{indexName},
+		keepAlive,
+		c.client.OpenPointInTime.WithContext(ctx),
+	)
+	if err != nil {
+		return "", fmt.Errorf("open point in time failed: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return "", fmt.Errorf("open point in time error: %s", res.String())
+	}
+
+	var body struct {
+		ID string `json:"id"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return body.ID, nil
+}
+
+// ClosePointInTime releases a point in time before its keep alive expires
+func (c *esClient) ClosePointInTime(ctx context.Context, pitID string) error {
+	ctx, cancel := withTimeout(ctx, c.cfg.SearchTimeout)
+	defer cancel()
+
+	body, err := json.Marshal(map[string]string{"id": pitID})
+	if err != nil {
+		return fmt.Errorf("failed to marshal pit id: %w", err)
+	}
+
+	res, err := c.client.ClosePointInTime(
+		c.client.ClosePointInTime.WithContext(ctx),
+		c.client.ClosePointInTime.WithBody(bytes.NewReader(body)),
+	)
+	if err != nil {
+		return fmt.Errorf("close point in time failed: %w", err)
+	}
+	defer res.Body.Close()
+
+	// 404 means the pit already expired, nothing left to release
+	if res.IsError() && res.StatusCode != 404 {
+		return fmt.Errorf("close point in time error: %s", res.String())
+	}
+
+	return nil
+}
+
+// RefreshIndex makes recent writes on an index visible to search
+func (c *esClient) RefreshIndex(ctx context.Context, indexName string) error {
+	ctx, cancel := withTimeout(ctx, c.cfg.AdminTimeout)
+	defer cancel()
+	indexName = c.index(indexName)
+
+	res, err := c.client.Indices.Refresh(
+		c.client.Indices.Refresh.WithContext(ctx),
+		c.client.Indices.Refresh.WithIndex(indexName),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to refresh index: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return fmt.Errorf("refresh index error: %s", res.String())
+	}
+
+	return nil
+}
+
+// Count returns the number of documents in an index or alias
+func (c *esClient) Count(ctx context.Context, indexName string) (int64, error) {
+	ctx, cancel := withTimeout(ctx, c.cfg.AdminTimeout)
+	defer cancel()
+	indexName = c.index(indexName)
+
+	res, err := c.client.Count(
+		c.client.Count.WithContext(ctx),
+		c.client.Count.WithIndex(indexName),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("count request failed: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return 0, fmt.Errorf("count error: %s", res.String())
+	}
+
+	var body struct {
+		Count int64 `json:"count"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		return 0, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return body.Count, nil
+}
+
+// GetMapping returns the "mappings" object of an index, or of the single
+// index behind an alias. errors.Is(err, ErrNotFound) when it doesn't exist.
+func (c *esClient) GetMapping(ctx context.Context, indexName string) (map[string]interface{}, error) {
+	ctx, cancel := withTimeout(ctx, c.cfg.AdminTimeout)
+	defer cancel()
+	indexName = c.index(indexName)
+
+	res, err := c.client.Indices.GetMapping(
+		c.client.Indices.GetMapping.WithContext(ctx),
+		c.client.Indices.GetMapping.WithIndex(indexName),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get mapping: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return nil, newResponseError("get mapping", res)
+	}
+
+	var body map[string]struct {
+		Mappings map[string]interface{} `json:"mappings"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	if len(body) != 1 {
+		return nil, fmt.Errorf("get mapping: %s resolves to %d indices", indexName, len(body))
+	}
+	for _, index := range body {
+		return index.Mappings, nil
+	}
+	return nil, nil
+}
+
+// GetSettings returns the "index" settings of an index, or of the single
+// index behind an alias. Values come back as strings.
+func (c *esClient) GetSettings(ctx context.Context, indexName string) (map[string]interface{}, error) {
+	ctx, cancel := withTimeout(ctx, c.cfg.AdminTimeout)
+	defer cancel()
+	indexName = c.index(indexName)
+
+	res, err := c.client.Indices.GetSettings(
+		c.client.Indices.GetSettings.WithContext(ctx),
+		c.client.Indices.GetSettings.WithIndex(indexName),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get settings: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return nil, newResponseError("get settings", res)
+	}
+
+	var body map[string]struct {
+		Settings struct {
+			Index map[string]interface{} `json:"index"`
+		} `json:"settings"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	if len(body) != 1 {
+		return nil, fmt.Errorf("get settings: %s resolves to %d indices", indexName, len(body))
+	}
+	for _, index := range body {
+		renameSynonymsSets(index.Settings.Index, c.trimIndex)
+		return index.Settings.Index, nil
+	}
+	return nil, nil
+}
+
+// PutMapping adds fields to the mapping of an index or of every index behind an alias
+func (c *esClient) PutMapping(ctx context.Context, indexName string, mapping interface{}) error {
+	ctx, cancel := withTimeout(ctx, c.cfg.AdminTimeout)
+	defer cancel()
+	indexName = c.index(indexName)
+
+	body, err := json.Marshal(mapping)
+	if err != nil {
+		return fmt.Errorf("failed to marshal mapping: %w", err)
+	}
+
+	res, err := c.client.Indices.PutMapping(
+		[]string{indexName},
+		bytes.NewReader(body),
+		c.client.Indices.PutMapping.WithContext(ctx),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to put mapping: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return newResponseError("put mapping", res)
+	}
+
+	return nil
+}
+
+// ListIndices returns the concrete indices matching a wildcard pattern
+func (c *esClient) ListIndices(ctx context.Context, pattern string) ([]string, error) {
+	ctx, cancel := withTimeout(ctx, c.cfg.AdminTimeout)
+	defer cancel()
+	pattern = c.index(pattern)
+
+	res, err := c.client.Cat.Indices(
+		c.client.Cat.Indices.WithContext(ctx),
+		c.client.Cat.Indices.WithIndex(pattern),
+		c.client.Cat.Indices.WithFormat("json"),
+		c.client.Cat.Indices.WithH("index"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list indices: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == 404 {
+		return nil, nil
+	}
+	if res.IsError() {
+		return nil, fmt.Errorf("list indices error: %s", res.String())
+	}
+
+	var rows []struct {
+		Index string `json:"index"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&rows); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	indices := make([]string, 0, len(rows))
+	for _, row := range rows {
+		indices = append(indices, c.trimIndex(row.Index))
+	}
+	return indices, nil
+}
+
+// GetAlias returns the indices an alias points to, empty if it doesn't exist
+func (c *esClient) GetAlias(ctx context.Context, alias string) ([]string, error) {
+	ctx, cancel := withTimeout(ctx, c.cfg.AdminTimeout)
+	defer cancel()
+	alias = c.index(alias)
+
+	res, err := c.client.Indices.GetAlias(
+		c.client.Indices.GetAlias.WithContext(ctx),
+		c.client.Indices.GetAlias.WithName(alias),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get alias: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == 404 {
+		return nil, nil
+	}
+	if res.IsError() {
+		return nil, fmt.Errorf("get alias error: %s", res.String())
+	}
+
+	var body map[string]interface{}
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	indices := make([]string, 0, len(body))
+	for index := range body {
+		indices = append(indices, c.trimIndex(index))
+	}
+	return indices, nil
+}
+
+// UpdateAliases applies all alias actions in one atomic request
+func (c *esClient) UpdateAliases(ctx context.Context, actions []AliasAction) error {
+	ctx, cancel := withTimeout(ctx, c.cfg.AdminTimeout)
+	defer cancel()
+
+	prefixed := make([]AliasAction, 0, len(actions))
+	for _, action := range actions {
+		prefixed = append(prefixed, AliasAction{
+			Add:         c.aliasTarget(action.Add),
+			Remove:      c.aliasTarget(action.Remove),
+			RemoveIndex: c.aliasTarget(action.RemoveIndex),
+		})
+	}
+
+	body, err := json.Marshal(map[string]interface{}{"actions": prefixed})
+	if err != nil {
+		return fmt.Errorf("failed to marshal alias actions: %w", err)
+	}
+
+	res, err := c.client.Indices.UpdateAliases(
+		bytes.NewReader(body),
+		c.client.Indices.UpdateAliases.WithContext(ctx),
+	)
+	if err != nil {
+		return fmt.Errorf("update aliases failed: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return fmt.Errorf("update aliases error: %s", res.String())
+	}
+
+	return nil
+}
@@ -0,0 +1,82 @@
+package es
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// newTestClient returns a client of a fake cluster served by handler, with
+// short retry backoffs
+func newTestClient(t *testing.T, cfg Config, handler http.HandlerFunc) Client {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the client refuses to talk to anything but Elasticsearch
+		w.Header().Set("X-Elastic-Product", "Elasticsearch")
+		w.Header().Set("Content-Type", "application/json")
+		handler(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	cfg.Addresses = []string{server.URL}
+	cfg.RetryBackoffMin, cfg.RetryBackoffMax = time.Millisecond, time.Millisecond
+	client, err := NewClient(cfg)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	return client
+}
+
+func TestSearchResponseError(t *testing.T) {
+	tests := []struct {
+		name         string
+		status       int
+		body         string
+		wantType     string
+		wantNotFound bool
+	}{
+		{
+			name:   "expired point in time",
+			status: http.StatusNotFound,
+			body: `{"error": {"type": "search_phase_execution_exception", "reason": "all shards failed",
+				"root_cause": [{"type": "search_context_missing_exception", "reason": "No search context found for id [1]"}]}, "status": 404}`,
+			wantType:     "search_phase_execution_exception",
+			wantNotFound: true,
+		},
+		{
+			name:     "bad query",
+			status:   http.StatusBadRequest,
+			body:     `{"error": {"type": "parsing_exception", "reason": "unknown query [mtch]"}, "status": 400}`,
+			wantType: "parsing_exception",
+		},
+		{
+			name:   "no error body",
+			status: http.StatusBadRequest,
+			body:   `{}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newTestClient(t, Config{}, func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.body))
+			})
+
+			_, err := client.Search(context.Background(), "", map[string]interface{}{"size": 1})
+			var resErr *ResponseError
+			if !errors.As(err, &resErr) {
+				t.Fatalf("err = %v, want a *ResponseError", err)
+			}
+			if resErr.Op != "search" || resErr.StatusCode != tt.status || resErr.Type != tt.wantType {
+				t.Errorf("got %+v, want search status %d type %q", resErr, tt.status, tt.wantType)
+			}
+			if got := errors.Is(err, ErrNotFound); got != tt.wantNotFound {
+				t.Errorf("errors.Is(err, ErrNotFound) = %v, want %v", got, tt.wantNotFound)
+			}
+		})
+	}
+}
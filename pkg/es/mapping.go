@@ -0,0 +1,280 @@
+package es
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// IndexDefinition declares an index: its settings and the Go type whose
+// JSON form is stored in it. The mapping is derived with MappingOf.
+type IndexDefinition struct {
+	Name     string // index or alias the definition is checked against
+	Settings map[string]interface{}
+	Model    interface{}
+}
+
+// Body is the create index request of the definition
+func (d IndexDefinition) Body() (map[string]interface{}, error) {
+	properties, err := MappingOf(d.Model)
+	if err != nil {
+		return nil, err
+	}
+	body := map[string]interface{}{
+		"mappings": map[string]interface{}{"properties": properties},
+	}
+	if len(d.Settings) > 0 {
+		body["settings"] = d.Settings
+	}
+	return body, nil
+}
+
+// SortableField is a field of the mapping a search can sort on
+type SortableField struct {
+	Type       string // keyword, date or a numeric type
+	NestedPath string // enclosing nested field, empty at the top level
+}
+
+// IsNumeric reports whether the field holds numbers, which sum, avg and
+// median sort modes need
+func (f SortableField) IsNumeric() bool {
+	return numericTypes[f.Type]
+}
+
+var numericTypes = map[string]bool{
+	"long": true, "integer": true, "short": true, "byte": true, "unsigned_long": true,
+	"double": true, "float": true, "half_float": true, "scaled_float": true,
+}
+
+// SortableFields lists the keyword, date and numeric fields of the mapping by
+// their dotted path, subfields included. Text fields have no doc values and
+// can't be sorted on.
+func (d IndexDefinition) SortableFields() (map[string]SortableField, error) {
+	properties, err := MappingOf(d.Model)
+	if err != nil {
+		return nil, err
+	}
+	sortable := map[string]SortableField{}
+	collectSortable(properties, "", "", sortable)
+	return sortable, nil
+}
+
+func collectSortable(properties map[string]interface{}, prefix, nestedPath string, sortable map[string]SortableField) {
+	for name, p := range properties {
+		property, ok := p.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		path := prefix + name
+		fieldType, _ := property["type"].(string)
+		if sub, ok := property["properties"].(map[string]interface{}); ok {
+			inner := nestedPath
+			if fieldType == "nested" {
+				inner = path
+			}
+			collectSortable(sub, path+".", inner, sortable)
+			continue
+		}
+		if isSortableType(fieldType) {
+			sortable[path] = SortableField{Type: fieldType, NestedPath: nestedPath}
+		}
+		if fields, ok := property["fields"].(map[string]interface{}); ok {
+			for subName, f := range fields {
+				def, _ := f.(map[string]interface{})
+				subType, _ := def["type"].(string)
+				if isSortableType(subType) {
+					sortable[path+"."+subName] = SortableField{Type: subType, NestedPath: nestedPath}
+				}
+			}
+		}
+	}
+}
+
+func isSortableType(t string) bool {
+	return t == "keyword" || t == "date" || numericTypes[t]
+}
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// MappingOf derives mapping properties from a struct. Fields are named like
+// encoding/json names them and skipped when json ignores them. The es tag
+// sets the type, mapping parameters and subfields:
+//
+//	Name string `json:"name" es:"text,analyzer=vi_folded,field=exact:text:vi_exact"`
+//
+// A subfield is field=name:type[:analyzer]. Without a type the field type is
+// inferred: strings and text marshalers (uuid.UUID) are keyword, time.Time
+// is date, numbers are long or double, structs are objects. es:"-" leaves a
+// field out of the mapping.
+func MappingOf(v interface{}) (map[string]interface{}, error) {
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("mapping: %T is not a struct", v)
+	}
+	return structProperties(t)
+}
+
+func structProperties(t reflect.Type) (map[string]interface{}, error) {
+	properties := map[string]interface{}{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		// encoding/json still promotes the fields of an unexported embedded struct
+		if !f.IsExported() && !(f.Anonymous && f.Type.Kind() == reflect.Struct) {
+			continue
+		}
+		name, skip := jsonName(f)
+		if skip || f.Tag.Get("es") == "-" {
+			continue
+		}
+
+		// embedded structs without a json name are flattened, like encoding/json does
+		if f.Anonymous && name == "" && indirect(f.Type).Kind() == reflect.Struct {
+			embedded, err := structProperties(indirect(f.Type))
+			if err != nil {
+				return nil, err
+			}
+			for k, p := range embedded {
+				if _, ok := properties[k]; !ok {
+					properties[k] = p
+				}
+			}
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		property, err := fieldProperty(f)
+		if err != nil {
+			return nil, fmt.Errorf("mapping: field %s.%s: %w", t.Name(), f.Name, err)
+		}
+		properties[name] = property
+	}
+	return properties, nil
+}
+
+func jsonName(f reflect.StructField) (string, bool) {
+	tag := f.Tag.Get("json")
+	if tag == "-" {
+		return "", true
+	}
+	name, _, _ := strings.Cut(tag, ",")
+	return name, false
+}
+
+func fieldProperty(f reflect.StructField) (map[string]interface{}, error) {
+	property := map[string]interface{}{}
+	fieldType := ""
+	fields := map[string]interface{}{}
+
+	if tag := f.Tag.Get("es"); tag != "" {
+		for i, part := range strings.Split(tag, ",") {
+			part = strings.TrimSpace(part)
+			key, value, hasValue := strings.Cut(part, "=")
+			switch {
+			case !hasValue && i == 0:
+				fieldType = part
+			case !hasValue:
+				return nil, fmt.Errorf("invalid es tag option %q", part)
+			case key == "type":
+				fieldType = value
+			case key == "field":
+				sub := strings.Split(value, ":")
+				if len(sub) < 2 || len(sub) > 3 || sub[0] == "" || sub[1] == "" {
+					return nil, fmt.Errorf("subfield %q must be name:type[:analyzer]", value)
+				}
+				def := map[string]interface{}{"type": sub[1]}
+				if len(sub) == 3 {
+					def["analyzer"] = sub[2]
+				}
+				fields[sub[0]] = def
+			default:
+				property[key] = tagValue(value)
+			}
+		}
+	}
+
+	t := indirect(f.Type)
+	if (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) && t.Elem().Kind() != reflect.Uint8 &&
+		!t.Implements(textMarshalerType) && !reflect.PtrTo(t).Implements(textMarshalerType) {
+		// arrays are plain multi-valued fields in Elasticsearch
+		t = indirect(t.Elem())
+	}
+
+	if fieldType == "" {
+		fieldType = inferType(t)
+	}
+	if fieldType == "" {
+		return nil, fmt.Errorf("can't infer a type for %s, set one with an es tag", f.Type)
+	}
+
+	if fieldType == "object" || fieldType == "nested" {
+		if t.Kind() != reflect.Struct {
+			return nil, fmt.Errorf("%s needs a struct, got %s", fieldType, f.Type)
+		}
+		sub, err := structProperties(t)
+		if err != nil {
+			return nil, err
+		}
+		property["properties"] = sub
+		// object is the default type, Elasticsearch leaves it out of the mapping
+		if fieldType == "object" {
+			return property, nil
+		}
+	}
+
+	property["type"] = fieldType
+	if len(fields) > 0 {
+		property["fields"] = fields
+	}
+	return property, nil
+}
+
+func inferType(t reflect.Type) string {
+	switch {
+	case t == timeType:
+		return "date"
+	case t.Implements(textMarshalerType) || reflect.PtrTo(t).Implements(textMarshalerType):
+		return "keyword"
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return "keyword"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "long"
+	case reflect.Float32, reflect.Float64:
+		return "double"
+	case reflect.Struct:
+		return "object"
+	}
+	return ""
+}
+
+func indirect(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
+
+func tagValue(v string) interface{} {
+	if b, err := strconv.ParseBool(v); err == nil {
+		return b
+	}
+	if n, err := strconv.Atoi(v); err == nil {
+		return n
+	}
+	return v
+}
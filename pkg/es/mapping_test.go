@@ -0,0 +1,218 @@
+package es
+
+import (
+	"business/pkg/model"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type mappingInner struct {
+	Role string `json:"role"`
+}
+
+type mappingBase struct {
+	ID    uuid.UUID `json:"id"`
+	Title string    `json:"title" es:"text"`
+}
+
+type mappingDoc struct {
+	mappingBase
+	// declared on the outer struct, wins over the embedded one
+	Title string `json:"title" es:"keyword,field=text:text:standard"`
+
+	Name       string         `json:"name" es:"text,analyzer=vi_folded,search_analyzer=vi_folded_synonym,field=exact:text:vi_exact,field=raw:keyword"`
+	Code       string         `json:"code" es:"type=keyword,ignore_above=256,doc_values=false"`
+	NoJSON     string         // named like the Go field
+	Created    time.Time      `json:"created"`
+	Updated    *time.Time     `json:"updated"`
+	Count      int            `json:"count"`
+	Ratio      float64        `json:"ratio"`
+	Active     bool           `json:"active"`
+	Tags       []string       `json:"tags"`
+	Owners     []uuid.UUID    `json:"owners"`
+	Inner      mappingInner   `json:"inner"`
+	Staffs     []mappingInner `json:"staffs" es:"nested"`
+	Password   string         `json:"password" es:"-"`
+	Ignored    string         `json:"-"`
+	unexported string
+}
+
+func TestMappingOf(t *testing.T) {
+	tests := []struct {
+		name    string
+		v       interface{}
+		want    string
+		wantErr string
+	}{
+		{
+			name: "tags, inference and flattening",
+			v:    &mappingDoc{},
+			want: `{
+				"id": {"type": "keyword"},
+				"title": {"type": "keyword", "fields": {"text": {"type": "text", "analyzer": "standard"}}},
+				"name": {
+					"type": "text", "analyzer": "vi_folded", "search_analyzer": "vi_folded_synonym",
+					"fields": {"exact": {"type": "text", "analyzer": "vi_exact"}, "raw": {"type": "keyword"}}
+				},
+				"code": {"type": "keyword", "ignore_above": 256, "doc_values": false},
+				"NoJSON": {"type": "keyword"},
+				"created": {"type": "date"},
+				"updated": {"type": "date"},
+				"count": {"type": "long"},
+				"ratio": {"type": "double"},
+				"active": {"type": "boolean"},
+				"tags": {"type": "keyword"},
+				"owners": {"type": "keyword"},
+				"inner": {"properties": {"role": {"type": "keyword"}}},
+				"staffs": {"type": "nested", "properties": {"role": {"type": "keyword"}}}
+			}`,
+		},
+		{
+			name: "business index",
+			v:    model.Business{},
+			want: `{
+				"ID": {"type": "keyword"},
+				"name": {
+					"type": "text", "analyzer": "vi_folded", "search_analyzer": "vi_folded_synonym",
+					"fields": {
+						"exact": {"type": "text", "analyzer": "vi_exact"},
+						"suggest": {"type": "search_as_you_type", "analyzer": "vi_folded"}
+					}
+				},
+				"Description": {
+					"type": "text", "analyzer": "vi_folded", "search_analyzer": "vi_folded_synonym",
+					"fields": {"exact": {"type": "text", "analyzer": "vi_exact"}}
+				},
+				"address": {
+					"type": "text", "analyzer": "vi_folded", "search_analyzer": "vi_folded_synonym",
+					"fields": {"exact": {"type": "text", "analyzer": "vi_exact"}}
+				},
+				"type": {"type": "keyword"},
+				"status": {"type": "keyword"},
+				"CreateAt": {"type": "date"},
+				"Staffs": {"type": "nested", "properties": {
+					"id": {"type": "keyword"},
+					"username": {"type": "keyword"},
+					"fullname": {"type": "text", "analyzer": "vi_folded", "fields": {"exact": {"type": "text", "analyzer": "vi_exact"}}},
+					"email": {"type": "keyword"},
+					"role": {"type": "keyword"},
+					"created_at": {"type": "date"},
+					"business_id": {"type": "keyword"}
+				}},
+				"woker_name": {"type": "text"}
+			}`,
+		},
+		{
+			name: "staff index",
+			v:    model.StaffDocument{},
+			want: `{
+				"id": {"type": "keyword"},
+				"username": {"type": "keyword", "fields": {"text": {"type": "text", "analyzer": "vi_folded"}}},
+				"fullname": {"type": "text", "analyzer": "vi_folded", "fields": {"exact": {"type": "text", "analyzer": "vi_exact"}}},
+				"email": {"type": "keyword"},
+				"role": {"type": "keyword"},
+				"created_at": {"type": "date"},
+				"business_id": {"type": "keyword"}
+			}`,
+		},
+		{
+			name:    "not a struct",
+			v:       "business",
+			wantErr: "string is not a struct",
+		},
+		{
+			name: "subfield without a type",
+			v: struct {
+				Name string `es:"text,field=exact"`
+			}{},
+			wantErr: `subfield "exact" must be name:type[:analyzer]`,
+		},
+		{
+			name: "subfield with too many parts",
+			v: struct {
+				Name string `es:"text,field=exact:text:vi:extra"`
+			}{},
+			wantErr: "must be name:type[:analyzer]",
+		},
+		{
+			name: "option without a value",
+			v: struct {
+				Name string `es:"text,index"`
+			}{},
+			wantErr: `invalid es tag option "index"`,
+		},
+		{
+			name: "type that can't be inferred",
+			v: struct {
+				Attrs map[string]string
+			}{},
+			wantErr: "can't infer a type",
+		},
+		{
+			name: "nested on a scalar",
+			v: struct {
+				Name string `es:"nested"`
+			}{},
+			wantErr: "nested needs a struct",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := MappingOf(tt.v)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("err = %v", err)
+			}
+			assertSameJSON(t, got, tt.want)
+		})
+	}
+}
+
+func TestSortableFields(t *testing.T) {
+	sortable, err := IndexDefinition{Model: mappingDoc{}}.SortableFields()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]SortableField{
+		"id": {Type: "keyword"}, "title": {Type: "keyword"}, "name.raw": {Type: "keyword"},
+		"code": {Type: "keyword"}, "NoJSON": {Type: "keyword"}, "created": {Type: "date"},
+		"updated": {Type: "date"}, "count": {Type: "long"}, "ratio": {Type: "double"},
+		"tags": {Type: "keyword"}, "owners": {Type: "keyword"}, "inner.role": {Type: "keyword"},
+		"staffs.role": {Type: "keyword", NestedPath: "staffs"},
+	}
+	if len(sortable) != len(want) {
+		t.Errorf("got %v, want %v", sortable, want)
+	}
+	for field, w := range want {
+		if got, ok := sortable[field]; !ok || got != w {
+			t.Errorf("%s = %+v, want %+v", field, got, w)
+		}
+	}
+}
+
+// assertSameJSON compares the JSON of got with the want document, key order aside
+func assertSameJSON(t *testing.T, got interface{}, want string) {
+	t.Helper()
+	gotJSON, err := json.Marshal(got)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	var wantValue interface{}
+	if err := json.Unmarshal([]byte(want), &wantValue); err != nil {
+		t.Fatalf("bad want JSON: %v", err)
+	}
+	wantJSON, _ := json.Marshal(wantValue)
+	if string(gotJSON) != string(wantJSON) {
+		t.Errorf("got  %s\nwant %s", gotJSON, wantJSON)
+	}
+}
@@ -1,6 +1,9 @@
 package es
 
 import (
+	"encoding/json"
+	"fmt"
+	"strings"
 	"time"
 )
 
@@ -9,6 +12,89 @@ type BulkDocument struct {
 	Data interface{}
 }
 
+// Document is a single document read with GetDocument
+type Document struct {
+	Index       string          `json:"_index"`
+	ID          string          `json:"_id"`
+	Version     int64           `json:"_version"`
+	SeqNo       int64           `json:"_seq_no"`
+	PrimaryTerm int64           `json:"_primary_term"`
+	Found       bool            `json:"found"`
+	Source      json.RawMessage `json:"_source"`
+}
+
+// Script is an inline painless script with its parameters
+type Script struct {
+	Source string                 `json:"source"`
+	Lang   string                 `json:"lang,omitempty"`
+	Params map[string]interface{} `json:"params,omitempty"`
+}
+
+// DocumentUpdate is the body of a partial update. Set Doc for a merge or
+// Script for a scripted update, Upsert/DocAsUpsert create missing documents.
+type DocumentUpdate struct {
+	Doc            interface{} `json:"doc,omitempty"`
+	DocAsUpsert    bool        `json:"doc_as_upsert,omitempty"`
+	Script         *Script     `json:"script,omitempty"`
+	ScriptedUpsert bool        `json:"scripted_upsert,omitempty"`
+	Upsert         interface{} `json:"upsert,omitempty"`
+
+	// Optimistic concurrency, taken from a Document read earlier
+	IfSeqNo         *int `json:"-"`
+	IfPrimaryTerm   *int `json:"-"`
+	RetryOnConflict *int `json:"-"`
+}
+
+// ByQueryResult is the summary of a delete or update by query
+type ByQueryResult struct {
+	Took             int64             `json:"took"`
+	Total            int64             `json:"total"`
+	Deleted          int64             `json:"deleted"`
+	Updated          int64             `json:"updated"`
+	VersionConflicts int64             `json:"version_conflicts"`
+	Failures         []json.RawMessage `json:"failures"`
+}
+
+// AliasAction is one entry of an _aliases request, set exactly one field
+type AliasAction struct {
+	Add         *AliasTarget `json:"add,omitempty"`
+	Remove      *AliasTarget `json:"remove,omitempty"`
+	RemoveIndex *AliasTarget `json:"remove_index,omitempty"`
+}
+
+type AliasTarget struct {
+	Index string `json:"index"`
+	Alias string `json:"alias,omitempty"`
+}
+
+// BulkIndexerConfig controls batching and retries of a BulkIndexer.
+// Zero values fall back to the defaults in bulk_indexer.go.
+type BulkIndexerConfig struct {
+	Index         string
+	Workers       int           // number of concurrent _bulk senders
+	FlushDocs     int           // flush a batch once it holds this many documents
+	FlushBytes    int           // flush a batch once its NDJSON body reaches this size
+	FlushInterval time.Duration // flush a non-empty batch at least this often
+	MaxRetries    int           // retries for items rejected with 429
+	RetryBackoff  time.Duration // first retry delay, doubled on every attempt
+	Refresh       string        // "true", "false" or "wait_for"
+}
+
+// BulkItemFailure is a document that could not be indexed
+type BulkItemFailure struct {
+	ID     string `json:"id"`
+	Status int    `json:"status"`
+	Type   string `json:"type"`
+	Reason string `json:"reason"`
+}
+
+// BulkReport summarizes what a BulkIndexer did
+type BulkReport struct {
+	Indexed  int64             `json:"indexed"`
+	Failed   int64             `json:"failed"`
+	Failures []BulkItemFailure `json:"failures"`
+}
+
 // SearchResult represents search response
 type SearchResult struct {
 	Hits struct {
@@ -16,11 +102,38 @@ type SearchResult struct {
 			Value int64 `json:"value"`
 		} `json:"total"`
 		Hits []struct {
+			// Index is the concrete index holding the hit, without the index prefix
+			Index  string                 `json:"_index"`
 			ID     string                 `json:"_id"`
 			Source map[string]interface{} `json:"_source"`
 			Score  float64                `json:"_score"`
+			Sort   []json.RawMessage      `json:"sort,omitempty"`
+			// Highlight maps a field to its matched fragments
+			Highlight map[string][]string `json:"highlight,omitempty"`
 		} `json:"hits"`
 	} `json:"hits"`
+	PitID        string                     `json:"pit_id,omitempty"`
+	Aggregations map[string]json.RawMessage `json:"aggregations,omitempty"`
+
+	// IndexTotals counts the hits of each index of SearchIndices, keyed by IndexBoost.Index
+	IndexTotals map[string]int64 `json:"index_totals,omitempty"`
+
+	// Facets is decoded by the service from Aggregations
+	Facets *FacetResult `json:"facets,omitempty"`
+	// FuzzyFallback is set when no strict match was found and the hits come from a fuzzy retry
+	FuzzyFallback bool `json:"fuzzy_fallback,omitempty"`
+	// RankingProfile is the profile the hits were ranked with, empty without one
+	RankingProfile string `json:"ranking_profile,omitempty"`
+
+	// NextCursor is filled by the service when paging with search_after
+	NextCursor string `json:"-"`
+}
+
+// IndexBoost is one index of a multi-index search, the scores of its hits
+// are multiplied by Boost
+type IndexBoost struct {
+	Index string
+	Boost float64
 }
 
 // Config holds ElasticSearch configuration
@@ -28,15 +141,221 @@ type Config struct {
 	Addresses []string
 	Username  string
 	Password  string
+
+	// Only one of basic auth, APIKey or ServiceToken may be set
+	APIKey       string // base64 encoded "id:api_key"
+	ServiceToken string // sent as a bearer token
+
+	// TLS, file paths are read by NewClient
+	CACertPath             string
+	ClientCertPath         string
+	ClientKeyPath          string
+	CertificateFingerprint string // SHA256 hex fingerprint of the cluster certificate
+
+	// Sniffing replaces Addresses with the nodes the cluster reports
+	DiscoverNodesOnStart  bool
+	DiscoverNodesInterval time.Duration
+
+	// IndexPrefix is prepended to every index and alias name, so several
+	// environments can share a cluster
+	IndexPrefix string
+
+	// Retries on 429/502/503/504 and network errors, with exponential backoff and jitter.
+	// Zero retries 3 times, a negative MaxRetries turns retries off.
+	MaxRetries      int
+	RetryBackoffMin time.Duration
+	RetryBackoffMax time.Duration
+
+	// Per operation timeouts, applied on top of the caller's context
+	SearchTimeout   time.Duration // search and point in time calls
+	DocumentTimeout time.Duration // single document index, get, update and delete
+	BulkTimeout     time.Duration // one _bulk call, delete/update by query
+	AdminTimeout    time.Duration // index, alias and cluster calls
+
+	// The circuit breaker opens after this many consecutive failures and
+	// lets a single trial request through once BreakerOpenTimeout passed
+	BreakerFailureThreshold int
+	BreakerOpenTimeout      time.Duration
 }
 
 type SearchRequest struct {
     Index   string      `json:"index" example:"business"`      // Tên index
     Page    int          `json:"page" example:"1"`              // Số trang (bắt đầu từ 1)
     Size    int         `json:"size" example:"10"`             // Số document mỗi trang
-    Sort    string      `json:"sort,omitempty" example:"{\"created_at\":\"desc\"}"` // key=field, value="asc|desc"
+    Sort    SortSpec    `json:"sort,omitempty"`                 // danh sách field sắp xếp theo thứ tự ưu tiên, tự thêm _score và ID ở cuối
     Filters BusinessFilter `json:"filters,omitempty"` // các field cần filter
     Source  []string               `json:"_source,omitempty"`             // chọn field nào trả về (optional)
+    UseCursor bool   `json:"use_cursor,omitempty" example:"false"` // bật phân trang bằng cursor (search_after) cho trang sâu
+    Cursor    string `json:"cursor,omitempty"`                     // next_cursor của lần gọi trước
+    Aggs         *AggregationSpec `json:"aggs,omitempty"`          // các facet cần đếm (optional)
+    FacetFilters FacetSelection   `json:"facet_filters,omitempty"` // các facet đang chọn, áp dụng như post_filter
+    Highlight    *HighlightSpec   `json:"highlight,omitempty"`     // trả về đoạn text khớp với từ khóa (optional)
+    Fuzzy        *FuzzySpec       `json:"fuzzy,omitempty"`         // cho phép gõ sai chính tả (optional)
+    DisableFuzzyFallback bool     `json:"disable_fuzzy_fallback,omitempty"` // không tự tìm lại với fuzzy khi không có kết quả
+    RankingProfile       string   `json:"ranking_profile,omitempty" example:"default"` // profile xếp hạng của fulltext-search (optional), mặc định RANKING_DEFAULT_PROFILE
+}
+
+// SortField is one level of a search sort. Field must be a keyword, date or
+// numeric field of the index, or _score.
+type SortField struct {
+	Field      string `json:"field" example:"CreateAt"`
+	Order      string `json:"order,omitempty" example:"desc"`         // asc hoặc desc, mặc định asc (desc với _score)
+	Missing    string `json:"missing,omitempty" example:"_last"`      // _first hoặc _last, vị trí document không có giá trị
+	NestedPath string `json:"nested_path,omitempty" example:"Staffs"` // field nested chứa Field, tự điền nếu bỏ trống
+	Mode       string `json:"mode,omitempty" example:"min"`           // min, max, sum, avg, median khi field có nhiều giá trị
+}
+
+// SortSpec is an ordered list of sort fields. It also reads the former
+// "field:order" string, comma separated for several fields, and a single
+// {"field": "order"} object.
+type SortSpec []SortField
+
+func (s *SortSpec) UnmarshalJSON(data []byte) error {
+	data = []byte(strings.TrimSpace(string(data)))
+	switch {
+	case len(data) == 0 || string(data) == "null":
+		*s = nil
+		return nil
+	case data[0] == '[':
+		var fields []SortField
+		if err := json.Unmarshal(data, &fields); err != nil {
+			return err
+		}
+		*s = fields
+		return nil
+	case data[0] == '"':
+		var legacy string
+		if err := json.Unmarshal(data, &legacy); err != nil {
+			return err
+		}
+		fields, err := parseSortString(legacy)
+		if err != nil {
+			return err
+		}
+		*s = fields
+		return nil
+	case data[0] == '{':
+		var object map[string]string
+		if err := json.Unmarshal(data, &object); err != nil {
+			return fmt.Errorf("sort: object must map a field to its order: %w", err)
+		}
+		if len(object) > 1 {
+			return fmt.Errorf("sort: object form takes a single field, use a list for several")
+		}
+		*s = nil
+		for field, order := range object {
+			*s = append(*s, SortField{Field: field, Order: order})
+		}
+		return nil
+	}
+	return fmt.Errorf("sort: must be a list of fields")
+}
+
+func parseSortString(v string) (SortSpec, error) {
+	var fields SortSpec
+	for _, part := range strings.Split(v, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		field, order, _ := strings.Cut(part, ":")
+		if field == "" {
+			return nil, fmt.Errorf("sort: %q must be field:order", part)
+		}
+		fields = append(fields, SortField{Field: strings.TrimSpace(field), Order: strings.TrimSpace(order)})
+	}
+	return fields, nil
+}
+
+// FuzzySpec makes the text clauses typo tolerant
+type FuzzySpec struct {
+	Fuzziness     string `json:"fuzziness,omitempty" example:"AUTO"` // AUTO, AUTO:low,high, 0, 1 hoặc 2
+	PrefixLength  int    `json:"prefix_length,omitempty" example:"1"`
+	MaxExpansions int    `json:"max_expansions,omitempty" example:"50"`
+}
+
+// SuggestRequest is a type-ahead lookup on business names
+type SuggestRequest struct {
+	Prefix string `json:"prefix" form:"prefix" example:"caf"`
+	Size   int    `json:"size" form:"size" example:"5"`
+	Type   string `json:"type,omitempty" form:"type"`
+	Status string `json:"status,omitempty" form:"status"`
+}
+
+type Suggestion struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// UnifiedSearchRequest searches businesses and staffs together, for the global search bar
+type UnifiedSearchRequest struct {
+	Keyword  string   `json:"keyword" form:"keyword" example:"an"`
+	Types    []string `json:"types,omitempty" form:"types" example:"business,staff"` // mặc định tất cả
+	Page     int      `json:"page" form:"page" example:"1"`
+	PageSize int      `json:"page_size" form:"page_size" example:"20"`
+}
+
+// UnifiedHit is one hit of a unified search, Data is a business or a staff depending on Type
+type UnifiedHit struct {
+	Type  string      `json:"type"`
+	ID    string      `json:"id"`
+	Score float64     `json:"score"`
+	Data  interface{} `json:"data"`
+}
+
+type UnifiedSearchResult struct {
+	Hits     []UnifiedHit `json:"hits"`
+	Page     int          `json:"page"`
+	PageSize int          `json:"page_size"`
+	Total    int64        `json:"total"`
+	// Counts is the number of hits of each type
+	Counts map[string]int64 `json:"counts"`
+}
+
+// HighlightSpec controls the fragments returned for each hit
+type HighlightSpec struct {
+	Fields            []string `json:"fields,omitempty" example:"Description,address"` // mặc định Description và address
+	FragmentSize      int      `json:"fragment_size,omitempty" example:"150"`
+	NumberOfFragments *int     `json:"number_of_fragments,omitempty" example:"3"` // 0 trả về cả field
+	PreTags           []string `json:"pre_tags,omitempty" example:"<em>"`
+	PostTags          []string `json:"post_tags,omitempty" example:"</em>"`
+}
+
+// AggregationSpec chooses which facet counts come back with the hits
+type AggregationSpec struct {
+	Type              bool   `json:"type"`
+	Status            bool   `json:"status"`
+	StaffRole         bool   `json:"staff_role"`
+	CreatedAtInterval string `json:"created_at_interval,omitempty" example:"month"` // minute|hour|day|week|month|quarter|year
+	Size              int    `json:"size,omitempty" example:"10"`                    // số bucket tối đa mỗi facet
+}
+
+// FacetSelection is what the user picked in the facet list
+type FacetSelection struct {
+	Type        []string   `json:"type,omitempty"`
+	Status      []string   `json:"status,omitempty"`
+	StaffRole   []string   `json:"staff_role,omitempty"`
+	CreatedFrom *time.Time `json:"created_from,omitempty"`
+	CreatedTo   *time.Time `json:"created_to,omitempty"`
+}
+
+type Bucket struct {
+	Key      string `json:"key"`
+	DocCount int64  `json:"doc_count"`
+}
+
+type DateBucket struct {
+	Key         int64  `json:"key"`
+	KeyAsString string `json:"key_as_string"`
+	DocCount    int64  `json:"doc_count"`
+}
+
+// FacetResult holds the buckets of every requested facet
+type FacetResult struct {
+	Type      []Bucket     `json:"type,omitempty"`
+	Status    []Bucket     `json:"status,omitempty"`
+	StaffRole []Bucket     `json:"staff_role,omitempty"`
+	CreatedAt []DateBucket `json:"created_at,omitempty"`
 }
 
 type BusinessFilter struct {
@@ -0,0 +1,77 @@
+package es
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestSortSpecUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    SortSpec
+		wantErr bool
+	}{
+		{name: "null", data: `null`, want: nil},
+		{
+			name: "list",
+			data: `[{"field":"Staffs.role","order":"desc","missing":"_last","nested_path":"Staffs","mode":"min"},{"field":"_score"}]`,
+			want: SortSpec{
+				{Field: "Staffs.role", Order: "desc", Missing: "_last", NestedPath: "Staffs", Mode: "min"},
+				{Field: "_score"},
+			},
+		},
+		{name: "empty list", data: `[]`, want: SortSpec{}},
+		{
+			name: "legacy string",
+			data: `"CreateAt:desc, type"`,
+			want: SortSpec{{Field: "CreateAt", Order: "desc"}, {Field: "type"}},
+		},
+		{name: "legacy string with empty parts", data: `"status:asc,,"`, want: SortSpec{{Field: "status", Order: "asc"}}},
+		{name: "legacy string without a field", data: `":desc"`, wantErr: true},
+		{name: "object", data: `{"CreateAt":"desc"}`, want: SortSpec{{Field: "CreateAt", Order: "desc"}}},
+		{name: "object with several fields", data: `{"CreateAt":"desc","type":"asc"}`, wantErr: true},
+		{name: "object with a non string order", data: `{"CreateAt":1}`, wantErr: true},
+		{name: "number", data: `1`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got SortSpec
+			err := json.Unmarshal([]byte(tt.data), &got)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSortString(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    SortSpec
+		wantErr bool
+	}{
+		{in: "", want: nil},
+		{in: "name", want: SortSpec{{Field: "name"}}},
+		{in: " CreateAt : desc ", want: SortSpec{{Field: "CreateAt", Order: "desc"}}},
+		{in: "status:asc,ID:desc", want: SortSpec{{Field: "status", Order: "asc"}, {Field: "ID", Order: "desc"}}},
+		{in: "status,:asc", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := parseSortString(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
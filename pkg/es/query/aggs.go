@@ -0,0 +1,172 @@
+package query
+
+// Aggregation is any entry of the "aggs" section
+type Aggregation interface {
+	Source() interface{}
+}
+
+type subAggregations map[string]Aggregation
+
+func (a subAggregations) apply(body map[string]interface{}) {
+	if len(a) == 0 {
+		return
+	}
+	aggs := make(map[string]interface{}, len(a))
+	for name, agg := range a {
+		aggs[name] = agg.Source()
+	}
+	body["aggs"] = aggs
+}
+
+// TermsAggregation buckets documents by the values of a keyword field
+type TermsAggregation struct {
+	field string
+	size  int
+	aggs  subAggregations
+}
+
+func TermsAgg(field string) *TermsAggregation {
+	return &TermsAggregation{field: field}
+}
+
+func (a *TermsAggregation) Size(size int) *TermsAggregation {
+	a.size = size
+	return a
+}
+
+func (a *TermsAggregation) SubAggregation(name string, agg Aggregation) *TermsAggregation {
+	if a.aggs == nil {
+		a.aggs = subAggregations{}
+	}
+	a.aggs[name] = agg
+	return a
+}
+
+func (a *TermsAggregation) Source() interface{} {
+	params := map[string]interface{}{"field": a.field}
+	if a.size > 0 {
+		params["size"] = a.size
+	}
+	body := map[string]interface{}{"terms": params}
+	a.aggs.apply(body)
+	return body
+}
+
+// DateHistogramAggregation buckets documents by calendar interval of a date field
+type DateHistogramAggregation struct {
+	field            string
+	calendarInterval string
+	format           string
+	timeZone         string
+	minDocCount      *int
+	aggs             subAggregations
+}
+
+func DateHistogramAgg(field, calendarInterval string) *DateHistogramAggregation {
+	return &DateHistogramAggregation{field: field, calendarInterval: calendarInterval}
+}
+
+func (a *DateHistogramAggregation) Format(format string) *DateHistogramAggregation {
+	a.format = format
+	return a
+}
+
+func (a *DateHistogramAggregation) TimeZone(timeZone string) *DateHistogramAggregation {
+	a.timeZone = timeZone
+	return a
+}
+
+func (a *DateHistogramAggregation) MinDocCount(n int) *DateHistogramAggregation {
+	a.minDocCount = &n
+	return a
+}
+
+func (a *DateHistogramAggregation) SubAggregation(name string, agg Aggregation) *DateHistogramAggregation {
+	if a.aggs == nil {
+		a.aggs = subAggregations{}
+	}
+	a.aggs[name] = agg
+	return a
+}
+
+func (a *DateHistogramAggregation) Source() interface{} {
+	params := map[string]interface{}{
+		"field":             a.field,
+		"calendar_interval": a.calendarInterval,
+	}
+	if a.format != "" {
+		params["format"] = a.format
+	}
+	if a.timeZone != "" {
+		params["time_zone"] = a.timeZone
+	}
+	if a.minDocCount != nil {
+		params["min_doc_count"] = *a.minDocCount
+	}
+	body := map[string]interface{}{"date_histogram": params}
+	a.aggs.apply(body)
+	return body
+}
+
+// FilterAggregation narrows the documents its sub aggregations see
+type FilterAggregation struct {
+	filter Query
+	aggs   subAggregations
+}
+
+func FilterAgg(filter Query) *FilterAggregation {
+	return &FilterAggregation{filter: filter}
+}
+
+func (a *FilterAggregation) SubAggregation(name string, agg Aggregation) *FilterAggregation {
+	if a.aggs == nil {
+		a.aggs = subAggregations{}
+	}
+	a.aggs[name] = agg
+	return a
+}
+
+func (a *FilterAggregation) Source() interface{} {
+	body := map[string]interface{}{"filter": a.filter.Source()}
+	a.aggs.apply(body)
+	return body
+}
+
+// NestedAggregation steps into the objects of a nested field
+type NestedAggregation struct {
+	path string
+	aggs subAggregations
+}
+
+func NestedAgg(path string) *NestedAggregation {
+	return &NestedAggregation{path: path}
+}
+
+func (a *NestedAggregation) SubAggregation(name string, agg Aggregation) *NestedAggregation {
+	if a.aggs == nil {
+		a.aggs = subAggregations{}
+	}
+	a.aggs[name] = agg
+	return a
+}
+
+func (a *NestedAggregation) Source() interface{} {
+	body := map[string]interface{}{
+		"nested": map[string]interface{}{"path": a.path},
+	}
+	a.aggs.apply(body)
+	return body
+}
+
+// ReverseNestedAggregation steps back from nested objects to their parent documents
+type ReverseNestedAggregation struct{}
+
+func ReverseNestedAgg() *ReverseNestedAggregation {
+	return &ReverseNestedAggregation{}
+}
+
+func (a *ReverseNestedAggregation) Source() interface{} {
+	return map[string]interface{}{
+		"reverse_nested": map[string]interface{}{},
+	}
+}
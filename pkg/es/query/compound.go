@@ -0,0 +1,238 @@
+package query
+
+// NestedQuery runs a query against objects of a nested field
+type NestedQuery struct {
+	path      string
+	query     Query
+	scoreMode string
+}
+
+func Nested(path string, query Query) *NestedQuery {
+	return &NestedQuery{path: path, query: query}
+}
+
+// ScoreMode is avg (default), max, min, sum or none
+func (q *NestedQuery) ScoreMode(scoreMode string) *NestedQuery {
+	q.scoreMode = scoreMode
+	return q
+}
+
+func (q *NestedQuery) Source() interface{} {
+	body := map[string]interface{}{
+		"path":  q.path,
+		"query": q.query.Source(),
+	}
+	if q.scoreMode != "" {
+		body["score_mode"] = q.scoreMode
+	}
+	return map[string]interface{}{"nested": body}
+}
+
+// ScoreFunction is one entry of function_score.functions
+type ScoreFunction interface {
+	Source() interface{}
+}
+
+// WeightFunction multiplies the score of documents matching filter
+type WeightFunction struct {
+	filter Query
+	weight float64
+}
+
+func Weight(weight float64) *WeightFunction {
+	return &WeightFunction{weight: weight}
+}
+
+func (f *WeightFunction) Filter(filter Query) *WeightFunction {
+	f.filter = filter
+	return f
+}
+
+func (f *WeightFunction) Source() interface{} {
+	body := map[string]interface{}{"weight": f.weight}
+	if f.filter != nil {
+		body["filter"] = f.filter.Source()
+	}
+	return body
+}
+
+// DecayFunction lowers the score the further field is from origin
+type DecayFunction struct {
+	kind   string
+	field  string
+	origin interface{}
+	scale  string
+	offset string
+	decay  *float64
+	weight *float64
+	filter Query
+}
+
+// Gauss, Exp and Linear build the three decay curves
+func Gauss(field string) *DecayFunction  { return &DecayFunction{kind: "gauss", field: field} }
+func Exp(field string) *DecayFunction    { return &DecayFunction{kind: "exp", field: field} }
+func Linear(field string) *DecayFunction { return &DecayFunction{kind: "linear", field: field} }
+
+func (f *DecayFunction) Origin(origin interface{}) *DecayFunction {
+	f.origin = origin
+	return f
+}
+
+// Scale is the distance from origin where the score drops to Decay, e.g. "30d"
+func (f *DecayFunction) Scale(scale string) *DecayFunction {
+	f.scale = scale
+	return f
+}
+
+func (f *DecayFunction) Offset(offset string) *DecayFunction {
+	f.offset = offset
+	return f
+}
+
+func (f *DecayFunction) Decay(decay float64) *DecayFunction {
+	f.decay = &decay
+	return f
+}
+
+func (f *DecayFunction) Weight(weight float64) *DecayFunction {
+	f.weight = &weight
+	return f
+}
+
+func (f *DecayFunction) Filter(filter Query) *DecayFunction {
+	f.filter = filter
+	return f
+}
+
+func (f *DecayFunction) Source() interface{} {
+	params := map[string]interface{}{"scale": f.scale}
+	if f.origin != nil {
+		params["origin"] = f.origin
+	}
+	if f.offset != "" {
+		params["offset"] = f.offset
+	}
+	if f.decay != nil {
+		params["decay"] = *f.decay
+	}
+	body := map[string]interface{}{
+		f.kind: map[string]interface{}{f.field: params},
+	}
+	if f.weight != nil {
+		body["weight"] = *f.weight
+	}
+	if f.filter != nil {
+		body["filter"] = f.filter.Source()
+	}
+	return body
+}
+
+// FieldValueFactorFunction scores with a numeric field of the document
+type FieldValueFactorFunction struct {
+	field    string
+	factor   *float64
+	modifier string
+	missing  *float64
+}
+
+func FieldValueFactor(field string) *FieldValueFactorFunction {
+	return &FieldValueFactorFunction{field: field}
+}
+
+func (f *FieldValueFactorFunction) Factor(factor float64) *FieldValueFactorFunction {
+	f.factor = &factor
+	return f
+}
+
+// Modifier is none, log, log1p, log2p, ln, ln1p, ln2p, square, sqrt or reciprocal
+func (f *FieldValueFactorFunction) Modifier(modifier string) *FieldValueFactorFunction {
+	f.modifier = modifier
+	return f
+}
+
+func (f *FieldValueFactorFunction) Missing(missing float64) *FieldValueFactorFunction {
+	f.missing = &missing
+	return f
+}
+
+func (f *FieldValueFactorFunction) Source() interface{} {
+	params := map[string]interface{}{"field": f.field}
+	if f.factor != nil {
+		params["factor"] = *f.factor
+	}
+	if f.modifier != "" {
+		params["modifier"] = f.modifier
+	}
+	if f.missing != nil {
+		params["missing"] = *f.missing
+	}
+	return map[string]interface{}{"field_value_factor": params}
+}
+
+// FunctionScoreQuery rescores the documents of query with functions
+type FunctionScoreQuery struct {
+	query     Query
+	functions []ScoreFunction
+	scoreMode string
+	boostMode string
+	maxBoost  *float64
+	minScore  *float64
+}
+
+func FunctionScore(query Query) *FunctionScoreQuery {
+	return &FunctionScoreQuery{query: query}
+}
+
+func (q *FunctionScoreQuery) Add(functions ...ScoreFunction) *FunctionScoreQuery {
+	q.functions = append(q.functions, functions...)
+	return q
+}
+
+// ScoreMode combines the functions: multiply (default), sum, avg, first, max or min
+func (q *FunctionScoreQuery) ScoreMode(scoreMode string) *FunctionScoreQuery {
+	q.scoreMode = scoreMode
+	return q
+}
+
+// BoostMode combines the functions with the query score: multiply (default), replace, sum, avg, max or min
+func (q *FunctionScoreQuery) BoostMode(boostMode string) *FunctionScoreQuery {
+	q.boostMode = boostMode
+	return q
+}
+
+func (q *FunctionScoreQuery) MaxBoost(maxBoost float64) *FunctionScoreQuery {
+	q.maxBoost = &maxBoost
+	return q
+}
+
+func (q *FunctionScoreQuery) MinScore(minScore float64) *FunctionScoreQuery {
+	q.minScore = &minScore
+	return q
+}
+
+func (q *FunctionScoreQuery) Source() interface{} {
+	body := map[string]interface{}{}
+	if q.query != nil {
+		body["query"] = q.query.Source()
+	}
+	if len(q.functions) > 0 {
+		functions := make([]interface{}, 0, len(q.functions))
+		for _, f := range q.functions {
+			functions = append(functions, f.Source())
+		}
+		body["functions"] = functions
+	}
+	if q.scoreMode != "" {
+		body["score_mode"] = q.scoreMode
+	}
+	if q.boostMode != "" {
+		body["boost_mode"] = q.boostMode
+	}
+	if q.maxBoost != nil {
+		body["max_boost"] = *q.maxBoost
+	}
+	if q.minScore != nil {
+		body["min_score"] = *q.minScore
+	}
+	return map[string]interface{}{"function_score": body}
+}
@@ -0,0 +1,155 @@
+package query
+
+// MatchQuery is a full-text match on one field
+type MatchQuery struct {
+	field         string
+	query         interface{}
+	operator      string
+	fuzziness     string
+	prefixLength  int
+	maxExpansions int
+	analyzer      string
+	boost         *float64
+}
+
+func Match(field string, query interface{}) *MatchQuery {
+	return &MatchQuery{field: field, query: query}
+}
+
+// Operator is "or" (default) or "and"
+func (q *MatchQuery) Operator(operator string) *MatchQuery {
+	q.operator = operator
+	return q
+}
+
+// Fuzziness is "AUTO" or an edit distance such as "1"
+func (q *MatchQuery) Fuzziness(fuzziness string) *MatchQuery {
+	q.fuzziness = fuzziness
+	return q
+}
+
+// PrefixLength is the number of leading characters fuzziness leaves untouched
+func (q *MatchQuery) PrefixLength(n int) *MatchQuery {
+	q.prefixLength = n
+	return q
+}
+
+// MaxExpansions caps the terms a fuzzy query expands to
+func (q *MatchQuery) MaxExpansions(n int) *MatchQuery {
+	q.maxExpansions = n
+	return q
+}
+
+func (q *MatchQuery) Analyzer(analyzer string) *MatchQuery {
+	q.analyzer = analyzer
+	return q
+}
+
+func (q *MatchQuery) Boost(boost float64) *MatchQuery {
+	q.boost = &boost
+	return q
+}
+
+func (q *MatchQuery) Source() interface{} {
+	body := map[string]interface{}{"query": q.query}
+	if q.operator != "" {
+		body["operator"] = q.operator
+	}
+	if q.fuzziness != "" {
+		body["fuzziness"] = q.fuzziness
+	}
+	if q.prefixLength > 0 {
+		body["prefix_length"] = q.prefixLength
+	}
+	if q.maxExpansions > 0 {
+		body["max_expansions"] = q.maxExpansions
+	}
+	if q.analyzer != "" {
+		body["analyzer"] = q.analyzer
+	}
+	if q.boost != nil {
+		body["boost"] = *q.boost
+	}
+	return map[string]interface{}{
+		"match": map[string]interface{}{q.field: body},
+	}
+}
+
+// MultiMatchQuery runs one full-text query over several fields.
+// Fields accept the field^boost notation.
+type MultiMatchQuery struct {
+	query         interface{}
+	fields        []string
+	typ           string
+	operator      string
+	fuzziness     string
+	prefixLength  int
+	maxExpansions int
+	boost         *float64
+}
+
+func MultiMatch(query interface{}, fields ...string) *MultiMatchQuery {
+	return &MultiMatchQuery{query: query, fields: fields}
+}
+
+func (q *MultiMatchQuery) Field(field string) *MultiMatchQuery {
+	q.fields = append(q.fields, field)
+	return q
+}
+
+// Type is best_fields (default), most_fields, cross_fields, phrase, phrase_prefix or bool_prefix
+func (q *MultiMatchQuery) Type(typ string) *MultiMatchQuery {
+	q.typ = typ
+	return q
+}
+
+func (q *MultiMatchQuery) Operator(operator string) *MultiMatchQuery {
+	q.operator = operator
+	return q
+}
+
+func (q *MultiMatchQuery) Fuzziness(fuzziness string) *MultiMatchQuery {
+	q.fuzziness = fuzziness
+	return q
+}
+
+func (q *MultiMatchQuery) PrefixLength(n int) *MultiMatchQuery {
+	q.prefixLength = n
+	return q
+}
+
+func (q *MultiMatchQuery) MaxExpansions(n int) *MultiMatchQuery {
+	q.maxExpansions = n
+	return q
+}
+
+func (q *MultiMatchQuery) Boost(boost float64) *MultiMatchQuery {
+	q.boost = &boost
+	return q
+}
+
+func (q *MultiMatchQuery) Source() interface{} {
+	body := map[string]interface{}{"query": q.query}
+	if len(q.fields) > 0 {
+		body["fields"] = q.fields
+	}
+	if q.typ != "" {
+		body["type"] = q.typ
+	}
+	if q.operator != "" {
+		body["operator"] = q.operator
+	}
+	if q.fuzziness != "" {
+		body["fuzziness"] = q.fuzziness
+	}
+	if q.prefixLength > 0 {
+		body["prefix_length"] = q.prefixLength
+	}
+	if q.maxExpansions > 0 {
+		body["max_expansions"] = q.maxExpansions
+	}
+	if q.boost != nil {
+		body["boost"] = *q.boost
+	}
+	return map[string]interface{}{"multi_match": body}
+}
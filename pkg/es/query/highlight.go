@@ -0,0 +1,59 @@
+package query
+
+// Highlight asks ES to return the matched fragments of text fields
+type Highlight struct {
+	fields            []string
+	fragmentSize      *int
+	numberOfFragments *int
+	preTags           []string
+	postTags          []string
+}
+
+func NewHighlight(fields ...string) *Highlight {
+	return &Highlight{fields: fields}
+}
+
+func (h *Highlight) Field(field string) *Highlight {
+	h.fields = append(h.fields, field)
+	return h
+}
+
+// FragmentSize is the fragment length in characters
+func (h *Highlight) FragmentSize(size int) *Highlight {
+	h.fragmentSize = &size
+	return h
+}
+
+// NumberOfFragments caps the fragments per field, 0 returns the whole field
+func (h *Highlight) NumberOfFragments(n int) *Highlight {
+	h.numberOfFragments = &n
+	return h
+}
+
+// Tags wraps every match, ES uses <em> and </em> when unset
+func (h *Highlight) Tags(preTags, postTags []string) *Highlight {
+	h.preTags = preTags
+	h.postTags = postTags
+	return h
+}
+
+func (h *Highlight) Source() interface{} {
+	fields := make(map[string]interface{}, len(h.fields))
+	for _, f := range h.fields {
+		fields[f] = map[string]interface{}{}
+	}
+	body := map[string]interface{}{"fields": fields}
+	if h.fragmentSize != nil {
+		body["fragment_size"] = *h.fragmentSize
+	}
+	if h.numberOfFragments != nil {
+		body["number_of_fragments"] = *h.numberOfFragments
+	}
+	if len(h.preTags) > 0 {
+		body["pre_tags"] = h.preTags
+	}
+	if len(h.postTags) > 0 {
+		body["post_tags"] = h.postTags
+	}
+	return body
+}
@@ -0,0 +1,112 @@
+// Package query builds Elasticsearch query DSL with typed values instead of
+// nested maps. Every builder renders through Source into the exact JSON shape
+// Elasticsearch expects.
+package query
+
+// Query is any clause that can appear under "query"
+type Query interface {
+	// Source returns the value json.Marshal turns into the query DSL
+	Source() interface{}
+}
+
+// MatchAllQuery matches every document
+type MatchAllQuery struct {
+	boost *float64
+}
+
+func MatchAll() *MatchAllQuery {
+	return &MatchAllQuery{}
+}
+
+func (q *MatchAllQuery) Boost(boost float64) *MatchAllQuery {
+	q.boost = &boost
+	return q
+}
+
+func (q *MatchAllQuery) Source() interface{} {
+	body := map[string]interface{}{}
+	if q.boost != nil {
+		body["boost"] = *q.boost
+	}
+	return map[string]interface{}{"match_all": body}
+}
+
+// BoolQuery combines clauses with must, filter, should and must_not
+type BoolQuery struct {
+	must               []Query
+	filter             []Query
+	should             []Query
+	mustNot            []Query
+	minimumShouldMatch string
+	boost              *float64
+}
+
+func Bool() *BoolQuery {
+	return &BoolQuery{}
+}
+
+func (q *BoolQuery) Must(queries ...Query) *BoolQuery {
+	q.must = append(q.must, queries...)
+	return q
+}
+
+func (q *BoolQuery) Filter(queries ...Query) *BoolQuery {
+	q.filter = append(q.filter, queries...)
+	return q
+}
+
+func (q *BoolQuery) Should(queries ...Query) *BoolQuery {
+	q.should = append(q.should, queries...)
+	return q
+}
+
+func (q *BoolQuery) MustNot(queries ...Query) *BoolQuery {
+	q.mustNot = append(q.mustNot, queries...)
+	return q
+}
+
+func (q *BoolQuery) MinimumShouldMatch(value string) *BoolQuery {
+	q.minimumShouldMatch = value
+	return q
+}
+
+func (q *BoolQuery) Boost(boost float64) *BoolQuery {
+	q.boost = &boost
+	return q
+}
+
+// IsEmpty reports whether no clause was added
+func (q *BoolQuery) IsEmpty() bool {
+	return len(q.must) == 0 && len(q.filter) == 0 && len(q.should) == 0 && len(q.mustNot) == 0
+}
+
+func (q *BoolQuery) Source() interface{} {
+	body := map[string]interface{}{}
+	if len(q.must) > 0 {
+		body["must"] = sources(q.must)
+	}
+	if len(q.filter) > 0 {
+		body["filter"] = sources(q.filter)
+	}
+	if len(q.should) > 0 {
+		body["should"] = sources(q.should)
+	}
+	if len(q.mustNot) > 0 {
+		body["must_not"] = sources(q.mustNot)
+	}
+	if q.minimumShouldMatch != "" {
+		body["minimum_should_match"] = q.minimumShouldMatch
+	}
+	if q.boost != nil {
+		body["boost"] = *q.boost
+	}
+	return map[string]interface{}{"bool": body}
+}
+
+func sources(queries []Query) []interface{} {
+	out := make([]interface{}, 0, len(queries))
+	for _, q := range queries {
+		out = append(out, q.Source())
+	}
+	return out
+}
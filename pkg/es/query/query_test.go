@@ -0,0 +1,220 @@
+package query
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// source is anything that renders to query DSL
+type source interface {
+	Source() interface{}
+}
+
+func TestSource(t *testing.T) {
+	tests := []struct {
+		name string
+		src  source
+		want string
+	}{
+		{
+			name: "bool with every clause",
+			src: Bool().
+				Must(Match("name", "cafe").Operator("and").Fuzziness("AUTO").PrefixLength(1)).
+				Filter(Term("status", "active").Boost(2), TermsOf("type", "bar", "pub")).
+				Should(Exists("address")).
+				MustNot(Range("CreateAt").Gte("2024-01-01").Lt("now").Format("yyyy-MM-dd").TimeZone("+07:00")).
+				MinimumShouldMatch("1").
+				Boost(1.5),
+			want: `{"bool": {
+				"must": [{"match": {"name": {"query": "cafe", "operator": "and", "fuzziness": "AUTO", "prefix_length": 1}}}],
+				"filter": [
+					{"term": {"status": {"value": "active", "boost": 2}}},
+					{"terms": {"type": ["bar", "pub"]}}
+				],
+				"should": [{"exists": {"field": "address"}}],
+				"must_not": [{"range": {"CreateAt": {"gte": "2024-01-01", "lt": "now", "format": "yyyy-MM-dd", "time_zone": "+07:00"}}}],
+				"minimum_should_match": "1",
+				"boost": 1.5
+			}}`,
+		},
+		{
+			name: "empty bool",
+			src:  Bool(),
+			want: `{"bool": {}}`,
+		},
+		{
+			name: "terms without values",
+			src:  Terms("type").Boost(2),
+			want: `{"terms": {"type": [], "boost": 2}}`,
+		},
+		{
+			name: "multi_match",
+			src: MultiMatch("cafe sua", "name^3", "name.exact^6").
+				Field("address").
+				Type("best_fields").
+				Operator("or").
+				Fuzziness("AUTO:3,6").
+				PrefixLength(1).
+				MaxExpansions(50).
+				Boost(2),
+			want: `{"multi_match": {
+				"query": "cafe sua",
+				"fields": ["name^3", "name.exact^6", "address"],
+				"type": "best_fields",
+				"operator": "or",
+				"fuzziness": "AUTO:3,6",
+				"prefix_length": 1,
+				"max_expansions": 50,
+				"boost": 2
+			}}`,
+		},
+		{
+			name: "function_score with decay and weight",
+			src: FunctionScore(MatchAll()).
+				Add(
+					Gauss("CreateAt").Origin("now/h").Scale("30d").Offset("1d").Decay(0.5).Weight(2),
+					Exp("CreateAt").Scale("7d").Filter(Term("status", "active")),
+					Linear("CreateAt").Origin("now").Scale("365d"),
+					Weight(1.2).Filter(Term("status", "active")),
+					FieldValueFactor("rating").Factor(1.1).Modifier("log1p").Missing(1),
+				).
+				ScoreMode("multiply").
+				BoostMode("sum").
+				MaxBoost(10).
+				MinScore(0.1),
+			want: `{"function_score": {
+				"query": {"match_all": {}},
+				"functions": [
+					{"gauss": {"CreateAt": {"origin": "now/h", "scale": "30d", "offset": "1d", "decay": 0.5}}, "weight": 2},
+					{"exp": {"CreateAt": {"scale": "7d"}}, "filter": {"term": {"status": {"value": "active"}}}},
+					{"linear": {"CreateAt": {"origin": "now", "scale": "365d"}}},
+					{"weight": 1.2, "filter": {"term": {"status": {"value": "active"}}}},
+					{"field_value_factor": {"field": "rating", "factor": 1.1, "modifier": "log1p", "missing": 1}}
+				],
+				"score_mode": "multiply",
+				"boost_mode": "sum",
+				"max_boost": 10,
+				"min_score": 0.1
+			}}`,
+		},
+		{
+			name: "nested",
+			src:  Nested("Staffs", Bool().Filter(Term("Staffs.role", "admin"))).ScoreMode("max"),
+			want: `{"nested": {
+				"path": "Staffs",
+				"query": {"bool": {"filter": [{"term": {"Staffs.role": {"value": "admin"}}}]}},
+				"score_mode": "max"
+			}}`,
+		},
+		{
+			name: "terms agg with sub aggregation",
+			src:  TermsAgg("type").Size(10).SubAggregation("per_month", DateHistogramAgg("CreateAt", "month").Format("yyyy-MM").TimeZone("+07:00").MinDocCount(0)),
+			want: `{
+				"terms": {"field": "type", "size": 10},
+				"aggs": {"per_month": {"date_histogram": {
+					"field": "CreateAt", "calendar_interval": "month", "format": "yyyy-MM", "time_zone": "+07:00", "min_doc_count": 0
+				}}}
+			}`,
+		},
+		{
+			name: "filter and nested aggs",
+			src: FilterAgg(Term("status", "active")).
+				SubAggregation("staffs", NestedAgg("Staffs").
+					SubAggregation("roles", TermsAgg("Staffs.role").
+						SubAggregation("businesses", ReverseNestedAgg()))),
+			want: `{
+				"filter": {"term": {"status": {"value": "active"}}},
+				"aggs": {"staffs": {
+					"nested": {"path": "Staffs"},
+					"aggs": {"roles": {
+						"terms": {"field": "Staffs.role"},
+						"aggs": {"businesses": {"reverse_nested": {}}}
+					}}
+				}}
+			}`,
+		},
+		{
+			name: "highlight",
+			src:  NewHighlight("name").Field("address").FragmentSize(150).NumberOfFragments(0).Tags([]string{"<b>"}, []string{"</b>"}),
+			want: `{
+				"fields": {"name": {}, "address": {}},
+				"fragment_size": 150,
+				"number_of_fragments": 0,
+				"pre_tags": ["<b>"],
+				"post_tags": ["</b>"]
+			}`,
+		},
+		{
+			name: "sort",
+			src:  Sort{Field: "Staffs.role", Order: "asc", Missing: "_last", Mode: "min", NestedPath: "Staffs"},
+			want: `{"Staffs.role": {"order": "asc", "missing": "_last", "mode": "min", "nested": {"path": "Staffs"}}}`,
+		},
+		{
+			name: "search with cursor paging",
+			src: NewSearch().
+				Query(MatchAll().Boost(1)).
+				PostFilter(Term("type", "bar")).
+				Aggregation("types", TermsAgg("type")).
+				Highlight(NewHighlight("name")).
+				Size(20).
+				Sort(SortBy("_score", "desc"), SortBy("_shard_doc", "asc")).
+				FetchSource("name", "address").
+				SearchAfter([]json.RawMessage{json.RawMessage(`1.5`), json.RawMessage(`42`)}).
+				PointInTime("pit-id", "1m"),
+			want: `{
+				"query": {"match_all": {"boost": 1}},
+				"post_filter": {"term": {"type": {"value": "bar"}}},
+				"aggs": {"types": {"terms": {"field": "type"}}},
+				"highlight": {"fields": {"name": {}}},
+				"size": 20,
+				"sort": [{"_score": {"order": "desc"}}, {"_shard_doc": {"order": "asc"}}],
+				"_source": ["name", "address"],
+				"search_after": [1.5, 42],
+				"pit": {"id": "pit-id", "keep_alive": "1m"}
+			}`,
+		},
+		{
+			name: "search with from and size",
+			src:  NewSearch().Query(Bool()).From(10).Size(10),
+			want: `{"query": {"bool": {}}, "from": 10, "size": 10}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := json.Marshal(tt.src.Source())
+			if err != nil {
+				t.Fatalf("marshal: %v", err)
+			}
+			if want := normalize(t, tt.want); string(got) != want {
+				t.Errorf("got  %s\nwant %s", got, want)
+			}
+		})
+	}
+}
+
+func TestSearchSourceMarshalJSON(t *testing.T) {
+	search := NewSearch().Query(Term("status", "active")).Size(1)
+	got, err := json.Marshal(search)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	if want := normalize(t, `{"query": {"term": {"status": {"value": "active"}}}, "size": 1}`); string(got) != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+}
+
+// normalize rewrites a JSON document the way json.Marshal renders a map,
+// compact with sorted keys
+func normalize(t *testing.T, doc string) string {
+	t.Helper()
+	var v interface{}
+	if err := json.Unmarshal([]byte(doc), &v); err != nil {
+		t.Fatalf("bad golden JSON: %v", err)
+	}
+	out, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("marshal golden JSON: %v", err)
+	}
+	return string(out)
+}
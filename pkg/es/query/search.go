@@ -0,0 +1,173 @@
+package query
+
+import (
+	"encoding/json"
+)
+
+// Sort orders hits by one field
+type Sort struct {
+	Field      string
+	Order      string      // asc or desc
+	Missing    interface{} // _last, _first or a value
+	Mode       string      // min, max, sum, avg or median for multi-valued fields
+	NestedPath string
+}
+
+func SortBy(field, order string) Sort {
+	return Sort{Field: field, Order: order}
+}
+
+func (s Sort) Source() interface{} {
+	body := map[string]interface{}{}
+	if s.Order != "" {
+		body["order"] = s.Order
+	}
+	if s.Missing != nil {
+		body["missing"] = s.Missing
+	}
+	if s.Mode != "" {
+		body["mode"] = s.Mode
+	}
+	if s.NestedPath != "" {
+		body["nested"] = map[string]interface{}{"path": s.NestedPath}
+	}
+	return map[string]interface{}{s.Field: body}
+}
+
+// SearchSource is the body of a _search request
+type SearchSource struct {
+	query       Query
+	from        *int
+	size        *int
+	sorts       []Sort
+	source      []string
+	searchAfter []json.RawMessage
+	pitID       string
+	pitKeep     string
+	postFilter  Query
+	aggs        map[string]Aggregation
+	highlight   *Highlight
+}
+
+func NewSearch() *SearchSource {
+	return &SearchSource{}
+}
+
+func (s *SearchSource) Query(q Query) *SearchSource {
+	s.query = q
+	return s
+}
+
+func (s *SearchSource) From(from int) *SearchSource {
+	s.from = &from
+	return s
+}
+
+func (s *SearchSource) Size(size int) *SearchSource {
+	s.size = &size
+	return s
+}
+
+func (s *SearchSource) Sort(sorts ...Sort) *SearchSource {
+	s.sorts = append(s.sorts, sorts...)
+	return s
+}
+
+// PostFilter filters hits after aggregations were computed
+func (s *SearchSource) PostFilter(q Query) *SearchSource {
+	s.postFilter = q
+	return s
+}
+
+func (s *SearchSource) Aggregation(name string, agg Aggregation) *SearchSource {
+	if s.aggs == nil {
+		s.aggs = map[string]Aggregation{}
+	}
+	s.aggs[name] = agg
+	return s
+}
+
+func (s *SearchSource) Highlight(h *Highlight) *SearchSource {
+	s.highlight = h
+	return s
+}
+
+// HasSort reports whether any sort was set
+func (s *SearchSource) HasSort() bool {
+	return len(s.sorts) > 0
+}
+
+// Sorts returns the sort clauses in the order they were set
+func (s *SearchSource) Sorts() []Sort {
+	return s.sorts
+}
+
+// FetchSource limits the returned _source to fields
+func (s *SearchSource) FetchSource(fields ...string) *SearchSource {
+	s.source = append(s.source, fields...)
+	return s
+}
+
+// SearchAfter continues after the sort values of the last hit of the previous page
+func (s *SearchSource) SearchAfter(values []json.RawMessage) *SearchSource {
+	s.searchAfter = values
+	return s
+}
+
+// PointInTime searches a point in time instead of the live index
+func (s *SearchSource) PointInTime(id, keepAlive string) *SearchSource {
+	s.pitID = id
+	s.pitKeep = keepAlive
+	return s
+}
+
+func (s *SearchSource) Source() interface{} {
+	body := map[string]interface{}{}
+	if s.query != nil {
+		body["query"] = s.query.Source()
+	}
+	if s.postFilter != nil {
+		body["post_filter"] = s.postFilter.Source()
+	}
+	if len(s.aggs) > 0 {
+		aggs := make(map[string]interface{}, len(s.aggs))
+		for name, agg := range s.aggs {
+			aggs[name] = agg.Source()
+		}
+		body["aggs"] = aggs
+	}
+	if s.highlight != nil {
+		body["highlight"] = s.highlight.Source()
+	}
+	if s.from != nil {
+		body["from"] = *s.from
+	}
+	if s.size != nil {
+		body["size"] = *s.size
+	}
+	if len(s.sorts) > 0 {
+		sorts := make([]interface{}, 0, len(s.sorts))
+		for _, sort := range s.sorts {
+			sorts = append(sorts, sort.Source())
+		}
+		body["sort"] = sorts
+	}
+	if len(s.source) > 0 {
+		body["_source"] = s.source
+	}
+	if len(s.searchAfter) > 0 {
+		body["search_after"] = s.searchAfter
+	}
+	if s.pitID != "" {
+		pit := map[string]interface{}{"id": s.pitID}
+		if s.pitKeep != "" {
+			pit["keep_alive"] = s.pitKeep
+		}
+		body["pit"] = pit
+	}
+	return body
+}
+
+func (s *SearchSource) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.Source())
+}
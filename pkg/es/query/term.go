@@ -0,0 +1,159 @@
+package query
+
+// TermQuery matches an exact value on a keyword, numeric or date field
+type TermQuery struct {
+	field string
+	value interface{}
+	boost *float64
+}
+
+func Term(field string, value interface{}) *TermQuery {
+	return &TermQuery{field: field, value: value}
+}
+
+func (q *TermQuery) Boost(boost float64) *TermQuery {
+	q.boost = &boost
+	return q
+}
+
+func (q *TermQuery) Source() interface{} {
+	body := map[string]interface{}{"value": q.value}
+	if q.boost != nil {
+		body["boost"] = *q.boost
+	}
+	return map[string]interface{}{
+		"term": map[string]interface{}{q.field: body},
+	}
+}
+
+// TermsQuery matches any of the exact values
+type TermsQuery struct {
+	field  string
+	values []interface{}
+	boost  *float64
+}
+
+func Terms(field string, values ...interface{}) *TermsQuery {
+	return &TermsQuery{field: field, values: values}
+}
+
+// TermsOf is Terms for a string slice
+func TermsOf(field string, values ...string) *TermsQuery {
+	q := &TermsQuery{field: field, values: make([]interface{}, 0, len(values))}
+	for _, v := range values {
+		q.values = append(q.values, v)
+	}
+	return q
+}
+
+func (q *TermsQuery) Boost(boost float64) *TermsQuery {
+	q.boost = &boost
+	return q
+}
+
+func (q *TermsQuery) Source() interface{} {
+	values := q.values
+	if values == nil {
+		values = []interface{}{}
+	}
+	body := map[string]interface{}{q.field: values}
+	if q.boost != nil {
+		body["boost"] = *q.boost
+	}
+	return map[string]interface{}{"terms": body}
+}
+
+// RangeQuery bounds a numeric or date field
+type RangeQuery struct {
+	field    string
+	gt       interface{}
+	gte      interface{}
+	lt       interface{}
+	lte      interface{}
+	format   string
+	timeZone string
+	boost    *float64
+}
+
+func Range(field string) *RangeQuery {
+	return &RangeQuery{field: field}
+}
+
+func (q *RangeQuery) Gt(v interface{}) *RangeQuery {
+	q.gt = v
+	return q
+}
+
+func (q *RangeQuery) Gte(v interface{}) *RangeQuery {
+	q.gte = v
+	return q
+}
+
+func (q *RangeQuery) Lt(v interface{}) *RangeQuery {
+	q.lt = v
+	return q
+}
+
+func (q *RangeQuery) Lte(v interface{}) *RangeQuery {
+	q.lte = v
+	return q
+}
+
+// Format is the date format of the bounds, e.g. "strict_date_optional_time"
+func (q *RangeQuery) Format(format string) *RangeQuery {
+	q.format = format
+	return q
+}
+
+func (q *RangeQuery) TimeZone(timeZone string) *RangeQuery {
+	q.timeZone = timeZone
+	return q
+}
+
+func (q *RangeQuery) Boost(boost float64) *RangeQuery {
+	q.boost = &boost
+	return q
+}
+
+func (q *RangeQuery) Source() interface{} {
+	body := map[string]interface{}{}
+	if q.gt != nil {
+		body["gt"] = q.gt
+	}
+	if q.gte != nil {
+		body["gte"] = q.gte
+	}
+	if q.lt != nil {
+		body["lt"] = q.lt
+	}
+	if q.lte != nil {
+		body["lte"] = q.lte
+	}
+	if q.format != "" {
+		body["format"] = q.format
+	}
+	if q.timeZone != "" {
+		body["time_zone"] = q.timeZone
+	}
+	if q.boost != nil {
+		body["boost"] = *q.boost
+	}
+	return map[string]interface{}{
+		"range": map[string]interface{}{q.field: body},
+	}
+}
+
+// ExistsQuery matches documents with a value in field
+type ExistsQuery struct {
+	field string
+}
+
+func Exists(field string) *ExistsQuery {
+	return &ExistsQuery{field: field}
+}
+
+func (q *ExistsQuery) Source() interface{} {
+	return map[string]interface{}{
+		"exists": map[string]interface{}{"field": q.field},
+	}
+}
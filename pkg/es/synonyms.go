@@ -0,0 +1,88 @@
+package es
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// SynonymRule is one rule of a synonyms set in the Solr format, equivalent
+// terms "cafe, cà phê, coffee shop" or a one way mapping "coffee shop => cafe"
+type SynonymRule struct {
+	ID       string `json:"id,omitempty"`
+	Synonyms string `json:"synonyms"`
+}
+
+// PutSynonymsSet creates or replaces a synonyms set (Elasticsearch 8.10+).
+// Set ids carry the index prefix like index names do, so environments
+// sharing a cluster keep their own synonyms.
+func (c *esClient) PutSynonymsSet(ctx context.Context, setID string, rules []SynonymRule) error {
+	ctx, cancel := withTimeout(ctx, c.cfg.AdminTimeout)
+	defer cancel()
+	setID = c.index(setID)
+
+	if rules == nil {
+		rules = []SynonymRule{}
+	}
+	body, err := json.Marshal(map[string]interface{}{"synonyms_set": rules})
+	if err != nil {
+		return fmt.Errorf("failed to marshal synonyms set: %w", err)
+	}
+
+	res, err := c.client.SynonymsPutSynonym(
+		setID,
+		bytes.NewReader(body),
+		c.client.SynonymsPutSynonym.WithContext(ctx),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to put synonyms set: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return newResponseError("put synonyms set", res)
+	}
+
+	return nil
+}
+
+// ReloadSearchAnalyzers reloads the updateable search analyzers of an index
+// or of every index behind an alias
+func (c *esClient) ReloadSearchAnalyzers(ctx context.Context, indexName string) error {
+	ctx, cancel := withTimeout(ctx, c.cfg.AdminTimeout)
+	defer cancel()
+	indexName = c.index(indexName)
+
+	res, err := c.client.Indices.ReloadSearchAnalyzers(
+		[]string{indexName},
+		c.client.Indices.ReloadSearchAnalyzers.WithContext(ctx),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to reload search analyzers: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return newResponseError("reload search analyzers", res)
+	}
+
+	return nil
+}
+
+// renameSynonymsSets rewrites the synonyms_set of every analysis filter in
+// index settings, given with or without their "index" level
+func renameSynonymsSets(settings map[string]interface{}, rename func(string) string) {
+	if index, ok := settings["index"].(map[string]interface{}); ok {
+		renameSynonymsSets(index, rename)
+	}
+	analysis, _ := settings["analysis"].(map[string]interface{})
+	filters, _ := analysis["filter"].(map[string]interface{})
+	for _, filter := range filters {
+		if def, ok := filter.(map[string]interface{}); ok {
+			if set, ok := def["synonyms_set"].(string); ok {
+				def["synonyms_set"] = rename(set)
+			}
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package es
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// PutSearchTemplate stores a mustache search template as a script. Template
+// ids carry the index prefix like synonyms sets, stored scripts are shared
+// by the whole cluster.
+func (c *esClient) PutSearchTemplate(ctx context.Context, templateID string, source string) error {
+	ctx, cancel := withTimeout(ctx, c.cfg.AdminTimeout)
+	defer cancel()
+	templateID = c.index(templateID)
+
+	body, err := json.Marshal(map[string]interface{}{
+		"script": map[string]interface{}{"lang": "mustache", "source": source},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal search template: %w", err)
+	}
+
+	res, err := c.client.PutScript(
+		templateID,
+		bytes.NewReader(body),
+		c.client.PutScript.WithContext(ctx),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to put search template: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return newResponseError("put search template", res)
+	}
+
+	return nil
+}
+
+// DeleteSearchTemplate deletes a stored search template, a missing one is
+// reported as ErrNotFound
+func (c *esClient) DeleteSearchTemplate(ctx context.Context, templateID string) error {
+	ctx, cancel := withTimeout(ctx, c.cfg.AdminTimeout)
+	defer cancel()
+	templateID = c.index(templateID)
+
+	res, err := c.client.DeleteScript(
+		templateID,
+		c.client.DeleteScript.WithContext(ctx),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to delete search template: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return newResponseError("delete search template", res)
+	}
+
+	return nil
+}
+
+// RenderSearchTemplate returns the search body a stored template renders
+// to with params, without running it
+func (c *esClient) RenderSearchTemplate(ctx context.Context, templateID string, params map[string]interface{}) (json.RawMessage, error) {
+	ctx, cancel := withTimeout(ctx, c.cfg.SearchTimeout)
+	defer cancel()
+	templateID = c.index(templateID)
+
+	body, err := json.Marshal(map[string]interface{}{"params": params})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal template params: %w", err)
+	}
+
+	res, err := c.client.RenderSearchTemplate(
+		c.client.RenderSearchTemplate.WithContext(ctx),
+		c.client.RenderSearchTemplate.WithTemplateID(templateID),
+		c.client.RenderSearchTemplate.WithBody(bytes.NewReader(body)),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render search template: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return nil, newResponseError("render search template", res)
+	}
+
+	var result struct {
+		TemplateOutput json.RawMessage `json:"template_output"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return result.TemplateOutput, nil
+}
+
+// SearchTemplate runs a stored search template with params against an index
+func (c *esClient) SearchTemplate(ctx context.Context, indexName, templateID string, params map[string]interface{}) (*SearchResult, error) {
+	ctx, cancel := withTimeout(ctx, c.cfg.SearchTimeout)
+	defer cancel()
+	indexName = c.index(indexName)
+	templateID = c.index(templateID)
+
+	body, err := json.Marshal(map[string]interface{}{"id": templateID, "params": params})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal template params: %w", err)
+	}
+
+	res, err := c.client.SearchTemplate(
+		bytes.NewReader(body),
+		c.client.SearchTemplate.WithContext(ctx),
+		c.client.SearchTemplate.WithIndex(indexName),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("search template request failed: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return nil, newResponseError("search template", res)
+	}
+
+	var result SearchResult
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	for i := range result.Hits.Hits {
+		result.Hits.Hits[i].Index = c.trimIndex(result.Hits.Hits[i].Index)
+	}
+
+	return &result, nil
+}
@@ -4,17 +4,45 @@ import (
 	"business/pkg/es"
 	"business/pkg/model"
 	"business/pkg/service"
+	"business/pkg/utils"
+	"context"
+	"errors"
 	"net/http"
+	"strconv"
+	"time"
 	"gitlab.com/goxp/cloud0/ginext"
 	"gitlab.com/goxp/cloud0/logger"
 )
 
 type ElasticHandlers struct {
-	service service.EsInterface
+	service   service.EsInterface
+	searchLog service.SearchRecorder
 }
 
-func NewElasticHandlers(service *service.EsService) *ElasticHandlers {
-	return &ElasticHandlers{service: service}
+func NewElasticHandlers(service *service.EsService, searchLog service.SearchRecorder) *ElasticHandlers {
+	return &ElasticHandlers{service: service, searchLog: searchLog}
+}
+
+// apiError is a ginext client error, as returned by ginext.NewError
+type apiError interface {
+	error
+	ginext.ApiError
+}
+
+// esError turns an unavailable or slow cluster into 503 and 504 responses.
+// Client errors wrapped by the service are unwrapped, the error handler only
+// sees an ApiError returned as is.
+func esError(err error) error {
+	var apiErr apiError
+	switch {
+	case errors.As(err, &apiErr):
+		return apiErr
+	case errors.Is(err, es.ErrSearchUnavailable):
+		return ginext.NewError(http.StatusServiceUnavailable, utils.MessageError()[http.StatusServiceUnavailable])
+	case errors.Is(err, context.DeadlineExceeded):
+		return ginext.NewError(http.StatusGatewayTimeout, utils.MessageError()[http.StatusGatewayTimeout])
+	}
+	return err
 }
 
 // PushToElastic
@@ -25,7 +53,7 @@ func NewElasticHandlers(service *service.EsService) *ElasticHandlers {
 // @ID PushToElastic
 // @Accept  json
 // @Produce  json
-// @Success 200 {object} []model.Business
+// @Success 200 {object} es.BulkReport
 // @Router /api/v1/elastic/push-to-elastic [post]
 func (h *ElasticHandlers) PushToElastic(r *ginext.Request) (*ginext.Response, error) {
 	log := logger.WithCtx(r.GinCtx, "PushToElastic")
@@ -36,7 +64,7 @@ func (h *ElasticHandlers) PushToElastic(r *ginext.Request) (*ginext.Response, er
 	result, err := h.service.PushToEs(r.Context(), &req)
 	if err != nil {
 		log.WithError(err).Error("Failed to push data to Elastic")
-		return nil, err
+		return nil, esError(err)
 	}
 
 	return &ginext.Response{
@@ -47,6 +75,138 @@ func (h *ElasticHandlers) PushToElastic(r *ginext.Request) (*ginext.Response, er
 	}, nil
 }
 
+// UnifiedSearch
+// @Summary Search businesses and staffs together
+// @Description Rank businesses and staffs in one list for the global search bar, each hit carries its type and meta.counts the hits per type
+// @Tags Elastic
+// @ID UnifiedSearch
+// @Accept json
+// @Produce json
+// @Param request body es.UnifiedSearchRequest true "Search request"
+// @Success 200 {object} []es.UnifiedHit
+// @Router /api/v1/elastic/unified-search [post]
+func (h *ElasticHandlers) UnifiedSearch(r *ginext.Request) (*ginext.Response, error) {
+	log := logger.WithCtx(r.GinCtx, "UnifiedSearch")
+
+	var req es.UnifiedSearchRequest
+	r.MustBind(&req)
+
+	result, err := h.service.UnifiedSearch(r.Context(), req)
+	if err != nil {
+		log.WithError(err).Error("Failed to perform unified search")
+		return nil, esError(err)
+	}
+
+	return &ginext.Response{
+		Code: http.StatusOK,
+		GeneralBody: &ginext.GeneralBody{
+			Data: result.Hits,
+			Meta: map[string]interface{}{
+				"page":      result.Page,
+				"page_size": result.PageSize,
+				"total":     result.Total,
+				"counts":    result.Counts,
+			},
+		},
+	}, nil
+}
+
+// ReindexBusiness
+// @Tags Elastic
+// @Security ApiKeyAuth
+// @Summary Rebuild the business index without downtime
+// @Description Create the next business_vN index, fill it from postgres and swap the business alias to it
+// @ID ReindexBusiness
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} service.ReindexReport
+// @Router /api/v1/elastic/reindex [post]
+func (h *ElasticHandlers) ReindexBusiness(r *ginext.Request) (*ginext.Response, error) {
+	log := logger.WithCtx(r.GinCtx, "ReindexBusiness")
+
+	result, err := h.service.ReindexBusiness(r.Context())
+	if err != nil {
+		log.WithError(err).WithField("report", result).Error("Failed to reindex business")
+		return nil, esError(err)
+	}
+
+	return ginext.NewResponseData(http.StatusOK, result), nil
+}
+
+// MappingDrift
+// @Tags Elastic
+// @Security ApiKeyAuth
+// @Summary Compare live index mappings with their definitions
+// @Description For every managed index list the additive changes put-mapping can apply and the breaking ones that need a reindex
+// @ID MappingDrift
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} []es.MappingPlan
+// @Router /api/v1/elastic/mapping/drift [get]
+func (h *ElasticHandlers) MappingDrift(r *ginext.Request) (*ginext.Response, error) {
+	log := logger.WithCtx(r.GinCtx, "MappingDrift")
+
+	result, err := h.service.MappingDrift(r.Context())
+	if err != nil {
+		log.WithError(err).Error("Failed to compare index mappings")
+		return nil, esError(err)
+	}
+
+	return ginext.NewResponseData(http.StatusOK, result), nil
+}
+
+// MigrateMappings
+// @Tags Elastic
+// @Security ApiKeyAuth
+// @Summary Apply index definitions to the live indices
+// @Description Create missing indices and put additive mapping changes in place, breaking changes are reindexed when reindex=true
+// @ID MigrateMappings
+// @Accept  json
+// @Produce  json
+// @Param reindex query bool false "Reindex indices with breaking changes"
+// @Success 200 {object} service.MigrationReport
+// @Router /api/v1/elastic/mapping/migrate [post]
+func (h *ElasticHandlers) MigrateMappings(r *ginext.Request) (*ginext.Response, error) {
+	log := logger.WithCtx(r.GinCtx, "MigrateMappings")
+
+	reindex, _ := strconv.ParseBool(r.GinCtx.Query("reindex"))
+	result, err := h.service.MigrateMappings(r.Context(), reindex)
+	if err != nil {
+		log.WithError(err).WithField("report", result).Error("Failed to migrate index mappings")
+		return nil, esError(err)
+	}
+
+	return ginext.NewResponseData(http.StatusOK, result), nil
+}
+
+// SuggestBusiness
+// @Summary Autocomplete business names
+// @Description Return the top business names and ids for a prefix, optionally filtered by type and status
+// @Tags Elastic
+// @ID SuggestBusiness
+// @Accept json
+// @Produce json
+// @Param prefix query string true "Prefix typed by the user"
+// @Param size query int false "Number of suggestions, default 5, max 20"
+// @Param type query string false "Business type"
+// @Param status query string false "Business status"
+// @Success 200 {object} []es.Suggestion
+// @Router /api/v1/elastic/suggest [get]
+func (h *ElasticHandlers) SuggestBusiness(r *ginext.Request) (*ginext.Response, error) {
+	log := logger.WithCtx(r.GinCtx, "SuggestBusiness")
+
+	var req es.SuggestRequest
+	r.MustBind(&req)
+
+	result, err := h.service.SuggestBusiness(r.Context(), req)
+	if err != nil {
+		log.WithError(err).Error("Failed to suggest business names")
+		return nil, esError(err)
+	}
+
+	return ginext.NewResponseData(http.StatusOK, result), nil
+}
+
 // SearchByField
 // @Summary Search businesses by filters
 // @Description Search documents with multiple filters and pagination
@@ -74,10 +234,22 @@ func (h *ElasticHandlers) SearchByField(r *ginext.Request) (*ginext.Response, er
 		req.Index = "business"
 	}
 
+	start := time.Now()
 	result, err := h.service.SearchWithField(r.Context(), req)
 	if err != nil {
 		log.WithError(err).Error("Error when get list business")
-		return nil, err
+		return nil, esError(err)
+	}
+	// a search is logged once, not again for every page read with its cursor
+	if req.Cursor == "" {
+		hits, _ := result.Meta["total"].(int64)
+		h.searchLog.Record(service.SearchEntry{
+			Kind:    model.SearchKindField,
+			Request: req,
+			Hits:    hits,
+			Latency: time.Since(start),
+			UserID:  currentUserID(r),
+		})
 	}
 
 	return &ginext.Response{
@@ -85,8 +257,11 @@ func (h *ElasticHandlers) SearchByField(r *ginext.Request) (*ginext.Response, er
 		GeneralBody: &ginext.GeneralBody{
 			Data: result,
 			Meta: map[string]interface{}{
-				"page":      req.Page,
-				"page_size": req.Size,
+				"page":        req.Page,
+				"page_size":   req.Size,
+				"next_cursor": result.Meta["next_cursor"],
+				"facets":      result.Meta["facets"],
+				"fuzzy_fallback": result.Meta["fuzzy_fallback"],
 			},
 		},
 	}, nil
@@ -119,15 +294,27 @@ func (h *ElasticHandlers) FullTextSearch(r *ginext.Request) (*ginext.Response, e
 		req.Index = "business"
 	}
 
+	start := time.Now()
 	result, err := h.service.FullTextSearch(r.GinCtx, req)
 	if err != nil {
 		log.WithError(err).Error("Failed to perform full-text search")
-		return nil, err
+		return nil, esError(err)
 	}
 
 	var total int64
+	var nextCursor string
 	if result != nil {
 		total = result.Hits.Total.Value
+		nextCursor = result.NextCursor
+	}
+	if req.Cursor == "" {
+		h.searchLog.Record(service.SearchEntry{
+			Kind:    model.SearchKindFullText,
+			Request: req,
+			Hits:    total,
+			Latency: time.Since(start),
+			UserID:  currentUserID(r),
+		})
 	}
 
 	return &ginext.Response{
@@ -138,7 +325,108 @@ func (h *ElasticHandlers) FullTextSearch(r *ginext.Request) (*ginext.Response, e
 				"page": req.Page,
 				"size": req.Size,
 				"total": total,
+				"next_cursor": nextCursor,
 			},
 		},
 	}, nil
 }
+
+// Reconcile
+// @Tags Elastic
+// @Security ApiKeyAuth
+// @Summary Compare postgres with the business index
+// @Description Report businesses missing from the index, orphaned documents and stale ones, repair=true fixes them
+// @ID Reconcile
+// @Accept  json
+// @Produce  json
+// @Param repair query bool false "Index missing and stale documents, delete orphaned ones"
+// @Success 200 {object} service.ReconcileReport
+// @Router /api/v1/elastic/reconcile [post]
+func (h *ElasticHandlers) Reconcile(r *ginext.Request) (*ginext.Response, error) {
+	log := logger.WithCtx(r.GinCtx, "Reconcile")
+
+	repair, _ := strconv.ParseBool(r.GinCtx.Query("repair"))
+	result, err := h.service.Reconcile(r.Context(), repair)
+	if err != nil {
+		log.WithError(err).Error("Failed to reconcile the business index")
+		return nil, esError(err)
+	}
+
+	return ginext.NewResponseData(http.StatusOK, result), nil
+}
+
+// SearchStaff
+// @Tags Elastic
+// @Security ApiKeyAuth
+// @Summary Search staffs
+// @Description Filter staffs by username, email, role and business_id, keyword searches fullname and username
+// @ID SearchStaff
+// @Accept  json
+// @Produce  json
+// @Param request body model.GetListStaffRequest true "Search request"
+// @Success 200 {object} model.GetListStaffResponse
+// @Router /api/v1/elastic/staff/search [post]
+func (h *ElasticHandlers) SearchStaff(r *ginext.Request) (*ginext.Response, error) {
+	log := logger.WithCtx(r.GinCtx, "SearchStaff")
+
+	var req model.GetListStaffRequest
+	r.MustBind(&req)
+
+	rs, err := h.service.SearchStaff(r.Context(), &req)
+	if err != nil {
+		log.WithError(err).Error("Failed to search staff")
+		return nil, esError(err)
+	}
+
+	return &ginext.Response{
+		Code: http.StatusOK,
+		GeneralBody: &ginext.GeneralBody{
+			Data: rs.Data,
+			Meta: rs.Meta,
+		},
+	}, nil
+}
+
+// ReindexStaff
+// @Tags Elastic
+// @Security ApiKeyAuth
+// @Summary Rebuild the staff index without downtime
+// @Description Create the next staff_vN index, fill it from postgres and swap the staff alias to it. Run it once to index the existing staffs.
+// @ID ReindexStaff
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} service.ReindexReport
+// @Router /api/v1/elastic/staff/reindex [post]
+func (h *ElasticHandlers) ReindexStaff(r *ginext.Request) (*ginext.Response, error) {
+	log := logger.WithCtx(r.GinCtx, "ReindexStaff")
+
+	result, err := h.service.ReindexStaff(r.Context())
+	if err != nil {
+		log.WithError(err).WithField("report", result).Error("Failed to reindex staff")
+		return nil, esError(err)
+	}
+
+	return ginext.NewResponseData(http.StatusOK, result), nil
+}
+
+// SyncSynonyms
+// @Tags Elastic
+// @Security ApiKeyAuth
+// @Summary Push the synonym sets and reload the search analyzers
+// @Description Replace the business synonyms set with the synonym sets of postgres and reload the search analyzers of the business index, no reindex needed. Synonym set changes do it on their own, through the outbox.
+// @ID SyncSynonyms
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} service.SynonymSyncReport
+// @Router /api/v1/elastic/synonyms/reload [post]
+func (h *ElasticHandlers) SyncSynonyms(r *ginext.Request) (*ginext.Response, error) {
+	log := logger.WithCtx(r.GinCtx, "SyncSynonyms")
+
+	result, err := h.service.SyncSynonyms(r.Context())
+	if err != nil {
+		log.WithError(err).Error("Failed to sync synonyms")
+		return nil, esError(err)
+	}
+
+	return ginext.NewResponseData(http.StatusOK, result), nil
+}
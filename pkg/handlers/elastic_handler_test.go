@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"business/pkg/es/esfake"
+	"business/pkg/model"
+	"business/pkg/service"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gitlab.com/goxp/cloud0/ginext"
+)
+
+type discardRecorder struct{}
+
+func (discardRecorder) Record(service.SearchEntry) {}
+
+// noRanking resolves every search to no ranking profile
+type noRanking struct{}
+
+func (noRanking) ResolveRankingProfile(ctx context.Context, name string) (*model.RankingProfile, error) {
+	return nil, nil
+}
+
+type recordingRecorder struct{ entries []service.SearchEntry }
+
+func (r *recordingRecorder) Record(entry service.SearchEntry) { r.entries = append(r.entries, entry) }
+
+func newElasticRouter(client *esfake.Client, searchLog service.SearchRecorder) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	h := NewElasticHandlers(service.NewEsService(nil, client, noRanking{}), searchLog)
+
+	router := gin.New()
+	router.Use(ginext.CreateErrorHandler())
+	router.POST("/elastic/search-by-field", ginext.WrapHandler(h.SearchByField))
+	router.POST("/elastic/fulltext-search", ginext.WrapHandler(h.FullTextSearch))
+	return router
+}
+
+func postJSON(router *gin.Engine, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestSearchByFieldClientErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{name: "bad cursor", body: `{"cursor":"not-a-cursor"}`},
+		{name: "page beyond the result window", body: `{"page":1001,"size":10}`},
+		{name: "bad fuzziness", body: `{"filters":{"name":"cafe"},"fuzzy":{"fuzziness":"3"}}`},
+		{name: "sort on a text field", body: `{"sort":[{"field":"name"}]}`},
+	}
+	router := newElasticRouter(esfake.New(), discardRecorder{})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := postJSON(router, "/elastic/search-by-field", tt.body)
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("status = %d, want 400, body %s", w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestSearchLoggedOnceForCursorPages(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+	}{
+		{name: "search by field", path: "/elastic/search-by-field"},
+		{name: "full-text search", path: "/elastic/fulltext-search"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := esfake.New()
+			for n := 1; n <= 5; n++ {
+				id := uuid.MustParse(fmt.Sprintf("00000000-0000-0000-0000-%012d", n))
+				doc := model.Business{ID: id, Name: fmt.Sprintf("Cafe %d", n), Status: "active", CreateAt: time.Now()}
+				if err := client.IndexDocument(context.Background(), "business", id.String(), doc); err != nil {
+					t.Fatalf("IndexDocument: %v", err)
+				}
+			}
+			searchLog := &recordingRecorder{}
+			router := newElasticRouter(client, searchLog)
+
+			body, pages := `{"size": 2, "use_cursor": true}`, 0
+			for {
+				w := postJSON(router, tt.path, body)
+				if w.Code != http.StatusOK {
+					t.Fatalf("page %d: status = %d, body %s", pages+1, w.Code, w.Body.String())
+				}
+				pages++
+				var rs struct {
+					Meta struct {
+						NextCursor string `json:"next_cursor"`
+					} `json:"meta"`
+				}
+				if err := json.Unmarshal(w.Body.Bytes(), &rs); err != nil {
+					t.Fatalf("page %d: %v", pages, err)
+				}
+				if rs.Meta.NextCursor == "" || pages > 5 {
+					break
+				}
+				body = fmt.Sprintf(`{"size": 2, "cursor": %q}`, rs.Meta.NextCursor)
+			}
+
+			if pages != 3 {
+				t.Errorf("read %d pages, want 3", pages)
+			}
+			if len(searchLog.entries) != 1 || searchLog.entries[0].Request.Cursor != "" {
+				t.Errorf("logged %+v, want the first page only", searchLog.entries)
+			}
+		})
+	}
+}
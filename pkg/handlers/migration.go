@@ -22,6 +22,13 @@ func (h *MigrationHandler) Migrate(ctx *gin.Context) {
 		// TO DEMO
 		model.Business{},
 		model.Staff{},
+		model.OutboxEvent{},
+		model.SearchLog{},
+		model.SynonymSet{},
+		model.SynonymSetVersion{},
+		model.RankingProfile{},
+		model.SearchTemplate{},
+		model.SearchTemplateVersion{},
 	}
 	for _, m := range models {
 		err := h.db.AutoMigrate(m)
@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"business/pkg/model"
+	"business/pkg/service"
+	"business/pkg/utils"
+	"net/http"
+
+	"gitlab.com/goxp/cloud0/ginext"
+	"gitlab.com/goxp/cloud0/logger"
+)
+
+type OutboxHandlers struct {
+	service service.OutboxInterface
+}
+
+func NewOutboxHandlers(service service.OutboxInterface) *OutboxHandlers {
+	return &OutboxHandlers{service: service}
+}
+
+// ListOutboxEvent
+// @Tags Outbox
+// @Security ApiKeyAuth
+// @Summary List outbox events
+// @Description List outbox events, status=dead lists the events that exhausted their attempts
+// @ID ListOutboxEvent
+// @Accept  json
+// @Produce  json
+// @Param status query string false "pending, delivered or dead"
+// @Param aggregate query string false "business, staff or synonym"
+// @Param page query int false "page"
+// @Param page_size query int false "page size"
+// @Success 200 {object} model.GetListOutboxEventResponse
+// @Router /api/v1/outbox/events [get]
+func (h *OutboxHandlers) ListOutboxEvent(r *ginext.Request) (*ginext.Response, error) {
+	log := logger.WithCtx(r.GinCtx, "ListOutboxEvent")
+
+	var req model.GetListOutboxEventRequest
+	r.MustBind(&req)
+
+	rs, err := h.service.GetListOutboxEvent(r.Context(), &req)
+	if err != nil {
+		log.WithError(err).Error("Error when get list outbox event")
+		return nil, err
+	}
+
+	return &ginext.Response{
+		Code: http.StatusOK,
+		GeneralBody: &ginext.GeneralBody{
+			Data: rs.Data,
+			Meta: rs.Meta,
+		},
+	}, nil
+}
+
+// GetOneOutboxEvent
+// @Tags Outbox
+// @Security ApiKeyAuth
+// @Summary Get one outbox event
+// @Description Get an outbox event with its attempts and last error
+// @ID GetOneOutboxEvent
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Event ID"
+// @Success 200 {object} model.OutboxEvent
+// @Router /api/v1/outbox/events/{id} [get]
+func (h *OutboxHandlers) GetOneOutboxEvent(r *ginext.Request) (*ginext.Response, error) {
+	ID := utils.ParseIDFromUri(r.GinCtx)
+	if ID == nil {
+		return nil, ginext.NewError(http.StatusForbidden, "Wrong ID")
+	}
+
+	event, err := h.service.GetOneOutboxEvent(r.Context(), *ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return ginext.NewResponseData(http.StatusOK, event), nil
+}
+
+// RetryOutboxEvent
+// @Tags Outbox
+// @Security ApiKeyAuth
+// @Summary Retry a dead outbox event
+// @Description Put a dead event back in the queue with fresh attempts
+// @ID RetryOutboxEvent
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Event ID"
+// @Success 200 {object} model.OutboxEvent
+// @Router /api/v1/outbox/events/{id}/retry [post]
+func (h *OutboxHandlers) RetryOutboxEvent(r *ginext.Request) (*ginext.Response, error) {
+	ID := utils.ParseIDFromUri(r.GinCtx)
+	if ID == nil {
+		return nil, ginext.NewError(http.StatusForbidden, "Wrong ID")
+	}
+
+	event, err := h.service.RetryOutboxEvent(r.Context(), *ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return ginext.NewResponseData(http.StatusOK, event), nil
+}
@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"business/pkg/model"
+	"business/pkg/service"
+	"business/pkg/utils"
+	"net/http"
+
+	"gitlab.com/goxp/cloud0/ginext"
+	"gitlab.com/goxp/cloud0/logger"
+)
+
+type RankingProfileHandlers struct {
+	service service.RankingInterface
+}
+
+func NewRankingProfileHandlers(service service.RankingInterface) *RankingProfileHandlers {
+	return &RankingProfileHandlers{service: service}
+}
+
+// CreateRankingProfile
+// @Tags RankingProfile
+// @Security ApiKeyAuth
+// @Summary Create a ranking profile
+// @Description Create a ranking profile for fulltext-search: field boosts, a recency decay on the creation date and weights by status. A profile of the config file with the same name is overridden.
+// @ID CreateRankingProfile
+// @Accept  json
+// @Produce  json
+// @Param data body model.RankingProfileRequest true "body data"
+// @Success 200 {object} model.RankingProfile
+// @Router /api/v1/ranking-profiles [post]
+func (h *RankingProfileHandlers) CreateRankingProfile(r *ginext.Request) (*ginext.Response, error) {
+	log := logger.WithCtx(r.GinCtx, "CreateRankingProfile")
+
+	var req model.RankingProfileRequest
+	r.MustBind(&req)
+
+	profile, err := h.service.CreateRankingProfile(r.Context(), req)
+	if err != nil {
+		log.WithError(err).Error("Error when create ranking profile")
+		return nil, err
+	}
+
+	return ginext.NewResponseData(http.StatusOK, profile), nil
+}
+
+// GetListRankingProfile
+// @Tags RankingProfile
+// @Security ApiKeyAuth
+// @Summary List ranking profiles
+// @Description List the profiles a search can pick by name, from the config file and stored through the API
+// @ID GetListRankingProfile
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} []model.RankingProfile
+// @Router /api/v1/ranking-profiles [get]
+func (h *RankingProfileHandlers) GetListRankingProfile(r *ginext.Request) (*ginext.Response, error) {
+	log := logger.WithCtx(r.GinCtx, "GetListRankingProfile")
+
+	profiles, err := h.service.GetListRankingProfile(r.Context())
+	if err != nil {
+		log.WithError(err).Error("Error when get list ranking profile")
+		return nil, err
+	}
+
+	return ginext.NewResponseData(http.StatusOK, profiles), nil
+}
+
+// GetOneRankingProfile
+// @Tags RankingProfile
+// @Security ApiKeyAuth
+// @Summary Get one ranking profile
+// @Description Get a stored ranking profile
+// @ID GetOneRankingProfile
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Ranking profile ID"
+// @Success 200 {object} model.RankingProfile
+// @Router /api/v1/ranking-profiles/{id} [get]
+func (h *RankingProfileHandlers) GetOneRankingProfile(r *ginext.Request) (*ginext.Response, error) {
+	ID := utils.ParseIDFromUri(r.GinCtx)
+	if ID == nil {
+		return nil, ginext.NewError(http.StatusForbidden, "Wrong ID")
+	}
+
+	profile, err := h.service.GetOneRankingProfile(r.Context(), *ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return ginext.NewResponseData(http.StatusOK, profile), nil
+}
+
+// UpdateRankingProfile
+// @Tags RankingProfile
+// @Security ApiKeyAuth
+// @Summary Update a ranking profile
+// @Description Replace a stored ranking profile, the next searches use it
+// @ID UpdateRankingProfile
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Ranking profile ID"
+// @Param data body model.RankingProfileRequest true "body data"
+// @Success 200 {object} model.RankingProfile
+// @Router /api/v1/ranking-profiles/{id} [put]
+func (h *RankingProfileHandlers) UpdateRankingProfile(r *ginext.Request) (*ginext.Response, error) {
+	log := logger.WithCtx(r.GinCtx, "UpdateRankingProfile")
+
+	ID := utils.ParseIDFromUri(r.GinCtx)
+	if ID == nil {
+		return nil, ginext.NewError(http.StatusForbidden, "Wrong ID")
+	}
+
+	var req model.RankingProfileRequest
+	r.MustBind(&req)
+
+	profile, err := h.service.UpdateRankingProfile(r.Context(), *ID, req)
+	if err != nil {
+		log.WithError(err).Error("Error when update ranking profile")
+		return nil, err
+	}
+
+	return ginext.NewResponseData(http.StatusOK, profile), nil
+}
+
+// DeleteRankingProfile
+// @Tags RankingProfile
+// @Security ApiKeyAuth
+// @Summary Delete a ranking profile
+// @Description Delete a stored ranking profile, a config profile of the same name applies again
+// @ID DeleteRankingProfile
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Ranking profile ID"
+// @Success 200 {object} nil
+// @Router /api/v1/ranking-profiles/{id} [delete]
+func (h *RankingProfileHandlers) DeleteRankingProfile(r *ginext.Request) (*ginext.Response, error) {
+	log := logger.WithCtx(r.GinCtx, "DeleteRankingProfile")
+
+	ID := utils.ParseIDFromUri(r.GinCtx)
+	if ID == nil {
+		return nil, ginext.NewError(http.StatusForbidden, "Wrong ID")
+	}
+
+	if err := h.service.DeleteRankingProfile(r.Context(), *ID); err != nil {
+		log.WithError(err).Error("Error when delete ranking profile")
+		return nil, err
+	}
+
+	return ginext.NewResponse(http.StatusOK), nil
+}
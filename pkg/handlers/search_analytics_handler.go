@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"business/pkg/model"
+	"business/pkg/service"
+	"business/pkg/utils"
+	"net/http"
+
+	"github.com/google/uuid"
+	"gitlab.com/goxp/cloud0/ginext"
+	"gitlab.com/goxp/cloud0/logger"
+)
+
+type SearchAnalyticsHandlers struct {
+	service service.SearchAnalyticsInterface
+}
+
+func NewSearchAnalyticsHandlers(service service.SearchAnalyticsInterface) *SearchAnalyticsHandlers {
+	return &SearchAnalyticsHandlers{service: service}
+}
+
+// currentUserID is the x-user-id of the request, nil when it has none
+func currentUserID(r *ginext.Request) *uuid.UUID {
+	userID, err := utils.CurrentUser(r.GinCtx.Request)
+	if err != nil {
+		return nil
+	}
+	return &userID
+}
+
+// TopQueries
+// @Tags SearchAnalytics
+// @Security ApiKeyAuth
+// @Summary Most searched queries
+// @Description Queries of search-by-field and fulltext-search ranked by number of searches, the last 7 days by default
+// @ID TopQueries
+// @Accept  json
+// @Produce  json
+// @Param from query string false "First day, yyyy-mm-dd"
+// @Param to query string false "Last day, yyyy-mm-dd"
+// @Param index query string false "Index searched"
+// @Param limit query int false "Number of queries, default 20, max 100"
+// @Success 200 {object} []model.SearchQueryStat
+// @Router /api/v1/search-analytics/top-queries [get]
+func (h *SearchAnalyticsHandlers) TopQueries(r *ginext.Request) (*ginext.Response, error) {
+	log := logger.WithCtx(r.GinCtx, "TopQueries")
+
+	var req model.SearchReportRequest
+	r.MustBind(&req)
+
+	rs, err := h.service.TopQueries(r.Context(), &req)
+	if err != nil {
+		log.WithError(err).Error("Error when get top queries")
+		return nil, err
+	}
+
+	return ginext.NewResponseData(http.StatusOK, rs), nil
+}
+
+// ZeroResultQueries
+// @Tags SearchAnalytics
+// @Security ApiKeyAuth
+// @Summary Queries that found nothing
+// @Description Queries of searches with no hit, most searched first
+// @ID ZeroResultQueries
+// @Accept  json
+// @Produce  json
+// @Param from query string false "First day, yyyy-mm-dd"
+// @Param to query string false "Last day, yyyy-mm-dd"
+// @Param index query string false "Index searched"
+// @Param limit query int false "Number of queries, default 20, max 100"
+// @Success 200 {object} []model.SearchQueryStat
+// @Router /api/v1/search-analytics/zero-results [get]
+func (h *SearchAnalyticsHandlers) ZeroResultQueries(r *ginext.Request) (*ginext.Response, error) {
+	log := logger.WithCtx(r.GinCtx, "ZeroResultQueries")
+
+	var req model.SearchReportRequest
+	r.MustBind(&req)
+
+	rs, err := h.service.ZeroResultQueries(r.Context(), &req)
+	if err != nil {
+		log.WithError(err).Error("Error when get zero result queries")
+		return nil, err
+	}
+
+	return ginext.NewResponseData(http.StatusOK, rs), nil
+}
+
+// SlowestQueries
+// @Tags SearchAnalytics
+// @Security ApiKeyAuth
+// @Summary Slowest queries
+// @Description Queries ranked by average latency, with their maximum latency
+// @ID SlowestQueries
+// @Accept  json
+// @Produce  json
+// @Param from query string false "First day, yyyy-mm-dd"
+// @Param to query string false "Last day, yyyy-mm-dd"
+// @Param index query string false "Index searched"
+// @Param limit query int false "Number of queries, default 20, max 100"
+// @Success 200 {object} []model.SearchQueryStat
+// @Router /api/v1/search-analytics/slowest-queries [get]
+func (h *SearchAnalyticsHandlers) SlowestQueries(r *ginext.Request) (*ginext.Response, error) {
+	log := logger.WithCtx(r.GinCtx, "SlowestQueries")
+
+	var req model.SearchReportRequest
+	r.MustBind(&req)
+
+	rs, err := h.service.SlowestQueries(r.Context(), &req)
+	if err != nil {
+		log.WithError(err).Error("Error when get slowest queries")
+		return nil, err
+	}
+
+	return ginext.NewResponseData(http.StatusOK, rs), nil
+}
+
+// DailyVolume
+// @Tags SearchAnalytics
+// @Security ApiKeyAuth
+// @Summary Searches per day
+// @Description Number of searches, zero result searches, users and average latency of each day
+// @ID DailyVolume
+// @Accept  json
+// @Produce  json
+// @Param from query string false "First day, yyyy-mm-dd"
+// @Param to query string false "Last day, yyyy-mm-dd"
+// @Param index query string false "Index searched"
+// @Success 200 {object} []model.DailySearchVolume
+// @Router /api/v1/search-analytics/daily-volume [get]
+func (h *SearchAnalyticsHandlers) DailyVolume(r *ginext.Request) (*ginext.Response, error) {
+	log := logger.WithCtx(r.GinCtx, "DailyVolume")
+
+	var req model.SearchReportRequest
+	r.MustBind(&req)
+
+	rs, err := h.service.DailyVolume(r.Context(), &req)
+	if err != nil {
+		log.WithError(err).Error("Error when get daily search volume")
+		return nil, err
+	}
+
+	return ginext.NewResponseData(http.StatusOK, rs), nil
+}
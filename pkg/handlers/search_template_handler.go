@@ -0,0 +1,259 @@
+package handlers
+
+import (
+	"business/pkg/model"
+	"business/pkg/service"
+	"business/pkg/utils"
+	"net/http"
+
+	"gitlab.com/goxp/cloud0/ginext"
+	"gitlab.com/goxp/cloud0/logger"
+)
+
+type SearchTemplateHandlers struct {
+	service service.SearchTemplateInterface
+}
+
+func NewSearchTemplateHandlers(service service.SearchTemplateInterface) *SearchTemplateHandlers {
+	return &SearchTemplateHandlers{service: service}
+}
+
+// CreateSearchTemplate
+// @Tags SearchTemplate
+// @Security ApiKeyAuth
+// @Summary Create a search template
+// @Description Store a mustache search template with its parameter schema. Parameters are string, number, integer, boolean, array or object, and the source may only use declared ones.
+// @ID CreateSearchTemplate
+// @Accept  json
+// @Produce  json
+// @Param data body model.SearchTemplateRequest true "body data"
+// @Success 200 {object} model.SearchTemplate
+// @Router /api/v1/search-templates [post]
+func (h *SearchTemplateHandlers) CreateSearchTemplate(r *ginext.Request) (*ginext.Response, error) {
+	log := logger.WithCtx(r.GinCtx, "CreateSearchTemplate")
+
+	var req model.SearchTemplateRequest
+	r.MustBind(&req)
+
+	template, err := h.service.CreateSearchTemplate(r.Context(), req, currentUserID(r))
+	if err != nil {
+		log.WithError(err).Error("Error when create search template")
+		return nil, esError(err)
+	}
+
+	return ginext.NewResponseData(http.StatusOK, template), nil
+}
+
+// GetListSearchTemplate
+// @Tags SearchTemplate
+// @Security ApiKeyAuth
+// @Summary List search templates
+// @Description List search templates by name, keyword matches the name or the description
+// @ID GetListSearchTemplate
+// @Accept  json
+// @Produce  json
+// @Param keyword query string false "keyword"
+// @Param page query int false "page"
+// @Param page_size query int false "page size"
+// @Success 200 {object} model.GetListSearchTemplateResponse
+// @Router /api/v1/search-templates [get]
+func (h *SearchTemplateHandlers) GetListSearchTemplate(r *ginext.Request) (*ginext.Response, error) {
+	log := logger.WithCtx(r.GinCtx, "GetListSearchTemplate")
+
+	var req model.GetListSearchTemplateRequest
+	r.MustBind(&req)
+
+	rs, err := h.service.GetListSearchTemplate(r.Context(), &req)
+	if err != nil {
+		log.WithError(err).Error("Error when get list search template")
+		return nil, err
+	}
+
+	return &ginext.Response{
+		Code: http.StatusOK,
+		GeneralBody: &ginext.GeneralBody{
+			Data: rs.Data,
+			Meta: rs.Meta,
+		},
+	}, nil
+}
+
+// GetOneSearchTemplate
+// @Tags SearchTemplate
+// @Security ApiKeyAuth
+// @Summary Get one search template
+// @Description Get the latest version of a search template
+// @ID GetOneSearchTemplate
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Search template ID"
+// @Success 200 {object} model.SearchTemplate
+// @Router /api/v1/search-templates/{id} [get]
+func (h *SearchTemplateHandlers) GetOneSearchTemplate(r *ginext.Request) (*ginext.Response, error) {
+	ID := utils.ParseIDFromUri(r.GinCtx)
+	if ID == nil {
+		return nil, ginext.NewError(http.StatusForbidden, "Wrong ID")
+	}
+
+	template, err := h.service.GetOneSearchTemplate(r.Context(), *ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return ginext.NewResponseData(http.StatusOK, template), nil
+}
+
+// UpdateSearchTemplate
+// @Tags SearchTemplate
+// @Security ApiKeyAuth
+// @Summary Update a search template
+// @Description Store a new version of a search template, the previous versions can still be run by number
+// @ID UpdateSearchTemplate
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Search template ID"
+// @Param data body model.SearchTemplateRequest true "body data"
+// @Success 200 {object} model.SearchTemplate
+// @Router /api/v1/search-templates/{id} [put]
+func (h *SearchTemplateHandlers) UpdateSearchTemplate(r *ginext.Request) (*ginext.Response, error) {
+	log := logger.WithCtx(r.GinCtx, "UpdateSearchTemplate")
+
+	ID := utils.ParseIDFromUri(r.GinCtx)
+	if ID == nil {
+		return nil, ginext.NewError(http.StatusForbidden, "Wrong ID")
+	}
+
+	var req model.SearchTemplateRequest
+	r.MustBind(&req)
+
+	template, err := h.service.UpdateSearchTemplate(r.Context(), *ID, req, currentUserID(r))
+	if err != nil {
+		log.WithError(err).Error("Error when update search template")
+		return nil, esError(err)
+	}
+
+	return ginext.NewResponseData(http.StatusOK, template), nil
+}
+
+// DeleteSearchTemplate
+// @Tags SearchTemplate
+// @Security ApiKeyAuth
+// @Summary Delete a search template
+// @Description Delete a search template with every stored version, its history is kept
+// @ID DeleteSearchTemplate
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Search template ID"
+// @Success 200 {object} nil
+// @Router /api/v1/search-templates/{id} [delete]
+func (h *SearchTemplateHandlers) DeleteSearchTemplate(r *ginext.Request) (*ginext.Response, error) {
+	log := logger.WithCtx(r.GinCtx, "DeleteSearchTemplate")
+
+	ID := utils.ParseIDFromUri(r.GinCtx)
+	if ID == nil {
+		return nil, ginext.NewError(http.StatusForbidden, "Wrong ID")
+	}
+
+	if err := h.service.DeleteSearchTemplate(r.Context(), *ID, currentUserID(r)); err != nil {
+		log.WithError(err).Error("Error when delete search template")
+		return nil, err
+	}
+
+	return ginext.NewResponse(http.StatusOK), nil
+}
+
+// GetListSearchTemplateVersion
+// @Tags SearchTemplate
+// @Security ApiKeyAuth
+// @Summary List the versions of a search template
+// @Description Every change of a template with the user who made it, latest first. A deleted template keeps its versions.
+// @ID GetListSearchTemplateVersion
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Search template ID"
+// @Success 200 {object} []model.SearchTemplateVersion
+// @Router /api/v1/search-templates/{id}/versions [get]
+func (h *SearchTemplateHandlers) GetListSearchTemplateVersion(r *ginext.Request) (*ginext.Response, error) {
+	ID := utils.ParseIDFromUri(r.GinCtx)
+	if ID == nil {
+		return nil, ginext.NewError(http.StatusForbidden, "Wrong ID")
+	}
+
+	versions, err := h.service.GetListSearchTemplateVersion(r.Context(), *ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return ginext.NewResponseData(http.StatusOK, versions), nil
+}
+
+// RenderSearchTemplate
+// @Tags SearchTemplate
+// @Security ApiKeyAuth
+// @Summary Preview a search template
+// @Description Render a template with parameters to the search it would run, for debugging
+// @ID RenderSearchTemplate
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Search template ID"
+// @Param data body model.RunSearchTemplateRequest true "body data"
+// @Success 200 {object} model.RenderedSearchTemplate
+// @Router /api/v1/search-templates/{id}/render [post]
+func (h *SearchTemplateHandlers) RenderSearchTemplate(r *ginext.Request) (*ginext.Response, error) {
+	log := logger.WithCtx(r.GinCtx, "RenderSearchTemplate")
+
+	ID := utils.ParseIDFromUri(r.GinCtx)
+	if ID == nil {
+		return nil, ginext.NewError(http.StatusForbidden, "Wrong ID")
+	}
+
+	var req model.RunSearchTemplateRequest
+	r.MustBind(&req)
+
+	rendered, err := h.service.RenderSearchTemplate(r.Context(), *ID, req)
+	if err != nil {
+		log.WithError(err).Error("Failed to render search template")
+		return nil, esError(err)
+	}
+
+	return ginext.NewResponseData(http.StatusOK, rendered), nil
+}
+
+// RunSearchTemplate
+// @Tags Elastic
+// @Summary Search with a stored template
+// @Description Run a search template with a parameter map checked against its schema, version runs an older version than the latest
+// @ID RunSearchTemplate
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Search template ID"
+// @Param data body model.RunSearchTemplateRequest true "body data"
+// @Success 200 {object} es.SearchResult
+// @Router /api/v1/elastic/templates/{id}/search [post]
+func (h *SearchTemplateHandlers) RunSearchTemplate(r *ginext.Request) (*ginext.Response, error) {
+	log := logger.WithCtx(r.GinCtx, "RunSearchTemplate")
+
+	ID := utils.ParseIDFromUri(r.GinCtx)
+	if ID == nil {
+		return nil, ginext.NewError(http.StatusForbidden, "Wrong ID")
+	}
+
+	var req model.RunSearchTemplateRequest
+	r.MustBind(&req)
+
+	result, err := h.service.RunSearchTemplate(r.Context(), *ID, req)
+	if err != nil {
+		log.WithError(err).Error("Failed to run search template")
+		return nil, esError(err)
+	}
+
+	return &ginext.Response{
+		Code: http.StatusOK,
+		GeneralBody: &ginext.GeneralBody{
+			Data: result,
+			Meta: map[string]interface{}{
+				"total": result.Hits.Total.Value,
+			},
+		},
+	}, nil
+}
@@ -0,0 +1,188 @@
+package handlers
+
+import (
+	"business/pkg/model"
+	"business/pkg/service"
+	"business/pkg/utils"
+	"net/http"
+
+	"gitlab.com/goxp/cloud0/ginext"
+	"gitlab.com/goxp/cloud0/logger"
+)
+
+type SynonymHandlers struct {
+	service service.SynonymInterface
+}
+
+func NewSynonymHandlers(service service.SynonymInterface) *SynonymHandlers {
+	return &SynonymHandlers{service: service}
+}
+
+// CreateSynonymSet
+// @Tags Synonym
+// @Security ApiKeyAuth
+// @Summary Create a synonym set
+// @Description Create a synonym set in the Solr format, "cafe, cà phê" or "coffee shop => cafe". Business searches use it once it reached Elasticsearch, without a reindex.
+// @ID CreateSynonymSet
+// @Accept  json
+// @Produce  json
+// @Param data body model.SynonymSetRequest true "body data"
+// @Success 200 {object} model.SynonymSet
+// @Router /api/v1/synonyms [post]
+func (h *SynonymHandlers) CreateSynonymSet(r *ginext.Request) (*ginext.Response, error) {
+	log := logger.WithCtx(r.GinCtx, "CreateSynonymSet")
+
+	var req model.SynonymSetRequest
+	r.MustBind(&req)
+
+	set, err := h.service.CreateSynonymSet(r.Context(), req, currentUserID(r))
+	if err != nil {
+		log.WithError(err).Error("Error when create synonym set")
+		return nil, err
+	}
+
+	return ginext.NewResponseData(http.StatusOK, set), nil
+}
+
+// GetListSynonymSet
+// @Tags Synonym
+// @Security ApiKeyAuth
+// @Summary List synonym sets
+// @Description List synonym sets by name, keyword matches the name or the synonyms
+// @ID GetListSynonymSet
+// @Accept  json
+// @Produce  json
+// @Param keyword query string false "keyword"
+// @Param page query int false "page"
+// @Param page_size query int false "page size"
+// @Success 200 {object} model.GetListSynonymSetResponse
+// @Router /api/v1/synonyms [get]
+func (h *SynonymHandlers) GetListSynonymSet(r *ginext.Request) (*ginext.Response, error) {
+	log := logger.WithCtx(r.GinCtx, "GetListSynonymSet")
+
+	var req model.GetListSynonymSetRequest
+	r.MustBind(&req)
+
+	rs, err := h.service.GetListSynonymSet(r.Context(), &req)
+	if err != nil {
+		log.WithError(err).Error("Error when get list synonym set")
+		return nil, err
+	}
+
+	return &ginext.Response{
+		Code: http.StatusOK,
+		GeneralBody: &ginext.GeneralBody{
+			Data: rs.Data,
+			Meta: rs.Meta,
+		},
+	}, nil
+}
+
+// GetOneSynonymSet
+// @Tags Synonym
+// @Security ApiKeyAuth
+// @Summary Get one synonym set
+// @Description Get one synonym set
+// @ID GetOneSynonymSet
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Synonym set ID"
+// @Success 200 {object} model.SynonymSet
+// @Router /api/v1/synonyms/{id} [get]
+func (h *SynonymHandlers) GetOneSynonymSet(r *ginext.Request) (*ginext.Response, error) {
+	ID := utils.ParseIDFromUri(r.GinCtx)
+	if ID == nil {
+		return nil, ginext.NewError(http.StatusForbidden, "Wrong ID")
+	}
+
+	set, err := h.service.GetOneSynonymSet(r.Context(), *ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return ginext.NewResponseData(http.StatusOK, set), nil
+}
+
+// UpdateSynonymSet
+// @Tags Synonym
+// @Security ApiKeyAuth
+// @Summary Update a synonym set
+// @Description Replace the name and synonyms of a set, the previous ones stay in its versions
+// @ID UpdateSynonymSet
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Synonym set ID"
+// @Param data body model.SynonymSetRequest true "body data"
+// @Success 200 {object} model.SynonymSet
+// @Router /api/v1/synonyms/{id} [put]
+func (h *SynonymHandlers) UpdateSynonymSet(r *ginext.Request) (*ginext.Response, error) {
+	log := logger.WithCtx(r.GinCtx, "UpdateSynonymSet")
+
+	ID := utils.ParseIDFromUri(r.GinCtx)
+	if ID == nil {
+		return nil, ginext.NewError(http.StatusForbidden, "Wrong ID")
+	}
+
+	var req model.SynonymSetRequest
+	r.MustBind(&req)
+
+	set, err := h.service.UpdateSynonymSet(r.Context(), *ID, req, currentUserID(r))
+	if err != nil {
+		log.WithError(err).Error("Error when update synonym set")
+		return nil, err
+	}
+
+	return ginext.NewResponseData(http.StatusOK, set), nil
+}
+
+// DeleteSynonymSet
+// @Tags Synonym
+// @Security ApiKeyAuth
+// @Summary Delete a synonym set
+// @Description Delete a synonym set, its versions are kept
+// @ID DeleteSynonymSet
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Synonym set ID"
+// @Success 200 {object} nil
+// @Router /api/v1/synonyms/{id} [delete]
+func (h *SynonymHandlers) DeleteSynonymSet(r *ginext.Request) (*ginext.Response, error) {
+	log := logger.WithCtx(r.GinCtx, "DeleteSynonymSet")
+
+	ID := utils.ParseIDFromUri(r.GinCtx)
+	if ID == nil {
+		return nil, ginext.NewError(http.StatusForbidden, "Wrong ID")
+	}
+
+	if err := h.service.DeleteSynonymSet(r.Context(), *ID, currentUserID(r)); err != nil {
+		log.WithError(err).Error("Error when delete synonym set")
+		return nil, err
+	}
+
+	return ginext.NewResponse(http.StatusOK), nil
+}
+
+// GetListSynonymSetVersion
+// @Tags Synonym
+// @Security ApiKeyAuth
+// @Summary List the versions of a synonym set
+// @Description Every change of a set with the user who made it, latest first. A deleted set keeps its versions.
+// @ID GetListSynonymSetVersion
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Synonym set ID"
+// @Success 200 {object} []model.SynonymSetVersion
+// @Router /api/v1/synonyms/{id}/versions [get]
+func (h *SynonymHandlers) GetListSynonymSetVersion(r *ginext.Request) (*ginext.Response, error) {
+	ID := utils.ParseIDFromUri(r.GinCtx)
+	if ID == nil {
+		return nil, ginext.NewError(http.StatusForbidden, "Wrong ID")
+	}
+
+	versions, err := h.service.GetListSynonymSetVersion(r.Context(), *ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return ginext.NewResponseData(http.StatusOK, versions), nil
+}
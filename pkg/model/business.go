@@ -6,16 +6,17 @@ import (
 	"github.com/google/uuid"
 )
 
+// Business is also the document of the business index, es tags declare its mapping
 type Business struct {
 	ID          uuid.UUID `gorm:"primary_key;type:uuid;default:uuid_generate_v4()"`
-	Name        string    `json:"name"`
-	Description string    `gorm:"type:text"`
-	Address     string    `json:"address"`
-	BusinessType        string    `json:"type"`
-	Status      string    `json:"status"`
+	Name        string    `json:"name" es:"text,analyzer=vi_folded,search_analyzer=vi_folded_synonym,field=exact:text:vi_exact,field=suggest:search_as_you_type:vi_folded"`
+	Description string    `gorm:"type:text" es:"text,analyzer=vi_folded,search_analyzer=vi_folded_synonym,field=exact:text:vi_exact"`
+	Address     string    `json:"address" es:"text,analyzer=vi_folded,search_analyzer=vi_folded_synonym,field=exact:text:vi_exact"`
+	BusinessType        string    `json:"type" es:"keyword"`
+	Status      string    `json:"status" es:"keyword"`
 	CreateAt    time.Time `gorm:"column:created_at"`
-	Staffs []Staff `gorm:"foreignKey:BusinessID"`
-	WorkerName string `json:"woker_name"`
+	Staffs []Staff `gorm:"foreignKey:BusinessID" es:"nested"`
+	WorkerName string `json:"woker_name" es:"text"`
 }
 
 type BusinessRequest struct {
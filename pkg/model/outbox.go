@@ -0,0 +1,67 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Outbox event states
+const (
+	OutboxStatusPending   = "pending"
+	OutboxStatusDelivered = "delivered"
+	OutboxStatusDead      = "dead"
+)
+
+// Outbox aggregates and event types
+const (
+	OutboxAggregateBusiness = "business"
+	OutboxAggregateStaff    = "staff"
+	OutboxAggregateSynonym  = "synonym"
+
+	OutboxEventCreated = "created"
+	OutboxEventUpdated = "updated"
+	OutboxEventDeleted = "deleted"
+)
+
+// OutboxEvent is a change written in the same transaction as the change
+// itself, a relay delivers it to the sinks afterwards
+type OutboxEvent struct {
+	ID          uuid.UUID       `gorm:"primary_key;type:uuid;default:uuid_generate_v4()" json:"id"`
+	Aggregate   string          `gorm:"column:aggregate;not null" json:"aggregate"`
+	AggregateID uuid.UUID       `gorm:"column:aggregate_id;type:uuid;not null" json:"aggregate_id"`
+	EventType   string          `gorm:"column:event_type;not null" json:"event_type"`
+	Payload     json.RawMessage `gorm:"column:payload;type:jsonb" json:"payload" swaggertype:"object"`
+	Status      string          `gorm:"column:status;not null;index:idx_outbox_event_due,priority:1" json:"status"`
+	Attempts    int             `gorm:"column:attempts;not null;default:0" json:"attempts"`
+	// NextAttemptAt is when the relay picks the event up, also the end of
+	// the lease of a relay delivering it
+	NextAttemptAt time.Time `gorm:"column:next_attempt_at;index:idx_outbox_event_due,priority:2" json:"next_attempt_at"`
+	// DeliveredSinks lists the sinks that already accepted the event, comma separated
+	DeliveredSinks string     `gorm:"column:delivered_sinks" json:"delivered_sinks"`
+	LastError      string     `gorm:"column:last_error;type:text" json:"last_error"`
+	CreateAt       time.Time  `gorm:"column:created_at;default:CURRENT_TIMESTAMP" json:"created_at"`
+	DeliveredAt    *time.Time `gorm:"column:delivered_at" json:"delivered_at"`
+}
+
+// OutboxPayload is the payload of business, staff and synonym events
+type OutboxPayload struct {
+	Business   *Business   `json:"business,omitempty"`
+	Staff      *Staff      `json:"staff,omitempty"`
+	SynonymSet *SynonymSet `json:"synonym_set,omitempty"`
+	// PreviousBusinessID is set when a staff moved to another business
+	PreviousBusinessID *uuid.UUID `json:"previous_business_id,omitempty"`
+}
+
+type GetListOutboxEventRequest struct {
+	Status    *string `json:"status,omitempty" form:"status"`
+	Aggregate *string `json:"aggregate,omitempty" form:"aggregate"`
+	Page      int     `json:"page" form:"page"`
+	PageSize  int     `json:"page_size" form:"page_size"`
+}
+
+type GetListOutboxEventResponse struct {
+	Data []OutboxEvent          `json:"data"`
+	Meta map[string]interface{} `json:"meta"`
+}
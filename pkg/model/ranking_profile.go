@@ -0,0 +1,91 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Ranking profile sources
+const (
+	RankingSourceConfig = "config"
+	RankingSourceStored = "stored"
+)
+
+// RankingProfile tunes the relevance of the business full-text search.
+// FieldBoosts weighs the fields of the multi_match, Recency favours recently
+// created businesses and StatusWeights multiplies the score by status.
+type RankingProfile struct {
+	ID          uuid.UUID `gorm:"primary_key;type:uuid;default:uuid_generate_v4()" json:"id"`
+	Name        string    `gorm:"column:name;unique;not null" json:"name"`
+	Description string    `gorm:"column:description;type:text" json:"description"`
+	// FieldBoosts maps an indexed field (name, Description, address, type, status) to its boost
+	FieldBoosts   Weights       `gorm:"column:field_boosts;type:jsonb" json:"field_boosts" swaggertype:"object"`
+	Recency       *RecencyDecay `gorm:"column:recency;type:jsonb" json:"recency,omitempty"`
+	StatusWeights Weights       `gorm:"column:status_weights;type:jsonb" json:"status_weights" swaggertype:"object"`
+	// BoostMode combines the recency and status functions with the text score, multiply by default
+	BoostMode string    `gorm:"column:boost_mode" json:"boost_mode,omitempty"`
+	CreateAt  time.Time `gorm:"column:created_at;default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdateAt  time.Time `gorm:"column:updated_at;default:CURRENT_TIMESTAMP" json:"updated_at"`
+
+	// Source tells a profile of the config file from one stored through the API
+	Source string `gorm:"-" json:"source"`
+}
+
+// RecencyDecay lowers the score of businesses the older they are
+type RecencyDecay struct {
+	// Function is gauss (default), exp or linear
+	Function string `json:"function,omitempty" example:"gauss"`
+	// Scale is the age where the score is multiplied by Decay
+	Scale string `json:"scale" example:"30d"`
+	// Offset is the age under which businesses are not decayed
+	Offset string  `json:"offset,omitempty" example:"7d"`
+	Decay  float64 `json:"decay,omitempty" example:"0.5"`
+}
+
+func (r RecencyDecay) Value() (driver.Value, error) {
+	return json.Marshal(r)
+}
+
+func (r *RecencyDecay) Scan(value interface{}) error {
+	return scanJSON(value, r)
+}
+
+// Weights maps a field or a value to a multiplier, stored as jsonb
+type Weights map[string]float64
+
+func (w Weights) Value() (driver.Value, error) {
+	if w == nil {
+		return nil, nil
+	}
+	return json.Marshal(w)
+}
+
+func (w *Weights) Scan(value interface{}) error {
+	return scanJSON(value, w)
+}
+
+func scanJSON(value interface{}, dst interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case []byte:
+		return json.Unmarshal(v, dst)
+	case string:
+		return json.Unmarshal([]byte(v), dst)
+	default:
+		return fmt.Errorf("can't scan %T as json", value)
+	}
+}
+
+type RankingProfileRequest struct {
+	Name          string        `json:"name" binding:"required" example:"default"`
+	Description   string        `json:"description"`
+	FieldBoosts   Weights       `json:"field_boosts" swaggertype:"object"`
+	Recency       *RecencyDecay `json:"recency,omitempty"`
+	StatusWeights Weights       `json:"status_weights" swaggertype:"object"`
+	BoostMode     string        `json:"boost_mode,omitempty" example:"multiply"`
+}
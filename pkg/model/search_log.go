@@ -0,0 +1,68 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Search log kinds, the endpoint a search came from
+const (
+	SearchKindField    = "field"
+	SearchKindFullText = "fulltext"
+)
+
+// SearchLog records one search for the analytics reports
+type SearchLog struct {
+	ID    uuid.UUID `gorm:"primary_key;type:uuid;default:uuid_generate_v4()" json:"id"`
+	Kind  string    `gorm:"column:kind;not null" json:"kind"`
+	Index string    `gorm:"column:index_name;not null" json:"index"`
+	// Query is the text the user typed, lowercased with its spaces collapsed
+	Query     string          `gorm:"column:query;not null;index" json:"query"`
+	Filters   json.RawMessage `gorm:"column:filters;type:jsonb" json:"filters" swaggertype:"object"`
+	Hits      int64           `gorm:"column:hits;not null" json:"hits"`
+	LatencyMs int64           `gorm:"column:latency_ms;not null" json:"latency_ms"`
+	UserID    *uuid.UUID      `gorm:"column:user_id;type:uuid" json:"user_id"`
+	CreateAt  time.Time       `gorm:"column:created_at;not null;index" json:"created_at"`
+}
+
+// SearchReportRequest picks the searches a report covers, dates are
+// yyyy-mm-dd and both ends are included
+type SearchReportRequest struct {
+	From  string  `json:"from" form:"from" example:"2026-10-01"`
+	To    string  `json:"to" form:"to" example:"2026-10-07"`
+	Index *string `json:"index,omitempty" form:"index"`
+	Limit int     `json:"limit" form:"limit" example:"20"`
+}
+
+// SearchLogRange is a SearchReportRequest once validated, To is excluded
+type SearchLogRange struct {
+	From time.Time
+	To   time.Time
+	// TimeZone is the IANA name of the zone From and To are midnights in,
+	// days are counted in it too
+	TimeZone string
+	Index    *string
+	Limit    int
+}
+
+// SearchQueryStat aggregates the searches of one query
+type SearchQueryStat struct {
+	Query          string    `json:"query"`
+	Searches       int64     `json:"searches"`
+	ZeroResults    int64     `json:"zero_results"`
+	AvgHits        float64   `json:"avg_hits"`
+	AvgLatencyMs   float64   `json:"avg_latency_ms"`
+	MaxLatencyMs   int64     `json:"max_latency_ms"`
+	LastSearchedAt time.Time `json:"last_searched_at"`
+}
+
+// DailySearchVolume aggregates the searches of one day
+type DailySearchVolume struct {
+	Day          time.Time `json:"day"`
+	Searches     int64     `json:"searches"`
+	ZeroResults  int64     `json:"zero_results"`
+	Users        int64     `json:"users"`
+	AvgLatencyMs float64   `json:"avg_latency_ms"`
+}
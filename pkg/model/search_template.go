@@ -0,0 +1,116 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Search template versions actions
+const (
+	SearchTemplateActionCreated = "created"
+	SearchTemplateActionUpdated = "updated"
+	SearchTemplateActionDeleted = "deleted"
+)
+
+// Types of a search template parameter, as they come in json
+const (
+	TemplateParamString  = "string"
+	TemplateParamNumber  = "number"
+	TemplateParamInteger = "integer"
+	TemplateParamBoolean = "boolean"
+	TemplateParamArray   = "array"
+	TemplateParamObject  = "object"
+)
+
+// SearchTemplate is a mustache search stored in Elasticsearch, clients run
+// it with parameters checked against Params. Every version stays stored, so
+// a client can pin one while the template moves on.
+type SearchTemplate struct {
+	ID          uuid.UUID `gorm:"primary_key;type:uuid;default:uuid_generate_v4()" json:"id"`
+	Name        string    `gorm:"column:name;unique;not null" json:"name"`
+	Description string    `gorm:"column:description;type:text" json:"description"`
+	// Index is the alias the template searches
+	Index  string         `gorm:"column:index_name;not null" json:"index"`
+	Source string         `gorm:"column:source;type:text;not null" json:"source"`
+	Params TemplateParams `gorm:"column:params;type:jsonb" json:"params"`
+	// Version is the latest SearchTemplateVersion, the one run by default
+	Version  int       `gorm:"column:version;not null" json:"version"`
+	CreateAt time.Time `gorm:"column:created_at;default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdateAt time.Time `gorm:"column:updated_at;default:CURRENT_TIMESTAMP" json:"updated_at"`
+}
+
+// SearchTemplateVersion keeps a search template as it was after each
+// change, deletions included
+type SearchTemplateVersion struct {
+	ID         uuid.UUID      `gorm:"primary_key;type:uuid;default:uuid_generate_v4()" json:"id"`
+	TemplateID uuid.UUID      `gorm:"column:template_id;type:uuid;not null;uniqueIndex:idx_search_template_version,priority:1" json:"template_id"`
+	Version    int            `gorm:"column:version;not null;uniqueIndex:idx_search_template_version,priority:2" json:"version"`
+	Action     string         `gorm:"column:action;not null" json:"action"`
+	Name       string         `gorm:"column:name;not null" json:"name"`
+	Index      string         `gorm:"column:index_name;not null" json:"index"`
+	Source     string         `gorm:"column:source;type:text;not null" json:"source"`
+	Params     TemplateParams `gorm:"column:params;type:jsonb" json:"params"`
+	UserID     *uuid.UUID     `gorm:"column:user_id;type:uuid" json:"user_id"`
+	CreateAt   time.Time      `gorm:"column:created_at;default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+// TemplateParam declares a parameter of a search template
+type TemplateParam struct {
+	Name        string      `json:"name" example:"keyword"`
+	Type        string      `json:"type" example:"string"`
+	Required    bool        `json:"required,omitempty"`
+	Default     interface{} `json:"default,omitempty" swaggertype:"object"`
+	Description string      `json:"description,omitempty"`
+}
+
+// TemplateParams is the parameter schema of a template, stored as jsonb
+type TemplateParams []TemplateParam
+
+func (p TemplateParams) Value() (driver.Value, error) {
+	if p == nil {
+		return nil, nil
+	}
+	return json.Marshal(p)
+}
+
+func (p *TemplateParams) Scan(value interface{}) error {
+	return scanJSON(value, p)
+}
+
+type SearchTemplateRequest struct {
+	Name        string         `json:"name" binding:"required" example:"business_by_keyword"`
+	Description string         `json:"description"`
+	Index       string         `json:"index" binding:"required" example:"business"`
+	Source      string         `json:"source" binding:"required" example:"{\"query\":{\"match\":{\"name\":\"{{keyword}}\"}},\"size\":{{size}}}"`
+	Params      TemplateParams `json:"params"`
+}
+
+type GetListSearchTemplateRequest struct {
+	Keyword  string `json:"keyword" form:"keyword"`
+	Page     int    `json:"page" form:"page"`
+	PageSize int    `json:"page_size" form:"page_size"`
+}
+
+type GetListSearchTemplateResponse struct {
+	Data []SearchTemplate       `json:"data"`
+	Meta map[string]interface{} `json:"meta"`
+}
+
+// RunSearchTemplateRequest runs or renders a template, Version picks an
+// older version than the latest
+type RunSearchTemplateRequest struct {
+	Params  map[string]interface{} `json:"params" swaggertype:"object"`
+	Version int                    `json:"version,omitempty" example:"0"`
+}
+
+// RenderedSearchTemplate is the search a template renders to
+type RenderedSearchTemplate struct {
+	TemplateID uuid.UUID              `json:"template_id"`
+	Version    int                    `json:"version"`
+	Index      string                 `json:"index"`
+	Params     map[string]interface{} `json:"params"`
+	Rendered   json.RawMessage        `json:"rendered" swaggertype:"object"`
+}
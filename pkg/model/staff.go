@@ -6,17 +6,28 @@ import (
 	"github.com/google/uuid"
 )
 
+// Staff is also the document of the staff index and nested in business
+// documents. Its es tags declare the nested mapping, changing them is a
+// breaking change of the business index. The password is never indexed.
 type Staff struct {
 	ID         uuid.UUID `gorm:"primary_key;type:uuid;default:uuid_generate_v4()" json:"id"`
 	Username   string    `gorm:"column:username;unique;not null" json:"username"`
-	Password   string    `gorm:"column:password;not null" json:"-"` 
-	Fullname   string    `gorm:"column:fullname" json:"fullname"` 
+	Password   string    `gorm:"column:password;not null" json:"-" es:"-"` 
+	Fullname   string    `gorm:"column:fullname" json:"fullname" es:"text,analyzer=vi_folded,field=exact:text:vi_exact"` 
 	Email      string    `gorm:"column:email;unique;not null" json:"email"`
 	Role       string    `gorm:"column:role;not null" json:"role"`
 	CreateAt   time.Time `gorm:"column:created_at;default:CURRENT_TIMESTAMP" json:"created_at"`
 	BusinessID uuid.UUID `gorm:"column:business_id" json:"business_id"`
 }
 
+// StaffDocument declares the mapping of the staff index, which stores Staff
+// documents with a full-text username on top. It is never marshalled, the
+// fields declared here take precedence over the embedded ones.
+type StaffDocument struct {
+	Staff
+	Username string `json:"username" es:"keyword,field=text:text:vi_folded"`
+}
+
 type StaffRequest struct {
 	ID         uuid.UUID `gorm:"primary_key;type:uuid;default:uuid_generate_v4()" json:"id"`
 	Username   string    `json:"username" binding:"required"`
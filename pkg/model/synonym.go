@@ -0,0 +1,56 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Synonym set version actions
+const (
+	SynonymActionCreated = "created"
+	SynonymActionUpdated = "updated"
+	SynonymActionDeleted = "deleted"
+)
+
+// SynonymSet is a group of words searched as one, in the Solr format:
+// "cafe, cà phê, coffee shop" for equivalent words or "coffee shop => cafe"
+// for a one way mapping. Every set is a rule of the business synonyms.
+type SynonymSet struct {
+	ID       uuid.UUID `gorm:"primary_key;type:uuid;default:uuid_generate_v4()" json:"id"`
+	Name     string    `gorm:"column:name;unique;not null" json:"name"`
+	Synonyms string    `gorm:"column:synonyms;type:text;not null" json:"synonyms"`
+	// Version is the latest SynonymSetVersion of the set
+	Version  int       `gorm:"column:version;not null" json:"version"`
+	CreateAt time.Time `gorm:"column:created_at;default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdateAt time.Time `gorm:"column:updated_at;default:CURRENT_TIMESTAMP" json:"updated_at"`
+}
+
+// SynonymSetVersion keeps a synonym set as it was after each change,
+// deletions included, for auditing
+type SynonymSetVersion struct {
+	ID       uuid.UUID  `gorm:"primary_key;type:uuid;default:uuid_generate_v4()" json:"id"`
+	SetID    uuid.UUID  `gorm:"column:set_id;type:uuid;not null;uniqueIndex:idx_synonym_set_version,priority:1" json:"set_id"`
+	Version  int        `gorm:"column:version;not null;uniqueIndex:idx_synonym_set_version,priority:2" json:"version"`
+	Action   string     `gorm:"column:action;not null" json:"action"`
+	Name     string     `gorm:"column:name;not null" json:"name"`
+	Synonyms string     `gorm:"column:synonyms;type:text;not null" json:"synonyms"`
+	UserID   *uuid.UUID `gorm:"column:user_id;type:uuid" json:"user_id"`
+	CreateAt time.Time  `gorm:"column:created_at;default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+type SynonymSetRequest struct {
+	Name     string `json:"name" binding:"required" example:"cafe"`
+	Synonyms string `json:"synonyms" binding:"required" example:"cafe, cà phê, coffee shop"`
+}
+
+type GetListSynonymSetRequest struct {
+	Keyword  string `json:"keyword" form:"keyword"`
+	Page     int    `json:"page" form:"page"`
+	PageSize int    `json:"page_size" form:"page_size"`
+}
+
+type GetListSynonymSetResponse struct {
+	Data []SynonymSet           `json:"data"`
+	Meta map[string]interface{} `json:"meta"`
+}
@@ -46,6 +46,7 @@ type PGInterface interface {
 	GetListBusiness_v2(ctx context.Context, req *model.GetListBusinessRequest, tx *gorm.DB) (rs model.GetListBusinessResponse, err error)
 	GetOneBusiness(ctx context.Context, businessID uuid.UUID, tx *gorm.DB) (rs *model.Business, err error)
 	GetOneBusiness_v2(ctx context.Context, businessId uuid.UUID, tx *gorm.DB) (rs *model.Business, err error)
+	GetBusinessPageAfter(ctx context.Context, afterID uuid.UUID, limit int, tx *gorm.DB) ([]model.Business, error)
 	UpdateBusiness(ctx context.Context, business *model.Business, tx *gorm.DB) error
 	DeleteBusiness(ctx context.Context, business *model.Business, tx *gorm.DB) error
 
@@ -57,6 +58,56 @@ type PGInterface interface {
 	UpdateStaff(ctx context.Context, staff *model.Staff, tx *gorm.DB) error
 	DeleteStaff(ctx context.Context, staff *model.Staff, tx *gorm.DB) error
 	GetStaffByBusinessID(ctx context.Context, businessID uuid.UUID, tx *gorm.DB) (model.GetListStaffResponse, error)
+	GetStaffPageAfter(ctx context.Context, afterID uuid.UUID, limit int, tx *gorm.DB) ([]model.Staff, error)
+
+	// Outbox methods
+	CreateOutboxEvent(ctx context.Context, event *model.OutboxEvent, tx *gorm.DB) error
+	LockDueOutboxEvents(ctx context.Context, limit int, tx *gorm.DB) ([]model.OutboxEvent, error)
+	LeaseOutboxEvents(ctx context.Context, ids []uuid.UUID, until time.Time, tx *gorm.DB) error
+	UpdateOutboxEvent(ctx context.Context, event *model.OutboxEvent, tx *gorm.DB) error
+	GetOneOutboxEvent(ctx context.Context, eventID uuid.UUID, tx *gorm.DB) (*model.OutboxEvent, error)
+	GetListOutboxEvent(ctx context.Context, req *model.GetListOutboxEventRequest, tx *gorm.DB) (model.GetListOutboxEventResponse, error)
+	GetOutboxEventsSince(ctx context.Context, aggregates []string, since time.Time, tx *gorm.DB) ([]model.OutboxEvent, error)
+
+	// Search log methods
+	CreateSearchLogs(ctx context.Context, logs []model.SearchLog, tx *gorm.DB) error
+	GetTopSearchQueries(ctx context.Context, rg *model.SearchLogRange, tx *gorm.DB) ([]model.SearchQueryStat, error)
+	GetZeroResultSearchQueries(ctx context.Context, rg *model.SearchLogRange, tx *gorm.DB) ([]model.SearchQueryStat, error)
+	GetSlowestSearchQueries(ctx context.Context, rg *model.SearchLogRange, tx *gorm.DB) ([]model.SearchQueryStat, error)
+	GetDailySearchVolume(ctx context.Context, rg *model.SearchLogRange, tx *gorm.DB) ([]model.DailySearchVolume, error)
+
+	// Synonym methods
+	CreateSynonymSet(ctx context.Context, set *model.SynonymSet, tx *gorm.DB) error
+	GetOneSynonymSet(ctx context.Context, setID uuid.UUID, tx *gorm.DB) (*model.SynonymSet, error)
+	LockSynonymSet(ctx context.Context, setID uuid.UUID, tx *gorm.DB) (*model.SynonymSet, error)
+	CountSynonymSetByName(ctx context.Context, name string, excludeID uuid.UUID, tx *gorm.DB) (int64, error)
+	GetListSynonymSet(ctx context.Context, req *model.GetListSynonymSetRequest, tx *gorm.DB) (model.GetListSynonymSetResponse, error)
+	GetAllSynonymSets(ctx context.Context, tx *gorm.DB) ([]model.SynonymSet, error)
+	UpdateSynonymSet(ctx context.Context, set *model.SynonymSet, tx *gorm.DB) error
+	DeleteSynonymSet(ctx context.Context, set *model.SynonymSet, tx *gorm.DB) error
+	CreateSynonymSetVersion(ctx context.Context, version *model.SynonymSetVersion, tx *gorm.DB) error
+	GetListSynonymSetVersion(ctx context.Context, setID uuid.UUID, tx *gorm.DB) ([]model.SynonymSetVersion, error)
+
+	// Ranking profile methods
+	CreateRankingProfile(ctx context.Context, profile *model.RankingProfile, tx *gorm.DB) error
+	GetOneRankingProfile(ctx context.Context, profileID uuid.UUID, tx *gorm.DB) (*model.RankingProfile, error)
+	GetRankingProfileByName(ctx context.Context, name string, tx *gorm.DB) (*model.RankingProfile, error)
+	CountRankingProfileByName(ctx context.Context, name string, excludeID uuid.UUID, tx *gorm.DB) (int64, error)
+	GetAllRankingProfiles(ctx context.Context, tx *gorm.DB) ([]model.RankingProfile, error)
+	UpdateRankingProfile(ctx context.Context, profile *model.RankingProfile, tx *gorm.DB) error
+	DeleteRankingProfile(ctx context.Context, profile *model.RankingProfile, tx *gorm.DB) error
+
+	// Search template methods
+	CreateSearchTemplate(ctx context.Context, template *model.SearchTemplate, tx *gorm.DB) error
+	GetOneSearchTemplate(ctx context.Context, templateID uuid.UUID, tx *gorm.DB) (*model.SearchTemplate, error)
+	LockSearchTemplate(ctx context.Context, templateID uuid.UUID, tx *gorm.DB) (*model.SearchTemplate, error)
+	CountSearchTemplateByName(ctx context.Context, name string, excludeID uuid.UUID, tx *gorm.DB) (int64, error)
+	GetListSearchTemplate(ctx context.Context, req *model.GetListSearchTemplateRequest, tx *gorm.DB) (model.GetListSearchTemplateResponse, error)
+	UpdateSearchTemplate(ctx context.Context, template *model.SearchTemplate, tx *gorm.DB) error
+	DeleteSearchTemplate(ctx context.Context, template *model.SearchTemplate, tx *gorm.DB) error
+	CreateSearchTemplateVersion(ctx context.Context, version *model.SearchTemplateVersion, tx *gorm.DB) error
+	GetSearchTemplateVersion(ctx context.Context, templateID uuid.UUID, version int, tx *gorm.DB) (*model.SearchTemplateVersion, error)
+	GetListSearchTemplateVersion(ctx context.Context, templateID uuid.UUID, tx *gorm.DB) ([]model.SearchTemplateVersion, error)
 }
 
 type RepoPG struct {
@@ -92,6 +92,23 @@ func (r *RepoPG) GetOneBusiness_v2(ctx context.Context, businessId uuid.UUID, tx
 	return rs, nil
 }
 
+// GetBusinessPageAfter returns up to limit businesses with their staffs whose
+// id is greater than afterID, in id order. Keyset paging keeps long scans
+// cheap where an offset would get slower page after page.
+func (r *RepoPG) GetBusinessPageAfter(ctx context.Context, afterID uuid.UUID, limit int, tx *gorm.DB) ([]model.Business, error) {
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+
+	var rs []model.Business
+	if err := tx.Where("id > ?", afterID).Order("id asc").Limit(limit).Preload("Staffs").Find(&rs).Error; err != nil {
+		return nil, err
+	}
+	return rs, nil
+}
+
 func (r RepoPG) UpdateBusiness(ctx context.Context, business *model.Business, tx *gorm.DB) error {
 	log := logger.WithCtx(ctx, "RepoPG.UpdateBusiness")
 
@@ -0,0 +1,125 @@
+package repo
+
+import (
+	"business/pkg/model"
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func (r *RepoPG) CreateOutboxEvent(ctx context.Context, event *model.OutboxEvent, tx *gorm.DB) error {
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+
+	if event.Status == "" {
+		event.Status = model.OutboxStatusPending
+	}
+	if event.NextAttemptAt.IsZero() {
+		event.NextAttemptAt = time.Now()
+	}
+	return tx.Create(event).Error
+}
+
+// LockDueOutboxEvents locks pending events whose next attempt is due, oldest
+// first. Rows locked by another relay are skipped, so it has to run inside
+// a transaction for the locks to mean anything.
+func (r *RepoPG) LockDueOutboxEvents(ctx context.Context, limit int, tx *gorm.DB) ([]model.OutboxEvent, error) {
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+
+	var events []model.OutboxEvent
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Where("status = ? AND next_attempt_at <= ?", model.OutboxStatusPending, time.Now()).
+		Order("next_attempt_at asc").Limit(limit).Find(&events).Error
+	return events, err
+}
+
+// LeaseOutboxEvents pushes the next attempt of events back to until, other
+// relays leave them alone while they are delivered
+func (r *RepoPG) LeaseOutboxEvents(ctx context.Context, ids []uuid.UUID, until time.Time, tx *gorm.DB) error {
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+
+	return tx.Model(&model.OutboxEvent{}).Where("id IN ?", ids).Update("next_attempt_at", until).Error
+}
+
+func (r *RepoPG) UpdateOutboxEvent(ctx context.Context, event *model.OutboxEvent, tx *gorm.DB) error {
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+
+	return tx.Save(event).Error
+}
+
+func (r *RepoPG) GetOneOutboxEvent(ctx context.Context, eventID uuid.UUID, tx *gorm.DB) (*model.OutboxEvent, error) {
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+
+	var event model.OutboxEvent
+	if err := tx.First(&event, eventID).Error; err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+func (r *RepoPG) GetListOutboxEvent(ctx context.Context, req *model.GetListOutboxEventRequest, tx *gorm.DB) (rs model.GetListOutboxEventResponse, err error) {
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+
+	page := r.GetPage(req.Page)
+	pageSize := r.GetPageSize(req.PageSize)
+
+	tx = tx.WithContext(ctx).Model(&model.OutboxEvent{})
+	if req.Status != nil {
+		tx = tx.Where("status = ?", req.Status)
+	}
+	if req.Aggregate != nil {
+		tx = tx.Where("aggregate = ?", req.Aggregate)
+	}
+
+	var total int64
+	if err := tx.Count(&total).Limit(pageSize).Offset(r.GetOffset(page, pageSize)).
+		Order("created_at desc").Find(&rs.Data).Error; err != nil {
+		return rs, err
+	}
+
+	if rs.Meta, err = r.GetPaginationInfo("", tx, int(total), page, pageSize); err != nil {
+		return rs, err
+	}
+	return rs, nil
+}
+
+// GetOutboxEventsSince lists the events of the aggregates recorded at or
+// after since, whatever their status, oldest first
+func (r *RepoPG) GetOutboxEventsSince(ctx context.Context, aggregates []string, since time.Time, tx *gorm.DB) ([]model.OutboxEvent, error) {
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+
+	var events []model.OutboxEvent
+	err := tx.Where("aggregate IN ? AND created_at >= ?", aggregates, since).
+		Order("created_at asc").Find(&events).Error
+	return events, err
+}
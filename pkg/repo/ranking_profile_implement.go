@@ -0,0 +1,93 @@
+package repo
+
+import (
+	"business/pkg/model"
+	"context"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+func (r *RepoPG) CreateRankingProfile(ctx context.Context, profile *model.RankingProfile, tx *gorm.DB) error {
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+
+	return tx.Create(profile).Error
+}
+
+func (r *RepoPG) GetOneRankingProfile(ctx context.Context, profileID uuid.UUID, tx *gorm.DB) (*model.RankingProfile, error) {
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+
+	var profile model.RankingProfile
+	if err := tx.First(&profile, profileID).Error; err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
+
+func (r *RepoPG) GetRankingProfileByName(ctx context.Context, name string, tx *gorm.DB) (*model.RankingProfile, error) {
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+
+	var profile model.RankingProfile
+	if err := tx.Where("name = ?", name).First(&profile).Error; err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
+
+// CountRankingProfileByName counts the profiles other than excludeID named
+// name, profile names are unique
+func (r *RepoPG) CountRankingProfileByName(ctx context.Context, name string, excludeID uuid.UUID, tx *gorm.DB) (int64, error) {
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+
+	var count int64
+	err := tx.Model(&model.RankingProfile{}).Where("name = ? AND id <> ?", name, excludeID).Count(&count).Error
+	return count, err
+}
+
+func (r *RepoPG) GetAllRankingProfiles(ctx context.Context, tx *gorm.DB) ([]model.RankingProfile, error) {
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+
+	var profiles []model.RankingProfile
+	err := tx.Order("name asc").Find(&profiles).Error
+	return profiles, err
+}
+
+func (r *RepoPG) UpdateRankingProfile(ctx context.Context, profile *model.RankingProfile, tx *gorm.DB) error {
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+
+	return tx.Save(profile).Error
+}
+
+func (r *RepoPG) DeleteRankingProfile(ctx context.Context, profile *model.RankingProfile, tx *gorm.DB) error {
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+
+	return tx.Delete(profile).Error
+}
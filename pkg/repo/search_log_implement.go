@@ -0,0 +1,98 @@
+package repo
+
+import (
+	"business/pkg/model"
+	"context"
+
+	"gorm.io/gorm"
+)
+
+const searchQueryStatColumns = `query,
+	count(*) AS searches,
+	count(*) FILTER (WHERE hits = 0) AS zero_results,
+	avg(hits) AS avg_hits,
+	avg(latency_ms) AS avg_latency_ms,
+	max(latency_ms) AS max_latency_ms,
+	max(created_at) AS last_searched_at`
+
+func (r *RepoPG) CreateSearchLogs(ctx context.Context, logs []model.SearchLog, tx *gorm.DB) error {
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+
+	return tx.CreateInBatches(logs, len(logs)).Error
+}
+
+// searchLogsIn scopes search logs to a range and an index. The query reports
+// leave out searches made of filters only, they have no text to group by.
+func searchLogsIn(tx *gorm.DB, rg *model.SearchLogRange) *gorm.DB {
+	tx = tx.Model(&model.SearchLog{}).Where("created_at >= ? AND created_at < ?", rg.From, rg.To)
+	if rg.Index != nil {
+		tx = tx.Where("index_name = ?", *rg.Index)
+	}
+	return tx
+}
+
+// GetTopSearchQueries returns the most searched queries first
+func (r *RepoPG) GetTopSearchQueries(ctx context.Context, rg *model.SearchLogRange, tx *gorm.DB) ([]model.SearchQueryStat, error) {
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+
+	var rs []model.SearchQueryStat
+	err := searchLogsIn(tx, rg).Where("query <> ''").Select(searchQueryStatColumns).
+		Group("query").Order("searches desc, query").Limit(rg.Limit).Scan(&rs).Error
+	return rs, err
+}
+
+// GetZeroResultSearchQueries returns the queries that found nothing, most
+// searched first
+func (r *RepoPG) GetZeroResultSearchQueries(ctx context.Context, rg *model.SearchLogRange, tx *gorm.DB) ([]model.SearchQueryStat, error) {
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+
+	var rs []model.SearchQueryStat
+	err := searchLogsIn(tx, rg).Where("query <> '' AND hits = 0").Select(searchQueryStatColumns).
+		Group("query").Order("searches desc, query").Limit(rg.Limit).Scan(&rs).Error
+	return rs, err
+}
+
+// GetSlowestSearchQueries returns the queries with the highest average latency first
+func (r *RepoPG) GetSlowestSearchQueries(ctx context.Context, rg *model.SearchLogRange, tx *gorm.DB) ([]model.SearchQueryStat, error) {
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+
+	var rs []model.SearchQueryStat
+	err := searchLogsIn(tx, rg).Where("query <> ''").Select(searchQueryStatColumns).
+		Group("query").Order("avg_latency_ms desc, query").Limit(rg.Limit).Scan(&rs).Error
+	return rs, err
+}
+
+// GetDailySearchVolume counts every search of the range per day, oldest day first
+func (r *RepoPG) GetDailySearchVolume(ctx context.Context, rg *model.SearchLogRange, tx *gorm.DB) ([]model.DailySearchVolume, error) {
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+
+	var rs []model.DailySearchVolume
+	// days start at midnight in the zone of the range, not of the session
+	err := searchLogsIn(tx, rg).Select(`date_trunc('day', created_at AT TIME ZONE ?) AT TIME ZONE ? AS day,
+	count(*) AS searches,
+	count(*) FILTER (WHERE hits = 0) AS zero_results,
+	count(DISTINCT user_id) AS users,
+	avg(latency_ms) AS avg_latency_ms`, rg.TimeZone, rg.TimeZone).
+		Group("day").Order("day").Scan(&rs).Error
+	return rs, err
+}
@@ -0,0 +1,149 @@
+package repo
+
+import (
+	"business/pkg/model"
+	"context"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func (r *RepoPG) CreateSearchTemplate(ctx context.Context, template *model.SearchTemplate, tx *gorm.DB) error {
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+
+	return tx.Create(template).Error
+}
+
+func (r *RepoPG) GetOneSearchTemplate(ctx context.Context, templateID uuid.UUID, tx *gorm.DB) (*model.SearchTemplate, error) {
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+
+	var template model.SearchTemplate
+	if err := tx.First(&template, templateID).Error; err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
+
+// LockSearchTemplate reads a template for update, so concurrent changes get
+// consecutive versions. It has to run inside a transaction.
+func (r *RepoPG) LockSearchTemplate(ctx context.Context, templateID uuid.UUID, tx *gorm.DB) (*model.SearchTemplate, error) {
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+
+	var template model.SearchTemplate
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&template, templateID).Error; err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
+
+// CountSearchTemplateByName counts the templates other than excludeID named
+// name, template names are unique
+func (r *RepoPG) CountSearchTemplateByName(ctx context.Context, name string, excludeID uuid.UUID, tx *gorm.DB) (int64, error) {
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+
+	var count int64
+	err := tx.Model(&model.SearchTemplate{}).Where("name = ? AND id <> ?", name, excludeID).Count(&count).Error
+	return count, err
+}
+
+func (r *RepoPG) GetListSearchTemplate(ctx context.Context, req *model.GetListSearchTemplateRequest, tx *gorm.DB) (rs model.GetListSearchTemplateResponse, err error) {
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+
+	page := r.GetPage(req.Page)
+	pageSize := r.GetPageSize(req.PageSize)
+
+	tx = tx.WithContext(ctx).Model(&model.SearchTemplate{})
+	if req.Keyword != "" {
+		tx = tx.Where("name ILIKE ? OR description ILIKE ?", "%"+req.Keyword+"%", "%"+req.Keyword+"%")
+	}
+
+	var total int64
+	if err := tx.Count(&total).Limit(pageSize).Offset(r.GetOffset(page, pageSize)).
+		Order("name asc").Find(&rs.Data).Error; err != nil {
+		return rs, err
+	}
+
+	if rs.Meta, err = r.GetPaginationInfo("", tx, int(total), page, pageSize); err != nil {
+		return rs, err
+	}
+	return rs, nil
+}
+
+func (r *RepoPG) UpdateSearchTemplate(ctx context.Context, template *model.SearchTemplate, tx *gorm.DB) error {
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+
+	return tx.Save(template).Error
+}
+
+func (r *RepoPG) DeleteSearchTemplate(ctx context.Context, template *model.SearchTemplate, tx *gorm.DB) error {
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+
+	return tx.Delete(template).Error
+}
+
+func (r *RepoPG) CreateSearchTemplateVersion(ctx context.Context, version *model.SearchTemplateVersion, tx *gorm.DB) error {
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+
+	return tx.Create(version).Error
+}
+
+func (r *RepoPG) GetSearchTemplateVersion(ctx context.Context, templateID uuid.UUID, version int, tx *gorm.DB) (*model.SearchTemplateVersion, error) {
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+
+	var v model.SearchTemplateVersion
+	if err := tx.Where("template_id = ? AND version = ?", templateID, version).First(&v).Error; err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// GetListSearchTemplateVersion returns the versions of a template, latest
+// first. They outlive the template, a deleted template keeps its history.
+func (r *RepoPG) GetListSearchTemplateVersion(ctx context.Context, templateID uuid.UUID, tx *gorm.DB) ([]model.SearchTemplateVersion, error) {
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+
+	var versions []model.SearchTemplateVersion
+	err := tx.Where("template_id = ?", templateID).Order("version desc").Find(&versions).Error
+	return versions, err
+}
@@ -173,3 +173,18 @@ func (r *RepoPG) GetStaffByBusinessID(ctx context.Context, businessID uuid.UUID,
 
 	return rs, nil
 }
+
+// GetStaffPageAfter returns up to limit staffs whose id is greater than afterID, in id order
+func (r *RepoPG) GetStaffPageAfter(ctx context.Context, afterID uuid.UUID, limit int, tx *gorm.DB) ([]model.Staff, error) {
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+
+	var rs []model.Staff
+	if err := tx.Where("id > ?", afterID).Order("id asc").Limit(limit).Find(&rs).Error; err != nil {
+		return nil, err
+	}
+	return rs, nil
+}
@@ -0,0 +1,148 @@
+package repo
+
+import (
+	"business/pkg/model"
+	"context"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func (r *RepoPG) CreateSynonymSet(ctx context.Context, set *model.SynonymSet, tx *gorm.DB) error {
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+
+	return tx.Create(set).Error
+}
+
+func (r *RepoPG) GetOneSynonymSet(ctx context.Context, setID uuid.UUID, tx *gorm.DB) (*model.SynonymSet, error) {
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+
+	var set model.SynonymSet
+	if err := tx.First(&set, setID).Error; err != nil {
+		return nil, err
+	}
+	return &set, nil
+}
+
+// LockSynonymSet reads a set for update, so concurrent changes get
+// consecutive versions. It has to run inside a transaction.
+func (r *RepoPG) LockSynonymSet(ctx context.Context, setID uuid.UUID, tx *gorm.DB) (*model.SynonymSet, error) {
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+
+	var set model.SynonymSet
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&set, setID).Error; err != nil {
+		return nil, err
+	}
+	return &set, nil
+}
+
+// CountSynonymSetByName counts the sets other than excludeID named name, set
+// names are unique
+func (r *RepoPG) CountSynonymSetByName(ctx context.Context, name string, excludeID uuid.UUID, tx *gorm.DB) (int64, error) {
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+
+	var count int64
+	err := tx.Model(&model.SynonymSet{}).Where("name = ? AND id <> ?", name, excludeID).Count(&count).Error
+	return count, err
+}
+
+func (r *RepoPG) GetListSynonymSet(ctx context.Context, req *model.GetListSynonymSetRequest, tx *gorm.DB) (rs model.GetListSynonymSetResponse, err error) {
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+
+	page := r.GetPage(req.Page)
+	pageSize := r.GetPageSize(req.PageSize)
+
+	tx = tx.WithContext(ctx).Model(&model.SynonymSet{})
+	if req.Keyword != "" {
+		tx = tx.Where("name ILIKE ? OR synonyms ILIKE ?", "%"+req.Keyword+"%", "%"+req.Keyword+"%")
+	}
+
+	var total int64
+	if err := tx.Count(&total).Limit(pageSize).Offset(r.GetOffset(page, pageSize)).
+		Order("name asc").Find(&rs.Data).Error; err != nil {
+		return rs, err
+	}
+
+	if rs.Meta, err = r.GetPaginationInfo("", tx, int(total), page, pageSize); err != nil {
+		return rs, err
+	}
+	return rs, nil
+}
+
+// GetAllSynonymSets returns every set, in a stable order for the synonyms set pushed to Elasticsearch
+func (r *RepoPG) GetAllSynonymSets(ctx context.Context, tx *gorm.DB) ([]model.SynonymSet, error) {
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+
+	var sets []model.SynonymSet
+	err := tx.Order("id asc").Find(&sets).Error
+	return sets, err
+}
+
+func (r *RepoPG) UpdateSynonymSet(ctx context.Context, set *model.SynonymSet, tx *gorm.DB) error {
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+
+	return tx.Save(set).Error
+}
+
+func (r *RepoPG) DeleteSynonymSet(ctx context.Context, set *model.SynonymSet, tx *gorm.DB) error {
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+
+	return tx.Delete(set).Error
+}
+
+func (r *RepoPG) CreateSynonymSetVersion(ctx context.Context, version *model.SynonymSetVersion, tx *gorm.DB) error {
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+
+	return tx.Create(version).Error
+}
+
+// GetListSynonymSetVersion returns the versions of a set, latest first. They
+// outlive the set, a deleted set keeps its history.
+func (r *RepoPG) GetListSynonymSetVersion(ctx context.Context, setID uuid.UUID, tx *gorm.DB) ([]model.SynonymSetVersion, error) {
+	var cancel context.CancelFunc
+	if tx == nil {
+		tx, cancel = r.DBWithTimeout(ctx)
+		defer cancel()
+	}
+
+	var versions []model.SynonymSetVersion
+	err := tx.Where("set_id = ?", setID).Order("version desc").Find(&versions).Error
+	return versions, err
+}
@@ -0,0 +1,109 @@
+package route
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+)
+
+const commandUsage = `usage:
+  es-mapping check                report how live indices differ from their definitions
+  es-mapping migrate [-reindex]   create missing indices, put additive changes and
+                                  with -reindex rebuild indices with breaking changes
+  es-reconcile [-repair]          compare postgres with the business index and with
+                                  -repair index missing and stale documents and
+                                  delete orphaned ones`
+
+// RunCommand runs a maintenance command instead of the http server, the
+// report is written to stdout as JSON
+func (s *Service) RunCommand(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return errors.New(commandUsage)
+	}
+
+	switch args[0] {
+	case "es-mapping":
+		return s.runMappingCommand(ctx, args[1:])
+	case "es-reconcile":
+		return s.runReconcileCommand(ctx, args[1:])
+	}
+	return errors.New(commandUsage)
+}
+
+func (s *Service) runMappingCommand(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return errors.New(commandUsage)
+	}
+
+	switch args[0] {
+	case "check":
+		plans, err := s.esService.MappingDrift(ctx)
+		if err != nil {
+			return err
+		}
+		if err := printJSON(plans); err != nil {
+			return err
+		}
+		for _, plan := range plans {
+			if !plan.UpToDate() {
+				return fmt.Errorf("index %s doesn't match its definition", plan.Index)
+			}
+		}
+		return nil
+
+	case "migrate":
+		flags := flag.NewFlagSet("es-mapping migrate", flag.ContinueOnError)
+		reindex := flags.Bool("reindex", false, "reindex indices with breaking changes")
+		if err := flags.Parse(args[1:]); err != nil {
+			return err
+		}
+		report, err := s.esService.MigrateMappings(ctx, *reindex)
+		if printErr := printJSON(report); printErr != nil && err == nil {
+			err = printErr
+		}
+		if err != nil {
+			return err
+		}
+		if len(report.Pending) > 0 {
+			return fmt.Errorf("breaking changes left in %v, run again with -reindex", report.Pending)
+		}
+		return nil
+	}
+
+	return errors.New(commandUsage)
+}
+
+// runReconcileCommand fails when differences are left, after a repair only
+// documents that couldn't be indexed count
+func (s *Service) runReconcileCommand(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("es-reconcile", flag.ContinueOnError)
+	repair := flags.Bool("repair", false, "index missing and stale documents, delete orphaned ones")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	report, err := s.esService.Reconcile(ctx, *repair)
+	if err != nil {
+		return err
+	}
+	if err := printJSON(report); err != nil {
+		return err
+	}
+	switch {
+	case *repair && len(report.Failures) > 0:
+		return fmt.Errorf("%d documents could not be repaired", len(report.Failures))
+	case !*repair && report.MissingCount+report.OrphanedCount+report.StaleCount > 0:
+		return fmt.Errorf("%d missing, %d orphaned and %d stale documents, run again with -repair",
+			report.MissingCount, report.OrphanedCount, report.StaleCount)
+	}
+	return nil
+}
+
+func printJSON(v interface{}) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
@@ -0,0 +1,103 @@
+package route
+
+import (
+	"business/conf"
+	"business/pkg/es"
+	"business/pkg/service"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"gitlab.com/goxp/cloud0/logger"
+)
+
+// ES_MAPPING_CHECK values
+const (
+	mappingCheckFail = "fail"
+	mappingCheckWarn = "warn"
+	mappingCheckOff  = "off"
+
+	mappingCheckTimeout = 10 * time.Second
+)
+
+// elasticConfig builds the es.Config from the app environment, es.NewClient validates it
+func elasticConfig(cfg conf.AppConfig) (es.Config, error) {
+	password, err := secret(cfg.ESPassword, cfg.ESPasswordFile)
+	if err != nil {
+		return es.Config{}, err
+	}
+	apiKey, err := secret(cfg.ESAPIKey, cfg.ESAPIKeyFile)
+	if err != nil {
+		return es.Config{}, err
+	}
+	serviceToken, err := secret(cfg.ESServiceToken, cfg.ESServiceTokenFile)
+	if err != nil {
+		return es.Config{}, err
+	}
+
+	addresses := make([]string, 0, len(cfg.ESAddresses))
+	for _, address := range cfg.ESAddresses {
+		if address = strings.TrimSpace(address); address != "" {
+			addresses = append(addresses, address)
+		}
+	}
+
+	// es.Config reads zero as the default, here it means no retries
+	maxRetries := cfg.ESMaxRetries
+	if maxRetries == 0 {
+		maxRetries = -1
+	}
+
+	return es.Config{
+		Addresses:              addresses,
+		Username:               cfg.ESUsername,
+		Password:               password,
+		APIKey:                 apiKey,
+		ServiceToken:           serviceToken,
+		CACertPath:             cfg.ESCACert,
+		ClientCertPath:         cfg.ESClientCert,
+		ClientKeyPath:          cfg.ESClientKey,
+		CertificateFingerprint: cfg.ESCertFingerprint,
+		DiscoverNodesOnStart:   cfg.ESSniffOnStart,
+		DiscoverNodesInterval:  cfg.ESSniffInterval,
+		IndexPrefix:            cfg.ESIndexPrefix,
+		MaxRetries:             maxRetries,
+	}, nil
+}
+
+// secret returns the content of file when it is set, value otherwise
+func secret(value, file string) (string, error) {
+	if file == "" {
+		return value, nil
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret file %s: %w", file, err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// checkMappings returns an error when a live index drifted from its
+// definition and mode is fail. An unreachable cluster only logs, the search
+// endpoints report it per request.
+func checkMappings(esService *service.EsService, mode string) error {
+	if mode == mappingCheckOff {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), mappingCheckTimeout)
+	defer cancel()
+
+	err := esService.CheckMappings(ctx)
+	if err == nil {
+		return nil
+	}
+	var drift *es.MappingDriftError
+	if errors.As(err, &drift) && mode == mappingCheckFail {
+		return err
+	}
+	logger.Tag("checkMappings").WithError(err).Warn("elasticsearch mapping check failed")
+	return nil
+}
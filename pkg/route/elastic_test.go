@@ -0,0 +1,32 @@
+package route
+
+import (
+	"business/conf"
+	"testing"
+)
+
+func TestElasticConfigMaxRetries(t *testing.T) {
+	tests := []struct {
+		name       string
+		maxRetries int
+		want       int
+	}{
+		{name: "zero turns retries off", maxRetries: 0, want: -1},
+		{name: "kept", maxRetries: 3, want: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := elasticConfig(conf.AppConfig{
+				ESAddresses:  []string{"http://localhost:9200"},
+				ESMaxRetries: tt.maxRetries,
+			})
+			if err != nil {
+				t.Fatalf("elasticConfig: %v", err)
+			}
+			if cfg.MaxRetries != tt.want {
+				t.Errorf("MaxRetries = %d, want %d", cfg.MaxRetries, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,24 @@
+package route
+
+import (
+	"business/conf"
+	"business/pkg/service"
+)
+
+func outboxConfig(cfg conf.AppConfig) service.OutboxConfig {
+	return service.OutboxConfig{
+		PollInterval: cfg.OutboxPollInterval,
+		BatchSize:    cfg.OutboxBatchSize,
+		MaxAttempts:  cfg.OutboxMaxAttempts,
+	}
+}
+
+// outboxSinks are the consumers of outbox events, the business index always
+// and an http consumer when one is configured
+func outboxSinks(cfg conf.AppConfig, esService *service.EsService) []service.OutboxSink {
+	sinks := []service.OutboxSink{service.NewEsSync(esService)}
+	if cfg.OutboxHTTPURL != "" {
+		sinks = append(sinks, service.NewHTTPSink(cfg.OutboxHTTPURL, cfg.OutboxHTTPTimeout))
+	}
+	return sinks
+}
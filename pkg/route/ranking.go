@@ -0,0 +1,29 @@
+package route
+
+import (
+	"business/conf"
+	"business/pkg/model"
+	"business/pkg/service"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// rankingConfig reads the ranking profiles of RANKING_PROFILES_FILE,
+// service.NewRankingService validates them
+func rankingConfig(cfg conf.AppConfig) (service.RankingConfig, error) {
+	rc := service.RankingConfig{Default: cfg.RankingDefaultProfile}
+	if cfg.RankingProfilesFile == "" {
+		return rc, nil
+	}
+	data, err := os.ReadFile(cfg.RankingProfilesFile)
+	if err != nil {
+		return rc, fmt.Errorf("failed to read ranking profiles file %s: %w", cfg.RankingProfilesFile, err)
+	}
+	var profiles []model.RankingProfileRequest
+	if err := json.Unmarshal(data, &profiles); err != nil {
+		return rc, fmt.Errorf("failed to decode ranking profiles file %s: %w", cfg.RankingProfilesFile, err)
+	}
+	rc.Profiles = profiles
+	return rc, nil
+}
@@ -1,11 +1,13 @@
 package route
 
 import (
+	"business/conf"
 	"business/pkg/handlers"
 	"business/pkg/middleware"
 	"business/pkg/repo"
 	"business/pkg/es"
 	service2 "business/pkg/service"
+	"context"
 
 	"github.com/caarlos0/env/v6"
 	swaggerFiles "github.com/swaggo/files"
@@ -21,13 +23,16 @@ type extraSetting struct {
 
 type Service struct {
 	*service.BaseApp
-	setting *extraSetting
+	setting   *extraSetting
+	esService *service2.EsService
+	outbox    *service2.OutboxRelay
+	analytics *service2.SearchAnalytics
 }
 
 func NewService() *Service {
 	s := &Service{
-		service.NewApp("MVT Adapter", "v1.0"),
-		&extraSetting{},
+		BaseApp: service.NewApp("MVT Adapter", "v1.0"),
+		setting: &extraSetting{},
 	}
 
 	// repo
@@ -37,23 +42,44 @@ func NewService() *Service {
 		db = db.Debug()
 	}
 	repoPG := repo.NewPGRepo(db)
-	esConfig := es.Config{
-		Addresses: []string{"http://localhost:9200"},
-		Username: "elastic",
-		Password: "VAMQ+bNBKdC5bu3Ee-1y",
+	esConfig, err := elasticConfig(conf.LoadEnv())
+	if err != nil {
+		panic(err)
 	}
 	client, err := es.NewClient(esConfig)
 	if err != nil {
 		panic(err)
 	}
+	rankingCfg, err := rankingConfig(conf.LoadEnv())
+	if err != nil {
+		panic(err)
+	}
 	// service
-	businessService := service2.NewBusinessService(repoPG)
-	staffService := service2.NewStaffService(repoPG)
-	esService := service2.NewEsService(repoPG, client)
+	rankingService, err := service2.NewRankingService(repoPG, rankingCfg)
+	if err != nil {
+		panic(err)
+	}
+	esService := service2.NewEsService(repoPG, client, rankingService)
+	s.esService = esService
+	s.outbox = service2.NewOutboxRelay(repoPG, outboxConfig(conf.LoadEnv()), outboxSinks(conf.LoadEnv(), esService)...)
+	businessService := service2.NewBusinessService(repoPG, s.outbox)
+	staffService := service2.NewStaffService(repoPG, s.outbox)
+	synonymService := service2.NewSynonymService(repoPG, s.outbox)
+	templateService := service2.NewSearchTemplateService(repoPG, client)
+	analyticsCfg, err := searchAnalyticsConfig(conf.LoadEnv())
+	if err != nil {
+		panic(err)
+	}
+	s.analytics = service2.NewSearchAnalytics(repoPG, analyticsCfg)
 	// handle
 	businessHandle := handlers.NewBusinessHandlers(businessService)
 	staffHandle := handlers.NewStaffHandler(staffService)
-	esHandle := handlers.NewElasticHandlers(esService)
+	esHandle := handlers.NewElasticHandlers(esService, s.analytics)
+	outboxHandle := handlers.NewOutboxHandlers(s.outbox)
+	analyticsHandle := handlers.NewSearchAnalyticsHandlers(s.analytics)
+	synonymHandle := handlers.NewSynonymHandlers(synonymService)
+	rankingHandle := handlers.NewRankingProfileHandlers(rankingService)
+	templateHandle := handlers.NewSearchTemplateHandlers(templateService)
 
 	// Áp dụng CORS middleware cho toàn bộ router
 	s.Router.Use(middleware.CORSMiddleware())
@@ -89,6 +115,46 @@ func NewService() *Service {
 	v1Api.POST("/elastic/push-to-elastic", ginext.WrapHandler(esHandle.PushToElastic))
 	v1Api.POST("/elastic/search-by-field", ginext.WrapHandler(esHandle.SearchByField))
 	v1Api.POST("/elastic/fulltext-search", ginext.WrapHandler(esHandle.FullTextSearch))
+	v1Api.GET("/elastic/suggest", ginext.WrapHandler(esHandle.SuggestBusiness))
+	v1Api.POST("/elastic/unified-search", ginext.WrapHandler(esHandle.UnifiedSearch))
+	v1Api.POST("/elastic/reindex", middleware.LoggingRequest(), ginext.WrapHandler(esHandle.ReindexBusiness)) // only admin portal
+	v1Api.GET("/elastic/mapping/drift", ginext.WrapHandler(esHandle.MappingDrift)) // only admin portal
+	v1Api.POST("/elastic/mapping/migrate", middleware.LoggingRequest(), ginext.WrapHandler(esHandle.MigrateMappings)) // only admin portal
+	v1Api.POST("/elastic/reconcile", middleware.LoggingRequest(), ginext.WrapHandler(esHandle.Reconcile)) // only admin portal
+	v1Api.POST("/elastic/staff/search", ginext.WrapHandler(esHandle.SearchStaff))
+	v1Api.POST("/elastic/staff/reindex", middleware.LoggingRequest(), ginext.WrapHandler(esHandle.ReindexStaff)) // only admin portal
+	v1Api.POST("/elastic/templates/:id/search", ginext.WrapHandler(templateHandle.RunSearchTemplate))
+	v1Api.POST("/elastic/synonyms/reload", middleware.LoggingRequest(), ginext.WrapHandler(esHandle.SyncSynonyms)) // only admin portal
+
+	v1Api.POST("/synonyms", middleware.LoggingRequest(), ginext.WrapHandler(synonymHandle.CreateSynonymSet)) // only admin portal
+	v1Api.GET("/synonyms", ginext.WrapHandler(synonymHandle.GetListSynonymSet)) // only admin portal
+	v1Api.GET("/synonyms/:id", ginext.WrapHandler(synonymHandle.GetOneSynonymSet)) // only admin portal
+	v1Api.PUT("/synonyms/:id", middleware.LoggingRequest(), ginext.WrapHandler(synonymHandle.UpdateSynonymSet)) // only admin portal
+	v1Api.DELETE("/synonyms/:id", middleware.LoggingRequest(), ginext.WrapHandler(synonymHandle.DeleteSynonymSet)) // only admin portal
+	v1Api.GET("/synonyms/:id/versions", ginext.WrapHandler(synonymHandle.GetListSynonymSetVersion)) // only admin portal
+
+	v1Api.POST("/ranking-profiles", middleware.LoggingRequest(), ginext.WrapHandler(rankingHandle.CreateRankingProfile)) // only admin portal
+	v1Api.GET("/ranking-profiles", ginext.WrapHandler(rankingHandle.GetListRankingProfile)) // only admin portal
+	v1Api.GET("/ranking-profiles/:id", ginext.WrapHandler(rankingHandle.GetOneRankingProfile)) // only admin portal
+	v1Api.PUT("/ranking-profiles/:id", middleware.LoggingRequest(), ginext.WrapHandler(rankingHandle.UpdateRankingProfile)) // only admin portal
+	v1Api.DELETE("/ranking-profiles/:id", middleware.LoggingRequest(), ginext.WrapHandler(rankingHandle.DeleteRankingProfile)) // only admin portal
+
+	v1Api.POST("/search-templates", middleware.LoggingRequest(), ginext.WrapHandler(templateHandle.CreateSearchTemplate)) // only admin portal
+	v1Api.GET("/search-templates", ginext.WrapHandler(templateHandle.GetListSearchTemplate)) // only admin portal
+	v1Api.GET("/search-templates/:id", ginext.WrapHandler(templateHandle.GetOneSearchTemplate)) // only admin portal
+	v1Api.PUT("/search-templates/:id", middleware.LoggingRequest(), ginext.WrapHandler(templateHandle.UpdateSearchTemplate)) // only admin portal
+	v1Api.DELETE("/search-templates/:id", middleware.LoggingRequest(), ginext.WrapHandler(templateHandle.DeleteSearchTemplate)) // only admin portal
+	v1Api.GET("/search-templates/:id/versions", ginext.WrapHandler(templateHandle.GetListSearchTemplateVersion)) // only admin portal
+	v1Api.POST("/search-templates/:id/render", ginext.WrapHandler(templateHandle.RenderSearchTemplate)) // only admin portal
+
+	v1Api.GET("/outbox/events", ginext.WrapHandler(outboxHandle.ListOutboxEvent)) // only admin portal
+	v1Api.GET("/outbox/events/:id", ginext.WrapHandler(outboxHandle.GetOneOutboxEvent)) // only admin portal
+	v1Api.POST("/outbox/events/:id/retry", middleware.LoggingRequest(), ginext.WrapHandler(outboxHandle.RetryOutboxEvent)) // only admin portal
+
+	v1Api.GET("/search-analytics/top-queries", ginext.WrapHandler(analyticsHandle.TopQueries)) // only admin portal
+	v1Api.GET("/search-analytics/zero-results", ginext.WrapHandler(analyticsHandle.ZeroResultQueries)) // only admin portal
+	v1Api.GET("/search-analytics/slowest-queries", ginext.WrapHandler(analyticsHandle.SlowestQueries)) // only admin portal
+	v1Api.GET("/search-analytics/daily-volume", ginext.WrapHandler(analyticsHandle.DailyVolume)) // only admin portal
 
 	
 	// Migrate
@@ -96,3 +162,19 @@ func NewService() *Service {
 	s.Router.POST("/internal/migrate", migrateHandler.Migrate)
 	return s
 }
+
+// Start runs the background workers next to the http server and stops them with it
+func (s *Service) Start(ctx context.Context) error {
+	// checked here rather than in NewService, so `es-mapping migrate` can
+	// still run against the drift that makes the server refuse to start
+	if err := checkMappings(s.esService, conf.LoadEnv().ESMappingCheck); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	go s.outbox.Run(ctx)
+	go s.analytics.Run(ctx)
+	return s.BaseApp.Start(ctx)
+}
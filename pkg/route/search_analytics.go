@@ -0,0 +1,24 @@
+package route
+
+import (
+	"business/conf"
+	"business/pkg/service"
+	"fmt"
+	"time"
+)
+
+// searchAnalyticsConfig loads the report time zone by its IANA name, which
+// Postgres is given to count days in the same zone
+func searchAnalyticsConfig(cfg conf.AppConfig) (service.SearchAnalyticsConfig, error) {
+	sc := service.SearchAnalyticsConfig{
+		Buffer:        cfg.SearchLogBuffer,
+		BatchSize:     cfg.SearchLogBatchSize,
+		FlushInterval: cfg.SearchLogFlushInterval,
+	}
+	loc, err := time.LoadLocation(cfg.SearchReportTimeZone)
+	if err != nil || loc == time.Local {
+		return sc, fmt.Errorf("SEARCH_REPORT_TIME_ZONE %q must be an IANA time zone name", cfg.SearchReportTimeZone)
+	}
+	sc.Location = loc
+	return sc, nil
+}
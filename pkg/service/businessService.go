@@ -18,11 +18,12 @@ import (
 )
 
 type BusinessService struct {
-	repo repo.PGInterface
+	repo   repo.PGInterface
+	outbox OutboxNotifier
 }
 
-func NewBusinessService(repo repo.PGInterface) BusinessInterface {
-	return &BusinessService{repo: repo}
+func NewBusinessService(repo repo.PGInterface, outbox OutboxNotifier) BusinessInterface {
+	return &BusinessService{repo: repo, outbox: outbox}
 }
 
 type BusinessInterface interface {
@@ -42,9 +43,17 @@ func (s *BusinessService) CreateBusiness(ctx context.Context, req model.Business
 
 	copier.Copy(Business, req)
 
-	if err := s.repo.CreateBusiness(ctx, Business, nil); err != nil {
+	err := s.repo.Transaction(ctx, func(rp repo.PGInterface) error {
+		if err := rp.CreateBusiness(ctx, Business, nil); err != nil {
+			return err
+		}
+		return recordOutboxEvent(ctx, rp, model.OutboxAggregateBusiness, Business.ID, model.OutboxEventCreated,
+			model.OutboxPayload{Business: Business})
+	})
+	if err != nil {
 		return nil, err
 	}
+	s.outbox.Notify()
 
 	return Business, nil
 }
@@ -53,6 +62,7 @@ func (s *BusinessService) CreateBusiness_v2(ctx context.Context) ([]model.Busine
 	log := logger.WithCtx(ctx, "BusinessService.CreateBusiness_v2")
 	BusinessList := make([]model.Business, 0, 10000)
 
+	// Seed data doesn't go through the outbox, push it with /elastic/push-to-elastic
 	// Tạo 10.000 business ngẫu nhiên trực tiếp
 	for i := 0; i < 10000; i++ {
 		b := model.Business{
@@ -105,10 +115,18 @@ func (s *BusinessService) UpdateBusiness(ctx context.Context, req model.Business
 
 	copier.Copy(Business, req)
 
-	if err := s.repo.UpdateBusiness(ctx, Business, nil); err != nil {
+	err = s.repo.Transaction(ctx, func(rp repo.PGInterface) error {
+		if err := rp.UpdateBusiness(ctx, Business, nil); err != nil {
+			return err
+		}
+		return recordOutboxEvent(ctx, rp, model.OutboxAggregateBusiness, Business.ID, model.OutboxEventUpdated,
+			model.OutboxPayload{Business: Business})
+	})
+	if err != nil {
 		log.WithError(err).WithField("req", req).Error("Error update Business")
 		return nil, err
 	}
+	s.outbox.Notify()
 
 	return Business, nil
 }
@@ -156,10 +174,18 @@ func (s *BusinessService) DeleteBusiness(ctx context.Context, BusinessID uuid.UU
 		return ginext.NewError(http.StatusNotFound, err.Error())
 	}
 
-	if err = s.repo.DeleteBusiness(ctx, Business, nil); err != nil {
+	err = s.repo.Transaction(ctx, func(rp repo.PGInterface) error {
+		if err := rp.DeleteBusiness(ctx, Business, nil); err != nil {
+			return err
+		}
+		return recordOutboxEvent(ctx, rp, model.OutboxAggregateBusiness, Business.ID, model.OutboxEventDeleted,
+			model.OutboxPayload{Business: Business})
+	})
+	if err != nil {
 		log.WithError(err).WithField("Business", Business).Error("Error when call func DeleteBusiness")
 		return ginext.NewError(http.StatusInternalServerError, utils.MessageError()[http.StatusInternalServerError])
 	}
+	s.outbox.Notify()
 
 	return nil
 }
@@ -0,0 +1,90 @@
+package service
+
+import (
+	"fmt"
+)
+
+// Vietnamese text is indexed twice: folded, so "ha noi" finds "Hà Nội" like
+// utils.TransformString does, and exact in a subfield that keeps diacritics.
+const (
+	analyzerViFolded = "vi_folded"
+	analyzerViExact  = "vi_exact"
+	charFilterViD    = "vi_d_mapping"
+
+	// vi_folded_synonym is vi_folded with the business synonyms, for search only
+	analyzerViFoldedSynonym = "vi_folded_synonym"
+	filterBusinessSynonyms  = "business_synonyms"
+	// businessSynonymsSet is the Elasticsearch synonyms set holding the rules of every model.SynonymSet
+	businessSynonymsSet = "business_synonyms"
+
+	subfieldExact = "exact"
+	// boost of the exact subfield over the folded field in queries
+	exactBoost = 2
+)
+
+// vietnameseAnalysis is the index.analysis settings shared by the text indices
+func vietnameseAnalysis() map[string]interface{} {
+	return map[string]interface{}{
+		"char_filter": map[string]interface{}{
+			charFilterViD: map[string]interface{}{
+				"type":     "mapping",
+				"mappings": []string{"đ => d", "Đ => D"},
+			},
+		},
+		"analyzer": map[string]interface{}{
+			analyzerViFolded: map[string]interface{}{
+				"type":        "custom",
+				"tokenizer":   "standard",
+				"char_filter": []string{charFilterViD},
+				"filter":      []string{"lowercase", "asciifolding"},
+			},
+			analyzerViExact: map[string]interface{}{
+				"type":      "custom",
+				"tokenizer": "standard",
+				"filter":    []string{"lowercase"},
+			},
+		},
+	}
+}
+
+// businessAnalysis is vietnameseAnalysis with the synonyms. A synonym_graph
+// filter only works at search time, and being updateable it picks up new
+// synonyms when the search analyzers are reloaded, without a reindex.
+func businessAnalysis() map[string]interface{} {
+	analysis := vietnameseAnalysis()
+	analysis["filter"] = map[string]interface{}{
+		filterBusinessSynonyms: map[string]interface{}{
+			"type":         "synonym_graph",
+			"synonyms_set": businessSynonymsSet,
+			"updateable":   true,
+		},
+	}
+	analyzers := analysis["analyzer"].(map[string]interface{})
+	analyzers[analyzerViFoldedSynonym] = map[string]interface{}{
+		"type":        "custom",
+		"tokenizer":   "standard",
+		"char_filter": []string{charFilterViD},
+		"filter":      []string{"lowercase", "asciifolding", filterBusinessSynonyms},
+	}
+	return analysis
+}
+
+// viTextFields are the fields tagged with both analyzers in model.Business
+var viTextFields = map[string]bool{
+	fieldName:        true,
+	fieldDescription: true,
+	fieldAddress:     true,
+}
+
+// withExactFields adds the boosted exact subfield after every folded text
+// field, so matches with the right diacritics score higher
+func withExactFields(fields []string) []string {
+	out := make([]string, 0, len(fields)*2)
+	for _, f := range fields {
+		out = append(out, f)
+		if viTextFields[f] {
+			out = append(out, fmt.Sprintf("%s.%s^%d", f, subfieldExact, exactBoost))
+		}
+	}
+	return out
+}
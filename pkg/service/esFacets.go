@@ -0,0 +1,199 @@
+package service
+
+import (
+	"business/pkg/es"
+	"business/pkg/es/query"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"gitlab.com/goxp/cloud0/ginext"
+)
+
+// Field names as they appear in the indexed model.Business documents
+const (
+	fieldID           = "ID"
+	fieldName         = "name"
+	fieldNameSuggest  = "name.suggest"
+	fieldDescription  = "Description"
+	fieldAddress      = "address"
+	fieldBusinessType = "type"
+	fieldStatus       = "status"
+	fieldCreatedAt    = "CreateAt"
+	fieldStaffs       = "Staffs"
+	fieldStaffRole    = "Staffs.role"
+)
+
+// Aggregation names, also the keys of es.FacetResult
+const (
+	facetType      = "type"
+	facetStatus    = "status"
+	facetStaffRole = "staff_role"
+	facetCreatedAt = "created_at"
+
+	facetValues     = "values"
+	facetStaffs     = "staffs"
+	facetBusinesses = "businesses"
+
+	defaultFacetSize = 10
+)
+
+var calendarIntervals = map[string]bool{
+	"minute": true, "hour": true, "day": true, "week": true,
+	"month": true, "quarter": true, "year": true,
+}
+
+// facetFilters returns one filter per selected facet, keyed by facet name
+func facetFilters(sel es.FacetSelection) map[string]query.Query {
+	filters := map[string]query.Query{}
+	if len(sel.Type) > 0 {
+		filters[facetType] = query.TermsOf(fieldBusinessType, sel.Type...)
+	}
+	if len(sel.Status) > 0 {
+		filters[facetStatus] = query.TermsOf(fieldStatus, sel.Status...)
+	}
+	if len(sel.StaffRole) > 0 {
+		filters[facetStaffRole] = query.Nested(fieldStaffs, query.TermsOf(fieldStaffRole, sel.StaffRole...))
+	}
+	if sel.CreatedFrom != nil || sel.CreatedTo != nil {
+		created := query.Range(fieldCreatedAt)
+		if sel.CreatedFrom != nil {
+			created.Gte(sel.CreatedFrom)
+		}
+		if sel.CreatedTo != nil {
+			created.Lte(sel.CreatedTo)
+		}
+		filters[facetCreatedAt] = created
+	}
+	return filters
+}
+
+// filtersExcept combines every selected facet but one, so a facet's own
+// selection never hides its other buckets
+func filtersExcept(filters map[string]query.Query, except string) *query.BoolQuery {
+	q := query.Bool()
+	for name, f := range filters {
+		if name != except {
+			q.Filter(f)
+		}
+	}
+	return q
+}
+
+// applyFacets adds the facet selections as post_filter and the requested
+// aggregations, each filtered by the selections of the other facets
+func applyFacets(req es.SearchRequest, search *query.SearchSource) error {
+	filters := facetFilters(req.FacetFilters)
+	if len(filters) > 0 {
+		search.PostFilter(filtersExcept(filters, ""))
+	}
+
+	spec := req.Aggs
+	if spec == nil {
+		return nil
+	}
+	size := spec.Size
+	if size <= 0 {
+		size = defaultFacetSize
+	}
+
+	if spec.Type {
+		search.Aggregation(facetType, query.FilterAgg(filtersExcept(filters, facetType)).
+			SubAggregation(facetValues, query.TermsAgg(fieldBusinessType).Size(size)))
+	}
+	if spec.Status {
+		search.Aggregation(facetStatus, query.FilterAgg(filtersExcept(filters, facetStatus)).
+			SubAggregation(facetValues, query.TermsAgg(fieldStatus).Size(size)))
+	}
+	if spec.StaffRole {
+		// reverse_nested counts businesses instead of staff objects
+		roles := query.TermsAgg(fieldStaffRole).Size(size).
+			SubAggregation(facetBusinesses, query.ReverseNestedAgg())
+		search.Aggregation(facetStaffRole, query.FilterAgg(filtersExcept(filters, facetStaffRole)).
+			SubAggregation(facetStaffs, query.NestedAgg(fieldStaffs).SubAggregation(facetValues, roles)))
+	}
+	if spec.CreatedAtInterval != "" {
+		if !calendarIntervals[spec.CreatedAtInterval] {
+			return ginext.NewError(http.StatusBadRequest, fmt.Sprintf("invalid created_at_interval %q", spec.CreatedAtInterval))
+		}
+		search.Aggregation(facetCreatedAt, query.FilterAgg(filtersExcept(filters, facetCreatedAt)).
+			SubAggregation(facetValues, query.DateHistogramAgg(fieldCreatedAt, spec.CreatedAtInterval).MinDocCount(0)))
+	}
+	return nil
+}
+
+type termsBuckets struct {
+	Buckets []struct {
+		Key        interface{} `json:"key"`
+		DocCount   int64       `json:"doc_count"`
+		Businesses *struct {
+			DocCount int64 `json:"doc_count"`
+		} `json:"businesses,omitempty"`
+	} `json:"buckets"`
+}
+
+// decodeFacets turns the raw aggregations of a facet search into typed buckets
+func decodeFacets(aggs map[string]json.RawMessage) (*es.FacetResult, error) {
+	if len(aggs) == 0 {
+		return nil, nil
+	}
+	facets := &es.FacetResult{}
+
+	terms := func(name string, path ...string) ([]es.Bucket, error) {
+		raw, ok := aggs[name]
+		if !ok {
+			return nil, nil
+		}
+		var tb termsBuckets
+		if err := decodeAggPath(raw, path, &tb); err != nil {
+			return nil, fmt.Errorf("failed to decode %s facet: %w", name, err)
+		}
+		buckets := make([]es.Bucket, 0, len(tb.Buckets))
+		for _, b := range tb.Buckets {
+			bucket := es.Bucket{Key: fmt.Sprint(b.Key), DocCount: b.DocCount}
+			if b.Businesses != nil {
+				bucket.DocCount = b.Businesses.DocCount
+			}
+			buckets = append(buckets, bucket)
+		}
+		return buckets, nil
+	}
+
+	var err error
+	if facets.Type, err = terms(facetType, facetValues); err != nil {
+		return nil, err
+	}
+	if facets.Status, err = terms(facetStatus, facetValues); err != nil {
+		return nil, err
+	}
+	if facets.StaffRole, err = terms(facetStaffRole, facetStaffs, facetValues); err != nil {
+		return nil, err
+	}
+	if raw, ok := aggs[facetCreatedAt]; ok {
+		var histogram struct {
+			Buckets []es.DateBucket `json:"buckets"`
+		}
+		if err := decodeAggPath(raw, []string{facetValues}, &histogram); err != nil {
+			return nil, fmt.Errorf("failed to decode %s facet: %w", facetCreatedAt, err)
+		}
+		facets.CreatedAt = histogram.Buckets
+	}
+
+	return facets, nil
+}
+
+// decodeAggPath walks down sub aggregations by name and decodes the last one
+func decodeAggPath(raw json.RawMessage, path []string, out interface{}) error {
+	for _, name := range path {
+		var level map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &level); err != nil {
+			return err
+		}
+		next, ok := level[name]
+		if !ok {
+			return fmt.Errorf("missing sub aggregation %s", name)
+		}
+		raw = next
+	}
+	return json.Unmarshal(raw, out)
+}
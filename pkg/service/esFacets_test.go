@@ -0,0 +1,205 @@
+package service
+
+import (
+	"business/pkg/es"
+	"business/pkg/es/query"
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestApplyFacets(t *testing.T) {
+	tests := []struct {
+		name     string
+		req      es.SearchRequest
+		want     string
+		wantCode int
+	}{
+		{
+			name: "no facets",
+			want: `{}`,
+		},
+		{
+			name: "selection only",
+			req:  es.SearchRequest{FacetFilters: es.FacetSelection{Type: []string{"cafe"}}},
+			want: `{"post_filter": {"bool": {"filter": [{"terms": {"type": ["cafe"]}}]}}}`,
+		},
+		{
+			name: "a facet ignores its own selection",
+			req: es.SearchRequest{
+				FacetFilters: es.FacetSelection{Type: []string{"cafe"}},
+				Aggs:         &es.AggregationSpec{Type: true, Status: true, Size: 5},
+			},
+			want: `{
+				"post_filter": {"bool": {"filter": [{"terms": {"type": ["cafe"]}}]}},
+				"aggs": {
+					"type": {"filter": {"bool": {}}, "aggs": {"values": {"terms": {"field": "type", "size": 5}}}},
+					"status": {
+						"filter": {"bool": {"filter": [{"terms": {"type": ["cafe"]}}]}},
+						"aggs": {"values": {"terms": {"field": "status", "size": 5}}}
+					}
+				}
+			}`,
+		},
+		{
+			name: "staff roles count businesses",
+			req:  es.SearchRequest{Aggs: &es.AggregationSpec{StaffRole: true}},
+			want: `{"aggs": {"staff_role": {
+				"filter": {"bool": {}},
+				"aggs": {"staffs": {
+					"nested": {"path": "Staffs"},
+					"aggs": {"values": {
+						"terms": {"field": "Staffs.role", "size": 10},
+						"aggs": {"businesses": {"reverse_nested": {}}}
+					}}
+				}}
+			}}}`,
+		},
+		{
+			name: "created at histogram",
+			req:  es.SearchRequest{Aggs: &es.AggregationSpec{CreatedAtInterval: "month"}},
+			want: `{"aggs": {"created_at": {
+				"filter": {"bool": {}},
+				"aggs": {"values": {"date_histogram": {"field": "CreateAt", "calendar_interval": "month", "min_doc_count": 0}}}
+			}}}`,
+		},
+		{
+			name:     "bad interval",
+			req:      es.SearchRequest{Aggs: &es.AggregationSpec{CreatedAtInterval: "fortnight"}},
+			wantCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			search := query.NewSearch()
+			err := applyFacets(tt.req, search)
+			if tt.wantCode != 0 {
+				if code := statusOf(err); code != tt.wantCode {
+					t.Fatalf("status = %d, want %d (err %v)", code, tt.wantCode, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("err = %v", err)
+			}
+			assertJSON(t, search.Source(), tt.want)
+		})
+	}
+}
+
+func TestSearchWithFieldFacetSelection(t *testing.T) {
+	cafe, bar, closed := testBusiness(1, "Cafe Sua"), testBusiness(2, "Bar Dem"), testBusiness(3, "Cafe Cu")
+	bar.BusinessType = "bar"
+	closed.Status = "closed"
+
+	tests := []struct {
+		name      string
+		selection es.FacetSelection
+		wantIDs   []string
+	}{
+		{name: "nothing selected", wantIDs: []string{fmtID(1), fmtID(2), fmtID(3)}},
+		{name: "one value", selection: es.FacetSelection{Type: []string{"cafe"}}, wantIDs: []string{fmtID(1), fmtID(3)}},
+		{name: "several values", selection: es.FacetSelection{Type: []string{"cafe", "bar"}}, wantIDs: []string{fmtID(1), fmtID(2), fmtID(3)}},
+		{
+			name:      "several facets",
+			selection: es.FacetSelection{Type: []string{"cafe"}, Status: []string{"active"}},
+			wantIDs:   []string{fmtID(1)},
+		},
+	}
+
+	e, _, _ := newTestEsService(t, nil, cafe, bar, closed)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rs, err := e.SearchWithField(context.Background(), es.SearchRequest{
+				Index: businessAlias, Page: 1, Size: 10,
+				Sort:         es.SortSpec{{Field: fieldID}},
+				FacetFilters: tt.selection,
+				Aggs:         &es.AggregationSpec{Type: true},
+			})
+			if err != nil {
+				t.Fatalf("err = %v", err)
+			}
+			if got := hitIDs(rs); !slices.Equal(got, tt.wantIDs) {
+				t.Errorf("hits = %v, want %v", got, tt.wantIDs)
+			}
+		})
+	}
+}
+
+func TestDecodeFacets(t *testing.T) {
+	tests := []struct {
+		name    string
+		aggs    string
+		want    *es.FacetResult
+		wantErr bool
+	}{
+		{name: "no aggregations", aggs: `{}`, want: nil},
+		{
+			name: "terms",
+			aggs: `{
+				"type": {"doc_count": 3, "values": {"buckets": [{"key": "cafe", "doc_count": 2}, {"key": "bar", "doc_count": 1}]}},
+				"status": {"doc_count": 3, "values": {"buckets": [{"key": "active", "doc_count": 3}]}}
+			}`,
+			want: &es.FacetResult{
+				Type:   []es.Bucket{{Key: "cafe", DocCount: 2}, {Key: "bar", DocCount: 1}},
+				Status: []es.Bucket{{Key: "active", DocCount: 3}},
+			},
+		},
+		{
+			name: "staff roles count businesses, not staffs",
+			aggs: `{"staff_role": {"doc_count": 2, "staffs": {"doc_count": 5, "values": {"buckets": [
+				{"key": "admin", "doc_count": 4, "businesses": {"doc_count": 2}}
+			]}}}}`,
+			want: &es.FacetResult{StaffRole: []es.Bucket{{Key: "admin", DocCount: 2}}},
+		},
+		{
+			name: "created at",
+			aggs: `{"created_at": {"doc_count": 2, "values": {"buckets": [
+				{"key": 1704067200000, "key_as_string": "2024-01-01T00:00:00.000Z", "doc_count": 2}
+			]}}}`,
+			want: &es.FacetResult{CreatedAt: []es.DateBucket{{Key: 1704067200000, KeyAsString: "2024-01-01T00:00:00.000Z", DocCount: 2}}},
+		},
+		{
+			name:    "missing sub aggregation",
+			aggs:    `{"staff_role": {"doc_count": 2, "values": {"buckets": []}}}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var aggs map[string]json.RawMessage
+			if err := json.Unmarshal([]byte(tt.aggs), &aggs); err != nil {
+				t.Fatal(err)
+			}
+			got, err := decodeFacets(aggs)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// assertJSON compares the JSON of got with the want document, key order aside
+func assertJSON(t *testing.T, got interface{}, want string) {
+	t.Helper()
+	gotJSON, err := json.Marshal(got)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	var wantValue interface{}
+	if err := json.Unmarshal([]byte(want), &wantValue); err != nil {
+		t.Fatalf("bad want JSON: %v", err)
+	}
+	wantJSON, _ := json.Marshal(wantValue)
+	if string(gotJSON) != string(wantJSON) {
+		t.Errorf("got  %s\nwant %s", gotJSON, wantJSON)
+	}
+}
@@ -0,0 +1,44 @@
+package service
+
+import (
+	"business/pkg/es"
+	"business/pkg/es/query"
+	"fmt"
+	"net/http"
+	"regexp"
+
+	"gitlab.com/goxp/cloud0/ginext"
+)
+
+const defaultFuzziness = "AUTO"
+
+// AUTO, AUTO:low,high or an edit distance of 0, 1 or 2
+var fuzzinessPattern = regexp.MustCompile(`^(AUTO(:\d+,\d+)?|[012])$`)
+
+func validateFuzzy(fuzzy es.FuzzySpec) error {
+	if fuzzy.Fuzziness != "" && !fuzzinessPattern.MatchString(fuzzy.Fuzziness) {
+		return ginext.NewError(http.StatusBadRequest, fmt.Sprintf("invalid fuzziness %q, use AUTO, AUTO:low,high, 0, 1 or 2", fuzzy.Fuzziness))
+	}
+	if fuzzy.PrefixLength < 0 {
+		return ginext.NewError(http.StatusBadRequest, "prefix_length must not be negative")
+	}
+	if fuzzy.MaxExpansions < 0 {
+		return ginext.NewError(http.StatusBadRequest, "max_expansions must not be negative")
+	}
+	return nil
+}
+
+// applyFuzzy sets the fuzzy options on a full-text clause
+func applyFuzzy(q *query.MultiMatchQuery, fuzzy es.FuzzySpec) {
+	fuzziness := fuzzy.Fuzziness
+	if fuzziness == "" {
+		fuzziness = defaultFuzziness
+	}
+	q.Fuzziness(fuzziness)
+	if fuzzy.PrefixLength > 0 {
+		q.PrefixLength(fuzzy.PrefixLength)
+	}
+	if fuzzy.MaxExpansions > 0 {
+		q.MaxExpansions(fuzzy.MaxExpansions)
+	}
+}
@@ -0,0 +1,107 @@
+package service
+
+import (
+	"business/pkg/es"
+	"context"
+	"fmt"
+
+	"gitlab.com/goxp/cloud0/logger"
+)
+
+// managedIndex is an index owned by this service with the ways to create
+// it and to rebuild it when its definition changed in a breaking way
+type managedIndex struct {
+	definition es.IndexDefinition
+	ensure     func(ctx context.Context) error
+	reindex    func(ctx context.Context) (*ReindexReport, error)
+}
+
+func (e *EsService) managedIndices() []managedIndex {
+	return []managedIndex{
+		{definition: businessIndex(), ensure: e.ensureBusinessIndex, reindex: e.ReindexBusiness},
+		{definition: staffIndex(), ensure: e.ensureStaffIndex, reindex: e.ReindexStaff},
+	}
+}
+
+// MigrationReport describes what MigrateMappings did for each index
+type MigrationReport struct {
+	Plans     []*es.MappingPlan `json:"plans"`
+	Created   []string          `json:"created"`
+	Updated   []string          `json:"updated"`
+	Reindexed []*ReindexReport  `json:"reindexed"`
+	// Pending are indices with breaking changes left for a reindex
+	Pending []string `json:"pending"`
+}
+
+// CheckMappings compares the live mapping of every managed index with its
+// definition, a drift is reported as *es.MappingDriftError
+func (e *EsService) CheckMappings(ctx context.Context) error {
+	for _, idx := range e.managedIndices() {
+		if err := es.CheckMapping(ctx, e.client, idx.definition); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MappingDrift plans the changes needed by every managed index without applying them
+func (e *EsService) MappingDrift(ctx context.Context) ([]*es.MappingPlan, error) {
+	var plans []*es.MappingPlan
+	for _, idx := range e.managedIndices() {
+		plan, err := es.PlanMapping(ctx, e.client, idx.definition)
+		if err != nil {
+			return nil, fmt.Errorf("failed to plan mapping of %s: %w", idx.definition.Name, err)
+		}
+		plans = append(plans, plan)
+	}
+	return plans, nil
+}
+
+// MigrateMappings creates missing indices and puts additive changes in
+// place. Indices with breaking changes are rebuilt when reindex is set,
+// otherwise they are reported as pending.
+func (e *EsService) MigrateMappings(ctx context.Context, reindex bool) (*MigrationReport, error) {
+	log := logger.WithCtx(ctx, "esService.MigrateMappings")
+	report := &MigrationReport{}
+
+	for _, idx := range e.managedIndices() {
+		name := idx.definition.Name
+		plan, err := es.PlanMapping(ctx, e.client, idx.definition)
+		if err != nil {
+			return report, fmt.Errorf("failed to plan mapping of %s: %w", name, err)
+		}
+		report.Plans = append(report.Plans, plan)
+
+		switch {
+		case !plan.Exists:
+			if err := idx.ensure(ctx); err != nil {
+				return report, fmt.Errorf("failed to create %s: %w", name, err)
+			}
+			report.Created = append(report.Created, name)
+			continue
+		case len(plan.Breaking) > 0 && reindex:
+			// the new index is built from the definition, additive changes included
+			rs, err := idx.reindex(ctx)
+			if rs != nil {
+				report.Reindexed = append(report.Reindexed, rs)
+			}
+			if err != nil {
+				return report, fmt.Errorf("failed to reindex %s: %w", name, err)
+			}
+			continue
+		case len(plan.Breaking) > 0:
+			log.Warnf("%s has %d breaking mapping changes, reindex needed", name, len(plan.Breaking))
+			report.Pending = append(report.Pending, name)
+		}
+
+		if len(plan.Additive) > 0 {
+			if err := es.ApplyAdditive(ctx, e.client, idx.definition, plan); err != nil {
+				return report, fmt.Errorf("failed to update mapping of %s: %w", name, err)
+			}
+			log.Infof("added %d fields to the mapping of %s", len(plan.Additive), name)
+			report.Updated = append(report.Updated, name)
+		}
+	}
+
+	return report, nil
+}
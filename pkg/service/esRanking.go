@@ -0,0 +1,81 @@
+package service
+
+import (
+	"business/pkg/es/query"
+	"business/pkg/model"
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// recencyOrigin is rounded to the hour so the pages of a cursor search and
+// repeated searches score the same
+const recencyOrigin = "now/h"
+
+// rankedFields is withExactFields with the field boosts of a profile, the
+// exact subfield keeps its extra boost on top. Fields without a boost weigh 1.
+func rankedFields(fields []string, boosts model.Weights) []string {
+	out := make([]string, 0, len(fields)*2)
+	for _, f := range fields {
+		boost, ok := boosts[f]
+		if !ok {
+			boost = 1
+		}
+		out = append(out, boostedField(f, boost))
+		if viTextFields[f] {
+			out = append(out, boostedField(fmt.Sprintf("%s.%s", f, subfieldExact), exactBoost*boost))
+		}
+	}
+	return out
+}
+
+func boostedField(field string, boost float64) string {
+	if boost == 1 {
+		return field
+	}
+	return field + "^" + strconv.FormatFloat(boost, 'f', -1, 64)
+}
+
+// rankedQuery wraps q in a function_score with the recency decay and status
+// weights of the profile. The functions multiply each other, a business
+// matching no status weight keeps its score.
+func rankedQuery(q query.Query, profile *model.RankingProfile) query.Query {
+	if profile == nil || (profile.Recency == nil && len(profile.StatusWeights) == 0) {
+		return q
+	}
+
+	functionScore := query.FunctionScore(q).ScoreMode("multiply")
+	if r := profile.Recency; r != nil {
+		var decay *query.DecayFunction
+		switch r.Function {
+		case "exp":
+			decay = query.Exp(fieldCreatedAt)
+		case "linear":
+			decay = query.Linear(fieldCreatedAt)
+		default:
+			decay = query.Gauss(fieldCreatedAt)
+		}
+		decay.Origin(recencyOrigin).Scale(r.Scale)
+		if r.Offset != "" {
+			decay.Offset(r.Offset)
+		}
+		if r.Decay > 0 {
+			decay.Decay(r.Decay)
+		}
+		functionScore.Add(decay)
+	}
+
+	statuses := make([]string, 0, len(profile.StatusWeights))
+	for status := range profile.StatusWeights {
+		statuses = append(statuses, status)
+	}
+	sort.Strings(statuses)
+	for _, status := range statuses {
+		functionScore.Add(query.Weight(profile.StatusWeights[status]).Filter(query.Term(fieldStatus, status)))
+	}
+
+	if profile.BoostMode != "" {
+		functionScore.BoostMode(profile.BoostMode)
+	}
+	return functionScore
+}
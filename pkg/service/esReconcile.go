@@ -0,0 +1,343 @@
+package service
+
+import (
+	"business/pkg/es"
+	"business/pkg/es/query"
+	"business/pkg/model"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"gitlab.com/goxp/cloud0/logger"
+	"gorm.io/gorm"
+)
+
+const (
+	reconcileBatchSize = 500
+	// each list of the report keeps this many IDs, the counts are exact
+	reconcileMaxListed = 1000
+)
+
+// ReconcileReport compares the businesses in Postgres with the documents of
+// the business index. Missing documents are absent from the index, orphaned
+// ones have no business left and stale ones differ from their business.
+type ReconcileReport struct {
+	StartedAt     time.Time `json:"started_at"`
+	FinishedAt    time.Time `json:"finished_at"`
+	Postgres      int64     `json:"postgres"`
+	Elasticsearch int64     `json:"elasticsearch"`
+
+	MissingCount  int      `json:"missing_count"`
+	OrphanedCount int      `json:"orphaned_count"`
+	StaleCount    int      `json:"stale_count"`
+	Missing       []string `json:"missing"`
+	Orphaned      []string `json:"orphaned"`
+	Stale         []string `json:"stale"`
+	// Truncated is set when a list was cut at reconcileMaxListed IDs
+	Truncated bool `json:"truncated"`
+
+	Repair   bool                 `json:"repair"`
+	Indexed  int64                `json:"indexed"`
+	Deleted  int64                `json:"deleted"`
+	Failures []es.BulkItemFailure `json:"failures"`
+}
+
+func (r *ReconcileReport) add(list *[]string, count *int, id string) {
+	*count++
+	if len(*list) < reconcileMaxListed {
+		*list = append(*list, id)
+	} else {
+		r.Truncated = true
+	}
+}
+
+// Reconcile streams businesses from Postgres and documents from the business
+// index, both sorted by ID, and merges them to find what is missing,
+// orphaned or stale. With repair, missing and stale businesses are indexed
+// again and orphaned documents deleted.
+func (e *EsService) Reconcile(ctx context.Context, repair bool) (*ReconcileReport, error) {
+	log := logger.WithCtx(ctx, "esService.Reconcile")
+	report := &ReconcileReport{StartedAt: time.Now(), Repair: repair}
+
+	exists, err := e.client.IndexExists(ctx, businessAlias)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check index existence: %w", err)
+	}
+
+	pg := &pgBusinessStream{e: e}
+	docs := &esBusinessStream{e: e, done: !exists}
+	defer docs.close(ctx)
+
+	var indexer es.BulkIndexer
+	if repair {
+		if err := e.ensureBusinessIndex(ctx); err != nil {
+			return nil, err
+		}
+		if indexer, err = e.client.NewBulkIndexer(ctx, es.BulkIndexerConfig{Index: businessAlias}); err != nil {
+			return nil, fmt.Errorf("failed to start bulk indexer: %w", err)
+		}
+	}
+	reindex := func(b *model.Business) error {
+		if indexer == nil {
+			return nil
+		}
+		if err := indexer.Add(ctx, es.BulkDocument{ID: b.ID.String(), Data: b}); err != nil {
+			return fmt.Errorf("failed to queue business %s: %w", b.ID, err)
+		}
+		return nil
+	}
+
+	business, err := pg.next(ctx)
+	if err != nil {
+		return e.abortReconcile(ctx, indexer, err)
+	}
+	doc, err := docs.next(ctx)
+	if err != nil {
+		return e.abortReconcile(ctx, indexer, err)
+	}
+
+	for business != nil || doc != nil {
+		var id string
+		if business != nil {
+			id = business.ID.String()
+		}
+		switch {
+		case doc == nil || (business != nil && id < doc.id):
+			report.add(&report.Missing, &report.MissingCount, id)
+			if err := reindex(business); err != nil {
+				return e.abortReconcile(ctx, indexer, err)
+			}
+			business, err = pg.next(ctx)
+
+		case business == nil || doc.id < id:
+			report.add(&report.Orphaned, &report.OrphanedCount, doc.id)
+			if repair {
+				deleted, err := e.deleteOrphan(ctx, doc.id)
+				if err != nil {
+					return e.abortReconcile(ctx, indexer, err)
+				}
+				if deleted {
+					report.Deleted++
+				}
+			}
+			doc, err = docs.next(ctx)
+
+		default:
+			hash, herr := businessHash(business)
+			if herr != nil {
+				return e.abortReconcile(ctx, indexer, herr)
+			}
+			if hash != doc.hash {
+				report.add(&report.Stale, &report.StaleCount, id)
+				if err := reindex(business); err != nil {
+					return e.abortReconcile(ctx, indexer, err)
+				}
+			}
+			if business, err = pg.next(ctx); err == nil {
+				doc, err = docs.next(ctx)
+			}
+		}
+		if err != nil {
+			return e.abortReconcile(ctx, indexer, err)
+		}
+	}
+	report.Postgres, report.Elasticsearch = pg.count, docs.count
+
+	if indexer != nil {
+		bulk, err := indexer.Close(ctx)
+		if err != nil {
+			return report, fmt.Errorf("failed to flush bulk indexer: %w", err)
+		}
+		report.Indexed = bulk.Indexed
+		report.Failures = bulk.Failures
+	}
+
+	report.FinishedAt = time.Now()
+	log.Infof("reconciled %d businesses with %d documents: %d missing, %d orphaned, %d stale",
+		report.Postgres, report.Elasticsearch, report.MissingCount, report.OrphanedCount, report.StaleCount)
+	return report, nil
+}
+
+func (e *EsService) abortReconcile(ctx context.Context, indexer es.BulkIndexer, err error) (*ReconcileReport, error) {
+	if indexer != nil {
+		_, _ = indexer.Close(ctx)
+	}
+	return nil, err
+}
+
+// deleteOrphan deletes the document of a business after checking it is
+// still gone, it may have been created since the scan went past its ID
+func (e *EsService) deleteOrphan(ctx context.Context, id string) (bool, error) {
+	businessID, err := uuid.Parse(id)
+	if err == nil {
+		_, err = e.repo.GetOneBusiness(ctx, businessID, nil)
+		if err == nil {
+			return false, nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, fmt.Errorf("failed to read business %s: %w", id, err)
+		}
+	}
+	if err := e.client.DeleteDocument(ctx, businessAlias, id); err != nil && !errors.Is(err, es.ErrNotFound) {
+		return false, fmt.Errorf("failed to delete document %s: %w", id, err)
+	}
+	return true, nil
+}
+
+// pgBusinessStream reads businesses in ID order, a batch at a time
+type pgBusinessStream struct {
+	e     *EsService
+	after uuid.UUID
+	buf   []model.Business
+	done  bool
+	count int64
+}
+
+func (s *pgBusinessStream) next(ctx context.Context) (*model.Business, error) {
+	if len(s.buf) == 0 && !s.done {
+		page, err := s.e.repo.GetBusinessPageAfter(ctx, s.after, reconcileBatchSize, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read businesses after %s: %w", s.after, err)
+		}
+		s.buf = page
+		s.done = len(page) < reconcileBatchSize
+		if len(page) > 0 {
+			s.after = page[len(page)-1].ID
+		}
+	}
+	if len(s.buf) == 0 {
+		return nil, nil
+	}
+	b := &s.buf[0]
+	s.buf = s.buf[1:]
+	s.count++
+	return b, nil
+}
+
+type reconcileDoc struct {
+	id   string
+	hash string
+}
+
+// esBusinessStream reads the business index in ID order through a point in
+// time, so documents written during the scan don't shift the pages
+type esBusinessStream struct {
+	e     *EsService
+	pitID string
+	after []json.RawMessage
+	buf   []reconcileDoc
+	done  bool
+	count int64
+}
+
+func (s *esBusinessStream) next(ctx context.Context) (*reconcileDoc, error) {
+	if len(s.buf) == 0 && !s.done {
+		if err := s.fetch(ctx); err != nil {
+			return nil, err
+		}
+	}
+	if len(s.buf) == 0 {
+		return nil, nil
+	}
+	doc := &s.buf[0]
+	s.buf = s.buf[1:]
+	s.count++
+	return doc, nil
+}
+
+func (s *esBusinessStream) fetch(ctx context.Context) error {
+	if s.pitID == "" {
+		id, err := s.e.client.OpenPointInTime(ctx, businessAlias, pitKeepAlive)
+		if err != nil {
+			return fmt.Errorf("failed to open point in time: %w", err)
+		}
+		s.pitID = id
+	}
+
+	search := query.NewSearch().Query(query.MatchAll()).Size(reconcileBatchSize).
+		PointInTime(s.pitID, pitKeepAlive).
+		Sort(query.SortBy(fieldID, "asc"), query.SortBy("_shard_doc", "asc"))
+	if s.after != nil {
+		search.SearchAfter(s.after)
+	}
+	result, err := s.e.client.Search(ctx, "", search)
+	if err != nil {
+		return fmt.Errorf("failed to read business documents: %w", err)
+	}
+	if result.PitID != "" {
+		s.pitID = result.PitID
+	}
+
+	hits := result.Hits.Hits
+	s.done = len(hits) < reconcileBatchSize
+	for _, hit := range hits {
+		hash, err := documentHash(hit.Source)
+		if err != nil {
+			return fmt.Errorf("failed to hash document %s: %w", hit.ID, err)
+		}
+		s.buf = append(s.buf, reconcileDoc{id: hit.ID, hash: hash})
+	}
+	if len(hits) > 0 {
+		s.after = hits[len(hits)-1].Sort
+	}
+	return nil
+}
+
+func (s *esBusinessStream) close(ctx context.Context) {
+	if s.pitID == "" {
+		return
+	}
+	if err := s.e.client.ClosePointInTime(ctx, s.pitID); err != nil {
+		logger.WithCtx(ctx, "esService.Reconcile").WithError(err).Warn("failed to close point in time")
+	}
+}
+
+// businessHash hashes a business the way it is stored in the index
+func businessHash(b *model.Business) (string, error) {
+	var source map[string]interface{}
+	data, err := json.Marshal(b)
+	if err != nil {
+		return "", err
+	}
+	if err := json.Unmarshal(data, &source); err != nil {
+		return "", err
+	}
+	return documentHash(source)
+}
+
+// documentHash hashes a document source. Keys are sorted by encoding/json
+// and staffs by id, Postgres returns them in no particular order.
+func documentHash(source map[string]interface{}) (string, error) {
+	if staffs, ok := source[fieldStaffs].([]interface{}); ok {
+		sorted := append([]interface{}{}, staffs...)
+		sort.SliceStable(sorted, func(i, j int) bool {
+			return fmt.Sprint(staffID(sorted[i])) < fmt.Sprint(staffID(sorted[j]))
+		})
+		copied := make(map[string]interface{}, len(source))
+		for k, v := range source {
+			copied[k] = v
+		}
+		copied[fieldStaffs] = sorted
+		source = copied
+	}
+
+	data, err := json.Marshal(source)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func staffID(staff interface{}) interface{} {
+	if m, ok := staff.(map[string]interface{}); ok {
+		return m["id"]
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package service
+
+import (
+	"business/pkg/model"
+	"context"
+	"slices"
+	"testing"
+)
+
+func TestReconcile(t *testing.T) {
+	inSync, stale, missing := testBusiness(1, "Cafe Sua"), testBusiness(2, "Tra Dao"), testBusiness(3, "Bar Dem")
+	staleDoc := stale
+	staleDoc.Name = "Tra Dao Cu"
+	orphan := testBusiness(4, "Gone")
+	// staffs come back from postgres in any order
+	inSync.Staffs = []model.Staff{{ID: testBusiness(12, "").ID, Role: "staff"}, {ID: testBusiness(11, "").ID, Role: "admin"}}
+	inSyncDoc := inSync
+	inSyncDoc.Staffs = []model.Staff{inSync.Staffs[1], inSync.Staffs[0]}
+
+	tests := []struct {
+		name         string
+		businesses   []model.Business
+		docs         []model.Business
+		wantMissing  []string
+		wantOrphaned []string
+		wantStale    []string
+	}{
+		{
+			name:       "in sync",
+			businesses: []model.Business{inSync, stale},
+			docs:       []model.Business{inSyncDoc, stale},
+		},
+		{
+			name:         "missing, orphaned and stale",
+			businesses:   []model.Business{inSync, stale, missing},
+			docs:         []model.Business{inSyncDoc, staleDoc, orphan},
+			wantMissing:  []string{fmtID(3)},
+			wantOrphaned: []string{fmtID(4)},
+			wantStale:    []string{fmtID(2)},
+		},
+		{
+			name:        "empty index",
+			businesses:  []model.Business{inSync, stale},
+			wantMissing: []string{fmtID(1), fmtID(2)},
+		},
+		{
+			name:         "empty postgres",
+			docs:         []model.Business{inSyncDoc, orphan},
+			wantOrphaned: []string{fmtID(1), fmtID(4)},
+		},
+	}
+
+	for _, tt := range tests {
+		for _, repair := range []bool{false, true} {
+			name := tt.name
+			if repair {
+				name += " repaired"
+			}
+			t.Run(name, func(t *testing.T) {
+				ctx := context.Background()
+				e, _, _ := newTestEsService(t, tt.businesses, tt.docs...)
+
+				report, err := e.Reconcile(ctx, repair)
+				if err != nil {
+					t.Fatalf("err = %v", err)
+				}
+				if report.Postgres != int64(len(tt.businesses)) || report.Elasticsearch != int64(len(tt.docs)) {
+					t.Errorf("compared %d businesses with %d documents, want %d with %d",
+						report.Postgres, report.Elasticsearch, len(tt.businesses), len(tt.docs))
+				}
+				for _, list := range []struct {
+					name      string
+					got, want []string
+					count     int
+				}{
+					{"missing", report.Missing, tt.wantMissing, report.MissingCount},
+					{"orphaned", report.Orphaned, tt.wantOrphaned, report.OrphanedCount},
+					{"stale", report.Stale, tt.wantStale, report.StaleCount},
+				} {
+					if !slices.Equal(list.got, list.want) || list.count != len(list.want) {
+						t.Errorf("%s = %v (%d), want %v", list.name, list.got, list.count, list.want)
+					}
+				}
+
+				if !repair {
+					if report.Indexed != 0 || report.Deleted != 0 {
+						t.Errorf("indexed %d and deleted %d without repair", report.Indexed, report.Deleted)
+					}
+					return
+				}
+				if want := int64(len(tt.wantMissing) + len(tt.wantStale)); report.Indexed != want {
+					t.Errorf("indexed %d, want %d", report.Indexed, want)
+				}
+				if want := int64(len(tt.wantOrphaned)); report.Deleted != want {
+					t.Errorf("deleted %d, want %d", report.Deleted, want)
+				}
+
+				again, err := e.Reconcile(ctx, false)
+				if err != nil {
+					t.Fatalf("second pass: %v", err)
+				}
+				if again.MissingCount+again.OrphanedCount+again.StaleCount != 0 {
+					t.Errorf("after repair: %d missing, %d orphaned, %d stale", again.MissingCount, again.OrphanedCount, again.StaleCount)
+				}
+			})
+		}
+	}
+}
+
+func TestReconcileKeepsOrphanCreatedSinceTheScan(t *testing.T) {
+	ctx := context.Background()
+	late := testBusiness(1, "Cafe Sua")
+	e, client, rp := newTestEsService(t, nil, late)
+
+	// the scan reads no business, then the business commits before the delete
+	rp.afterPage = func(r *fakeRepo) {
+		r.afterPage = nil
+		r.businesses = append(r.businesses, late)
+	}
+	report, err := e.Reconcile(ctx, true)
+	if err != nil {
+		t.Fatalf("err = %v", err)
+	}
+	if report.OrphanedCount != 1 || report.Deleted != 0 {
+		t.Errorf("%d orphaned and %d deleted, want 1 orphaned and none deleted", report.OrphanedCount, report.Deleted)
+	}
+	if _, err := client.GetDocument(ctx, businessAlias, late.ID.String()); err != nil {
+		t.Errorf("document of the new business: %v", err)
+	}
+}
@@ -0,0 +1,253 @@
+package service
+
+import (
+	"business/pkg/es"
+	"business/pkg/model"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gitlab.com/goxp/cloud0/logger"
+)
+
+const (
+	// businessAlias is what readers and writers use, it points to one business_vN index
+	businessAlias   = "business"
+	indexVersionSep = "_v"
+	reindexPageSize = 1000
+
+	// reindexReplayMargin reaches back before a reindex started, for changes
+	// whose transaction began earlier but committed after fill read past them
+	reindexReplayMargin = time.Minute
+)
+
+// ReindexReport describes a finished reindex of an alias
+type ReindexReport struct {
+	Alias           string               `json:"alias"`
+	NewIndex        string               `json:"new_index"`
+	PreviousIndices []string             `json:"previous_indices"`
+	Expected        int64                `json:"expected"`
+	Indexed         int64                `json:"indexed"`
+	Failures        []es.BulkItemFailure `json:"failures"`
+	// Replayed counts the outbox events synced again after the swap
+	Replayed int `json:"replayed"`
+}
+
+// ensureBusinessIndex creates business_v1 behind the business alias on a
+// fresh cluster, after the synonyms set its analysis refers to
+func (e *EsService) ensureBusinessIndex(ctx context.Context) error {
+	exists, err := e.client.IndexExists(ctx, businessAlias)
+	if err != nil {
+		return fmt.Errorf("failed to check index existence: %w", err)
+	}
+	if exists {
+		return nil
+	}
+	if _, err := e.putSynonyms(ctx); err != nil {
+		return err
+	}
+	return e.ensureIndex(ctx, businessIndex())
+}
+
+// ReindexBusiness builds the next business_vN index from Postgres and swaps
+// the business alias to it once the document count matches. Older versions
+// are left in place so the alias can be pointed back for a rollback. Writes
+// go on meanwhile, the changes they made are replayed after the swap.
+func (e *EsService) ReindexBusiness(ctx context.Context) (*ReindexReport, error) {
+	if _, err := e.putSynonyms(ctx); err != nil {
+		return nil, err
+	}
+	started := time.Now()
+	report, err := e.reindex(ctx, businessIndex(), e.fillBusinesses)
+	if err != nil {
+		return report, err
+	}
+	return e.replayChanges(ctx, report, started, model.OutboxAggregateBusiness, model.OutboxAggregateStaff)
+}
+
+func (e *EsService) fillBusinesses(ctx context.Context, indexer es.BulkIndexer) (int64, error) {
+	var expected int64
+	after := uuid.Nil
+	for {
+		// keyset paging, rows written meanwhile can't shift a page
+		businesses, err := e.repo.GetBusinessPageAfter(ctx, after, reindexPageSize, nil)
+		if err != nil {
+			return expected, fmt.Errorf("failed to read businesses after %s: %w", after, err)
+		}
+		for _, b := range businesses {
+			if err := indexer.Add(ctx, es.BulkDocument{ID: b.ID.String(), Data: b}); err != nil {
+				return expected, fmt.Errorf("failed to queue business %s: %w", b.ID, err)
+			}
+		}
+		expected += int64(len(businesses))
+		if len(businesses) < reindexPageSize {
+			return expected, nil
+		}
+		after = businesses[len(businesses)-1].ID
+	}
+}
+
+// ensureIndex creates <alias>_v1 behind the alias of def on a fresh cluster
+func (e *EsService) ensureIndex(ctx context.Context, def es.IndexDefinition) error {
+	exists, err := e.client.IndexExists(ctx, def.Name)
+	if err != nil {
+		return fmt.Errorf("failed to check index existence: %w", err)
+	}
+	if exists {
+		return nil
+	}
+
+	body, err := def.Body()
+	if err != nil {
+		return err
+	}
+	indexName := indexVersion(def.Name, 1)
+	if err := e.client.CreateIndex(ctx, indexName, body); err != nil {
+		return fmt.Errorf("failed to create index: %w", err)
+	}
+	return e.client.UpdateAliases(ctx, []es.AliasAction{
+		{Add: &es.AliasTarget{Index: indexName, Alias: def.Name}},
+	})
+}
+
+// reindex creates the next <alias>_vN index of def, lets fill queue every
+// document and swaps the alias once the count matches what fill expected
+func (e *EsService) reindex(ctx context.Context, def es.IndexDefinition, fill func(ctx context.Context, indexer es.BulkIndexer) (int64, error)) (*ReindexReport, error) {
+	log := logger.WithCtx(ctx, "esService.reindex")
+	alias := def.Name
+
+	previous, err := e.client.GetAlias(ctx, alias)
+	if err != nil {
+		return nil, err
+	}
+
+	// Indices created before aliases were introduced carry the alias name
+	legacy := false
+	if len(previous) == 0 {
+		legacy, err = e.client.IndexExists(ctx, alias)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check index existence: %w", err)
+		}
+	}
+
+	versions, err := e.client.ListIndices(ctx, alias+indexVersionSep+"*")
+	if err != nil {
+		return nil, err
+	}
+	next := 1
+	for _, index := range versions {
+		if v := parseIndexVersion(alias, index); v >= next {
+			next = v + 1
+		}
+	}
+	newIndex := indexVersion(alias, next)
+
+	body, err := def.Body()
+	if err != nil {
+		return nil, err
+	}
+	if err := e.client.CreateIndex(ctx, newIndex, body); err != nil {
+		return nil, fmt.Errorf("failed to create index %s: %w", newIndex, err)
+	}
+	log.Infof("filling %s from postgres", newIndex)
+
+	indexer, err := e.client.NewBulkIndexer(ctx, es.BulkIndexerConfig{Index: newIndex})
+	if err != nil {
+		return nil, fmt.Errorf("failed to start bulk indexer: %w", err)
+	}
+	expected, err := fill(ctx, indexer)
+	if err != nil {
+		_, _ = indexer.Close(ctx)
+		return nil, err
+	}
+
+	bulk, err := indexer.Close(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to flush bulk indexer: %w", err)
+	}
+	if err := e.client.RefreshIndex(ctx, newIndex); err != nil {
+		return nil, err
+	}
+	count, err := e.client.Count(ctx, newIndex)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ReindexReport{
+		Alias:           alias,
+		NewIndex:        newIndex,
+		PreviousIndices: previous,
+		Expected:        expected,
+		Indexed:         count,
+		Failures:        bulk.Failures,
+	}
+	if count != expected {
+		log.Errorf("%s holds %d documents, expected %d, alias left unchanged", newIndex, count, expected)
+		return report, fmt.Errorf("reindex into %s incomplete: %d of %d documents indexed", newIndex, count, expected)
+	}
+
+	actions := []es.AliasAction{
+		{Add: &es.AliasTarget{Index: newIndex, Alias: alias}},
+	}
+	for _, index := range previous {
+		actions = append(actions, es.AliasAction{Remove: &es.AliasTarget{Index: index, Alias: alias}})
+	}
+	if legacy {
+		// The alias can't share its name with an index, drop the old one in the same step
+		actions = append(actions, es.AliasAction{RemoveIndex: &es.AliasTarget{Index: alias}})
+		report.PreviousIndices = []string{alias}
+	}
+	if err := e.client.UpdateAliases(ctx, actions); err != nil {
+		return report, fmt.Errorf("failed to swap alias to %s: %w", newIndex, err)
+	}
+
+	log.Infof("alias %s now points to %s", alias, newIndex)
+	return report, nil
+}
+
+// replayChanges syncs again whatever the outbox recorded since the reindex
+// started. Until the swap the alias pointed at the previous index, so those
+// changes missed the new one, and a legacy index is gone by now.
+func (e *EsService) replayChanges(ctx context.Context, report *ReindexReport, started time.Time, aggregates ...string) (*ReindexReport, error) {
+	events, err := e.repo.GetOutboxEventsSince(ctx, aggregates, started.Add(-reindexReplayMargin), nil)
+	if err != nil {
+		return report, fmt.Errorf("alias swapped to %s but reading the changes to replay failed: %w", report.NewIndex, err)
+	}
+
+	sync := NewEsSync(e)
+	synced := map[uuid.UUID]bool{}
+	for _, event := range events {
+		// a business is read back whole from postgres, once is enough
+		if event.Aggregate == model.OutboxAggregateBusiness {
+			if synced[event.AggregateID] {
+				continue
+			}
+			synced[event.AggregateID] = true
+		}
+		if err := sync.Deliver(ctx, event); err != nil {
+			return report, fmt.Errorf("alias swapped to %s but replaying event %s failed: %w", report.NewIndex, event.ID, err)
+		}
+		report.Replayed++
+	}
+	return report, nil
+}
+
+func indexVersion(alias string, v int) string {
+	return alias + indexVersionSep + strconv.Itoa(v)
+}
+
+// parseIndexVersion returns N for <alias>_vN, 0 for anything else
+func parseIndexVersion(alias, index string) int {
+	suffix := strings.TrimPrefix(index, alias+indexVersionSep)
+	if suffix == index {
+		return 0
+	}
+	v, err := strconv.Atoi(suffix)
+	if err != nil {
+		return 0
+	}
+	return v
+}
@@ -0,0 +1,217 @@
+package service
+
+import (
+	"business/pkg/es"
+	"business/pkg/es/esfake"
+	"business/pkg/model"
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestReindexBusiness(t *testing.T) {
+	ctx := context.Background()
+	businesses := []model.Business{testBusiness(1, "Cafe Sua"), testBusiness(2, "Tra Dao")}
+	gone := testBusiness(9, "Gone")
+
+	tests := []struct {
+		name         string
+		setup        func(t *testing.T, e *EsService, client *esfake.Client)
+		wantIndex    string
+		wantPrevious []string
+		// indices that must be gone after the swap
+		wantDeleted []string
+	}{
+		{
+			name:      "fresh cluster",
+			setup:     func(t *testing.T, e *EsService, client *esfake.Client) {},
+			wantIndex: "business_v1",
+		},
+		{
+			name: "alias swapped to the next version",
+			setup: func(t *testing.T, e *EsService, client *esfake.Client) {
+				if err := e.ensureBusinessIndex(ctx); err != nil {
+					t.Fatal(err)
+				}
+				if err := client.IndexDocument(ctx, businessAlias, gone.ID.String(), gone); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantIndex:    "business_v2",
+			wantPrevious: []string{"business_v1"},
+		},
+		{
+			name: "legacy index named like the alias",
+			setup: func(t *testing.T, e *EsService, client *esfake.Client) {
+				if _, err := e.putSynonyms(ctx); err != nil {
+					t.Fatal(err)
+				}
+				body, err := businessIndex().Body()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := client.CreateIndex(ctx, businessAlias, body); err != nil {
+					t.Fatal(err)
+				}
+				if err := client.IndexDocument(ctx, businessAlias, gone.ID.String(), gone); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantIndex:    "business_v1",
+			wantPrevious: []string{businessAlias},
+			wantDeleted:  []string{businessAlias},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := esfake.New()
+			e := NewEsService(&fakeRepo{businesses: businesses}, client, nil)
+			tt.setup(t, e, client)
+
+			report, err := e.ReindexBusiness(ctx)
+			if err != nil {
+				t.Fatalf("err = %v", err)
+			}
+			if report.NewIndex != tt.wantIndex || !slices.Equal(report.PreviousIndices, tt.wantPrevious) {
+				t.Errorf("new index %s from %v, want %s from %v", report.NewIndex, report.PreviousIndices, tt.wantIndex, tt.wantPrevious)
+			}
+			if report.Expected != 2 || report.Indexed != 2 {
+				t.Errorf("indexed %d of %d, want 2 of 2", report.Indexed, report.Expected)
+			}
+
+			aliased, err := client.GetAlias(ctx, businessAlias)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(aliased, []string{tt.wantIndex}) {
+				t.Errorf("alias points to %v, want %s", aliased, tt.wantIndex)
+			}
+			// previous versions stay for a rollback, a legacy index can't
+			indices, err := client.ListIndices(ctx, "*")
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, index := range tt.wantPrevious {
+				exists := slices.Contains(indices, index)
+				if want := !slices.Contains(tt.wantDeleted, index); exists != want {
+					t.Errorf("%s exists = %v, want %v", index, exists, want)
+				}
+			}
+
+			if _, err := client.GetDocument(ctx, businessAlias, gone.ID.String()); !errors.Is(err, es.ErrNotFound) {
+				t.Errorf("document of a business gone from postgres: err = %v, want not found", err)
+			}
+			for _, b := range businesses {
+				if _, err := client.GetDocument(ctx, businessAlias, b.ID.String()); err != nil {
+					t.Errorf("business %s: %v", b.ID, err)
+				}
+			}
+		})
+	}
+}
+
+func TestReindexBusinessReplaysChanges(t *testing.T) {
+	ctx := context.Background()
+	existing := testBusiness(1, "Cafe Sua")
+	created := testBusiness(2, "Tra Dao")
+	staff := model.Staff{ID: uuid.MustParse(fmtID(11)), Username: "lan", Email: "lan@example.com", Role: "admin", BusinessID: existing.ID}
+	staffPayload, err := json.Marshal(model.OutboxPayload{Staff: &staff})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rp := &fakeRepo{
+		businesses: []model.Business{existing},
+		events: []model.OutboxEvent{
+			// synced long before the reindex
+			{ID: uuid.New(), Aggregate: model.OutboxAggregateBusiness, AggregateID: existing.ID, CreateAt: time.Now().Add(-time.Hour)},
+		},
+	}
+	// a business and a staff written while the new index is being filled
+	rp.afterPage = func(r *fakeRepo) {
+		r.afterPage = nil
+		now := time.Now()
+		r.businesses = append(r.businesses, created)
+		r.staffs = append(r.staffs, staff)
+		r.events = append(r.events,
+			model.OutboxEvent{ID: uuid.New(), Aggregate: model.OutboxAggregateBusiness, AggregateID: created.ID, CreateAt: now},
+			model.OutboxEvent{ID: uuid.New(), Aggregate: model.OutboxAggregateBusiness, AggregateID: created.ID, CreateAt: now},
+			model.OutboxEvent{ID: uuid.New(), Aggregate: model.OutboxAggregateStaff, AggregateID: staff.ID, Payload: staffPayload, CreateAt: now},
+		)
+	}
+	client := esfake.New()
+	e := NewEsService(rp, client, nil)
+
+	report, err := e.ReindexBusiness(ctx)
+	if err != nil {
+		t.Fatalf("err = %v", err)
+	}
+	if report.Indexed != 1 {
+		t.Errorf("indexed %d, want the 1 business read before the write", report.Indexed)
+	}
+	// the created business once, however many events it has, and the staff
+	if report.Replayed != 2 {
+		t.Errorf("replayed %d events, want 2", report.Replayed)
+	}
+
+	if _, err := client.GetDocument(ctx, businessAlias, created.ID.String()); err != nil {
+		t.Errorf("business created during the reindex: %v", err)
+	}
+	if _, err := client.GetDocument(ctx, staffAlias, staff.ID.String()); err != nil {
+		t.Errorf("staff created during the reindex: %v", err)
+	}
+}
+
+func TestReindexBusinessWritesDuringFill(t *testing.T) {
+	ctx := context.Background()
+	// more than a page, so rows are written between two reads
+	var businesses []model.Business
+	for n := 10; n < 10+reindexPageSize+5; n++ {
+		businesses = append(businesses, testBusiness(n, "Cafe"))
+	}
+	deleted, inserted := businesses[0], testBusiness(1, "Tra Dao")
+
+	rp := &fakeRepo{businesses: businesses}
+	rp.afterPage = func(r *fakeRepo) {
+		r.afterPage = nil
+		now := time.Now()
+		r.businesses = append(slices.Clone(r.businesses[1:]), inserted)
+		r.events = append(r.events,
+			model.OutboxEvent{ID: uuid.New(), Aggregate: model.OutboxAggregateBusiness, AggregateID: deleted.ID, CreateAt: now},
+			model.OutboxEvent{ID: uuid.New(), Aggregate: model.OutboxAggregateBusiness, AggregateID: inserted.ID, CreateAt: now},
+		)
+	}
+	client := esfake.New()
+	e := NewEsService(rp, client, nil)
+
+	report, err := e.ReindexBusiness(ctx)
+	if err != nil {
+		t.Fatalf("err = %v", err)
+	}
+	if report.Indexed != report.Expected || report.Expected != int64(len(businesses)) {
+		t.Errorf("indexed %d of %d, want %d", report.Indexed, report.Expected, len(businesses))
+	}
+
+	count, err := client.Count(ctx, businessAlias)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the deleted business is gone and the inserted one replayed
+	if want := int64(len(businesses)); count != want {
+		t.Errorf("%d documents, want %d", count, want)
+	}
+	if _, err := client.GetDocument(ctx, businessAlias, deleted.ID.String()); !errors.Is(err, es.ErrNotFound) {
+		t.Errorf("deleted business: err = %v, want not found", err)
+	}
+	for _, b := range append(slices.Clone(businesses[1:]), inserted) {
+		if _, err := client.GetDocument(ctx, businessAlias, b.ID.String()); err != nil {
+			t.Fatalf("business %s: %v", b.ID, err)
+		}
+	}
+}
@@ -2,35 +2,60 @@ package service
 
 import (
 	"business/pkg/es"
+	"business/pkg/es/query"
 	"business/pkg/model"
 	"business/pkg/repo"
-	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
+	"slices"
 	"strings"
 
 	"github.com/google/uuid"
+	"gitlab.com/goxp/cloud0/ginext"
 	"gitlab.com/goxp/cloud0/logger"
 )
 
+const (
+	// ES rejects from+size above index.max_result_window
+	maxResultWindow = 10000
+	pitKeepAlive    = "1m"
+	// cursorKeepAlive is how long a next_cursor stays valid, each page
+	// extends it. Clients read a page before asking for the next one.
+	cursorKeepAlive = "5m"
+)
+
 type EsService struct {
-	client es.Client
-	repo repo.PGInterface
+	client  es.Client
+	repo    repo.PGInterface
+	ranking RankingResolver
 }
 
-func NewEsService( repo repo.PGInterface, client es.Client) *EsService {
-	return &EsService{repo: repo, client: client}
+func NewEsService( repo repo.PGInterface, client es.Client, ranking RankingResolver) *EsService {
+	return &EsService{repo: repo, client: client, ranking: ranking}
 }
 
 type EsInterface interface {
-	PushToEs(ctx context.Context, req *model.GetListBusinessRequest) (*model.Business, error) 
+	PushToEs(ctx context.Context, req *model.GetListBusinessRequest) (*es.BulkReport, error)
 	SearchWithField(ctx context.Context, req es.SearchRequest) (*model.GetListBusinessResponse, error)
 	FullTextSearch(ctx context.Context, req es.SearchRequest) (*es.SearchResult, error)
+	ReindexBusiness(ctx context.Context) (*ReindexReport, error)
+	SuggestBusiness(ctx context.Context, req es.SuggestRequest) ([]es.Suggestion, error)
+	MappingDrift(ctx context.Context) ([]*es.MappingPlan, error)
+	MigrateMappings(ctx context.Context, reindex bool) (*MigrationReport, error)
+	Reconcile(ctx context.Context, repair bool) (*ReconcileReport, error)
+	SearchStaff(ctx context.Context, req *model.GetListStaffRequest) (model.GetListStaffResponse, error)
+	ReindexStaff(ctx context.Context) (*ReindexReport, error)
+	UnifiedSearch(ctx context.Context, req es.UnifiedSearchRequest) (*es.UnifiedSearchResult, error)
+	SyncSynonyms(ctx context.Context) (*SynonymSyncReport, error)
 
 }
 
-func (e *EsService) PushToEs(ctx context.Context, req *model.GetListBusinessRequest) (*model.Business, error) {
+func (e *EsService) PushToEs(ctx context.Context, req *model.GetListBusinessRequest) (*es.BulkReport, error) {
+	log := logger.WithCtx(ctx, "esService.PushToEs")
+
 	// Lấy toàn bộ business từ repo
 	businesses, err := e.repo.GetListBusiness(ctx,req,nil)
 	if err != nil {
@@ -38,107 +63,36 @@ func (e *EsService) PushToEs(ctx context.Context, req *model.GetListBusinessRequ
 	}
 
 	// Tạo index nếu chưa có
-	indexName := "business"
-	
-	// Check if index exists first
-	exists, err := e.client.IndexExists(ctx, indexName)
-	if err != nil {
-		return nil, fmt.Errorf("failed to check index existence: %w", err)
-	}
-	
-	if !exists {
-		mapping := map[string]interface{}{
-			"mappings": map[string]interface{}{
-				"properties": map[string]interface{}{
-					"id": map[string]string{
-						"type": "keyword",
-					},
-					"name": map[string]string{
-						"type": "text",
-					},
-					"description": map[string]string{
-						"type": "text",
-					},
-					"address": map[string]string{
-						"type": "text",
-					},
-					"businessType": map[string]string{
-						"type": "keyword",
-					},
-					"status": map[string]string{
-						"type": "keyword",
-					},
-					"createAt": map[string]string{
-						"type": "date",
-					},
-					"workerName": map[string]string{
-						"type": "text",
-					},
-					"staffs": map[string]interface{}{
-						"type": "nested",
-						"properties": map[string]interface{}{
-							"id": map[string]string{
-								"type": "keyword",
-							},
-							"name": map[string]string{
-								"type": "text",
-							},
-							"role": map[string]string{
-								"type": "keyword",
-							},
-						},
-					},
-				},
-			},
-		}
-		if err := e.client.CreateIndex(ctx, indexName, mapping); err != nil {
-			return nil, fmt.Errorf("failed to create index: %w", err)
-		}
+	indexName := businessAlias
+	if err := e.ensureBusinessIndex(ctx); err != nil {
+		return nil, err
+	}
+
+	indexer, err := e.client.NewBulkIndexer(ctx, es.BulkIndexerConfig{Index: indexName})
+	if err != nil {
+		return nil, fmt.Errorf("failed to start bulk indexer: %w", err)
 	}
 
 	for _, b := range businesses.Data {
-		if err := e.client.IndexDocument(ctx, indexName, b.ID.String(), b); err != nil {
-			return nil, err
+		if err := indexer.Add(ctx, es.BulkDocument{ID: b.ID.String(), Data: b}); err != nil {
+			_, _ = indexer.Close(ctx)
+			return nil, fmt.Errorf("failed to queue business %s: %w", b.ID, err)
 		}
 	}
 
-	if len(businesses.Data) > 0 {
-		return &businesses.Data[0], nil
+	report, err := indexer.Close(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to flush bulk indexer: %w", err)
+	}
+	if report.Failed > 0 {
+		log.Warnf("%d of %d businesses were not indexed", report.Failed, len(businesses.Data))
 	}
-	return nil, nil
+
+	return report, nil
 }
 
 func (e* EsService) SearchWithField(ctx context.Context, req es.SearchRequest) (*model.GetListBusinessResponse, error) {
-	log:= logger.WithCtx(ctx, "esService.SearchWithField")
-	from := (req.Page - 1) * req.Size
-	data, _ := json.Marshal(req.Filters)
-	var filterMap map[string]interface{}
-	err := json.Unmarshal(data, &filterMap)
-	if(err!= nil) {
-		log.WithError(err).Error("error in marshal")
-	}	
-	mustQueries := make([]map[string]interface{}, 0)
-	for field, value := range filterMap {
-		if v, ok := value.(string); ok && strings.TrimSpace(v) != "" {
-			mustQueries = append(mustQueries, map[string]interface{}{
-				"match": map[string]interface{}{
-					field: v,
-				},
-			})
-		}
-	}
-
-	query := map[string]interface{}{
-		"from": from,
-		"size": req.Size,
-		"query": map[string]interface{}{
-			"bool": map[string]interface{}{
-				"must": mustQueries,
-			},
-		},
-	}
-
-	result, err := e.client.Search(ctx, req.Index, query)
+	result, err := e.search(ctx, req, buildFieldSearch)
 	if err != nil {
 		return nil, fmt.Errorf("failed to search with filters: %w", err)
 	}
@@ -161,92 +115,406 @@ func (e* EsService) SearchWithField(ctx context.Context, req es.SearchRequest) (
 			"total": result.Hits.Total.Value,
 			"page":  req.Page,
 			"size":  req.Size,
+			"next_cursor":    result.NextCursor,
+			"facets":         result.Facets,
+			"fuzzy_fallback": result.FuzzyFallback,
 		},
 	}
 
 	return resp, nil
 }
 
+// FullTextSearch ranks the hits with the profile named in the request, or
+// the default one
 func (e *EsService) FullTextSearch(ctx context.Context, req es.SearchRequest) (*es.SearchResult, error) {
-    from := (req.Page - 1) * req.Size
-    if from < 0 {
-        from = 0
-    }
-
-    // Convert Filters (struct) → map
-    data, _ := json.Marshal(req.Filters)
-    var filterMap map[string]interface{}
-    _ = json.Unmarshal(data, &filterMap)
-
-    mustQueries := make([]map[string]interface{}, 0)
-
-    // Exact match filters
-    for field, value := range filterMap {
-        if str, ok := value.(string); ok && strings.TrimSpace(str) != "" {
-            mustQueries = append(mustQueries, map[string]interface{}{
-                "match": map[string]interface{}{
-                    field: str,
-                },
-            })
-        }
-    }
-
-    // Multi-field full-text search (tất cả string filters đều dùng multi_match)
-    multiFields := []string{}
-    multiQuery := ""
-    for field, value := range filterMap {
-        if str, ok := value.(string); ok && strings.TrimSpace(str) != "" {
-            multiFields = append(multiFields, field)
-            multiQuery = str
-        }
-    }
-    if len(multiFields) > 0 && multiQuery != "" {
-        mustQueries = append(mustQueries, map[string]interface{}{
-            "multi_match": map[string]interface{}{
-                "query":  multiQuery,
-                "fields": multiFields,
-            },
-        })
-    }
-
-    // Build sort
-    sortQuery := []map[string]interface{}{}
-    if req.Sort != "" {
-        parts := strings.Split(req.Sort, ":")
-        if len(parts) == 2 {
-            sortQuery = append(sortQuery, map[string]interface{}{
-                parts[0]: map[string]string{"order": parts[1]},
-            })
-        }
-    }
-
-    // Build final query
-    query := map[string]interface{}{
-        "from": from,
-        "size": req.Size,
-        "query": map[string]interface{}{
-            "bool": map[string]interface{}{
-                "must": mustQueries,
-            },
-        },
-    }
-    if len(sortQuery) > 0 {
-        query["sort"] = sortQuery
-    }
-    if len(req.Source) > 0 {
-        query["_source"] = req.Source
-    }
-
-    // Call ES
-    var buf bytes.Buffer
-    if err := json.NewEncoder(&buf).Encode(query); err != nil {
-        return nil, fmt.Errorf("encode query failed: %w", err)
-    }
-
-	result, err := e.client.Search(ctx, req.Index, query)
+	req, err := withCursorState(req)
+	if err != nil {
+		return nil, err
+	}
+	profile, err := e.ranking.ResolveRankingProfile(ctx, req.RankingProfile)
+	if err != nil {
+		return nil, err
+	}
+	req.RankingProfile = ""
+	if profile != nil {
+		// later pages are ranked with the profile of the first one
+		req.RankingProfile = profile.Name
+	}
+
+	result, err := e.search(ctx, req, func(req es.SearchRequest) (*query.SearchSource, error) {
+		return buildFullTextSearch(req, profile)
+	})
 	if err != nil {
 		return nil, fmt.Errorf("full-text search failed: %w", err)
 	}
+	result.RankingProfile = req.RankingProfile
+
+	return result, nil
+}
+
+// buildFieldSearch matches every filled filter on its own field, sorted as
+// the request asks
+func buildFieldSearch(req es.SearchRequest) (*query.SearchSource, error) {
+	filterQueries, err := businessFilterQueries(req.Filters, req.Fuzzy)
+	if err != nil {
+		return nil, err
+	}
+
+	sorts, err := businessSort(req.Sort)
+	if err != nil {
+		return nil, err
+	}
+
+	search := query.NewSearch().
+		Query(query.Bool().Must(filterQueries...)).
+		Sort(sorts...)
+	if err := applyFacets(req, search); err != nil {
+		return nil, err
+	}
+	return search, nil
+}
+
+// buildFullTextSearch matches the filters and runs a multi_match over them,
+// with sort, _source, facets and highlight taken from the request. A ranking
+// profile boosts the multi_match fields and rescores the hits.
+func buildFullTextSearch(req es.SearchRequest, profile *model.RankingProfile) (*query.SearchSource, error) {
+	filters := businessFilterValues(req.Filters)
+
+	// Exact match filters
+	filterQueries, err := businessFilterQueries(req.Filters, req.Fuzzy)
+	if err != nil {
+		return nil, err
+	}
+	boolQuery := query.Bool().Must(filterQueries...)
+
+	// Multi-field full-text search over every field that was filled
+	if len(filters) > 0 {
+		fields := make([]string, 0, len(filters))
+		for _, f := range filters {
+			fields = append(fields, f.field)
+		}
+		var boosts model.Weights
+		if profile != nil {
+			boosts = profile.FieldBoosts
+		}
+		multiMatch := query.MultiMatch(filters[0].value, rankedFields(fields, boosts)...)
+		if req.Fuzzy != nil {
+			applyFuzzy(multiMatch, *req.Fuzzy)
+		}
+		boolQuery.Must(multiMatch)
+	}
+
+	search := query.NewSearch().Query(rankedQuery(boolQuery, profile))
+
+	sorts, err := businessSort(req.Sort)
+	if err != nil {
+		return nil, err
+	}
+	search.Sort(sorts...)
+	if len(req.Source) > 0 {
+		search.FetchSource(req.Source...)
+	}
+	if err := applyFacets(req, search); err != nil {
+		return nil, err
+	}
+	if req.Highlight != nil {
+		highlight, err := buildHighlight(*req.Highlight)
+		if err != nil {
+			return nil, err
+		}
+		search.Highlight(highlight)
+	}
+	return search, nil
+}
+
+// search runs the query made by build. When a strict query finds nothing it
+// runs once more with AUTO fuzziness, unless the caller opted out.
+func (e *EsService) search(ctx context.Context, req es.SearchRequest, build func(es.SearchRequest) (*query.SearchSource, error)) (*es.SearchResult, error) {
+	req, err := withCursorState(req)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := e.searchOnce(ctx, req, build)
+	if err != nil {
+		return nil, err
+	}
+
+	if result.Hits.Total.Value == 0 && req.Fuzzy == nil && req.Cursor == "" &&
+		!req.DisableFuzzyFallback && hasTextFilter(req.Filters) {
+		logger.WithCtx(ctx, "esService.search").Info("no strict match, retrying with fuzziness")
+		req.Fuzzy = &es.FuzzySpec{Fuzziness: defaultFuzziness}
+		if result, err = e.searchOnce(ctx, req, build); err != nil {
+			return nil, err
+		}
+		result.FuzzyFallback = true
+	}
+
+	return result, nil
+}
+
+func (e *EsService) searchOnce(ctx context.Context, req es.SearchRequest, build func(es.SearchRequest) (*query.SearchSource, error)) (*es.SearchResult, error) {
+	search, err := build(req)
+	if err != nil {
+		return nil, err
+	}
+
+	pitID, err := e.applyPaging(ctx, req, search)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := e.client.Search(ctx, searchIndex(req, pitID), search)
+	if err == nil {
+		result.Facets, err = decodeFacets(result.Aggregations)
+	}
+	if err != nil {
+		// a point in time opened for this page is of no use to anyone, one
+		// from a cursor stays open so the client can retry that page
+		if pitID != "" && req.Cursor == "" {
+			e.closePointInTime(ctx, pitID)
+		}
+		// a cursor search names no index, not found means its point in time expired
+		if req.Cursor != "" && errors.Is(err, es.ErrNotFound) {
+			return nil, ginext.NewError(http.StatusBadRequest, "cursor expired, start again without a cursor")
+		}
+		return nil, err
+	}
+	result.NextCursor = e.nextCursor(ctx, req, pitID, result)
 
 	return result, nil
 }
+
+type filterValue struct {
+	field string
+	value string
+}
+
+// businessFilterValues lists the filled filters in a fixed order, field names
+// follow the json of the indexed model.Business
+func businessFilterValues(f es.BusinessFilter) []filterValue {
+	all := []filterValue{
+		{field: fieldName, value: f.Name},
+		{field: fieldDescription, value: f.Description},
+		{field: fieldAddress, value: f.Address},
+		{field: fieldBusinessType, value: f.BusinessType},
+		{field: fieldStatus, value: f.Status},
+	}
+
+	values := make([]filterValue, 0, len(all))
+	for _, v := range all {
+		if strings.TrimSpace(v.value) != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// businessFilterQueries turns every filled filter into a match clause,
+// text fields also match their exact subfield to rank diacritics first.
+// Fuzziness only applies to the text fields, type and status stay exact.
+func businessFilterQueries(f es.BusinessFilter, fuzzy *es.FuzzySpec) ([]query.Query, error) {
+	if fuzzy != nil {
+		if err := validateFuzzy(*fuzzy); err != nil {
+			return nil, err
+		}
+	}
+
+	values := businessFilterValues(f)
+	queries := make([]query.Query, 0, len(values))
+	for _, v := range values {
+		if viTextFields[v.field] {
+			q := query.MultiMatch(v.value, withExactFields([]string{v.field})...).Type("most_fields")
+			if fuzzy != nil {
+				applyFuzzy(q, *fuzzy)
+			}
+			queries = append(queries, q)
+			continue
+		}
+		queries = append(queries, query.Match(v.field, v.value))
+	}
+	return queries, nil
+}
+
+func hasTextFilter(f es.BusinessFilter) bool {
+	for _, v := range businessFilterValues(f) {
+		if viTextFields[v.field] {
+			return true
+		}
+	}
+	return false
+}
+
+// highlightFields are the text fields fragments can be taken from
+var highlightFields = map[string]bool{
+	fieldName:        true,
+	fieldDescription: true,
+	fieldAddress:     true,
+}
+
+// buildHighlight validates the highlight block of a search request
+func buildHighlight(spec es.HighlightSpec) (*query.Highlight, error) {
+	fields := spec.Fields
+	if len(fields) == 0 {
+		fields = []string{fieldDescription, fieldAddress}
+	}
+	for _, f := range fields {
+		if !highlightFields[f] {
+			return nil, ginext.NewError(http.StatusBadRequest, fmt.Sprintf("field %q can't be highlighted", f))
+		}
+	}
+	if len(spec.PreTags) != len(spec.PostTags) {
+		return nil, ginext.NewError(http.StatusBadRequest, "pre_tags and post_tags must have the same length")
+	}
+
+	highlight := query.NewHighlight(fields...).Tags(spec.PreTags, spec.PostTags)
+	if spec.FragmentSize > 0 {
+		highlight.FragmentSize(spec.FragmentSize)
+	}
+	if spec.NumberOfFragments != nil {
+		if *spec.NumberOfFragments < 0 {
+			return nil, ginext.NewError(http.StatusBadRequest, "number_of_fragments must not be negative")
+		}
+		highlight.NumberOfFragments(*spec.NumberOfFragments)
+	}
+	return highlight, nil
+}
+
+// applyPaging sets either from/size or pit/search_after on the search.
+// It returns the point in time id when cursor paging is used.
+func (e *EsService) applyPaging(ctx context.Context, req es.SearchRequest, search *query.SearchSource) (string, error) {
+	if req.Cursor == "" && !req.UseCursor {
+		from := (req.Page - 1) * req.Size
+		if from < 0 {
+			from = 0
+		}
+		if from+req.Size > maxResultWindow {
+			return "", ginext.NewError(http.StatusBadRequest, fmt.Sprintf("page is beyond the first %d results, use cursor paging instead", maxResultWindow))
+		}
+		search.From(from).Size(req.Size)
+		return "", nil
+	}
+
+	var pitID string
+	var searchAfter []json.RawMessage
+	if req.Cursor != "" {
+		cursor, err := es.DecodeCursor(req.Cursor)
+		if err != nil {
+			return "", ginext.NewError(http.StatusBadRequest, err.Error())
+		}
+		pitID = cursor.PitID
+		searchAfter = cursor.SearchAfter
+		search.SearchAfter(searchAfter)
+	} else {
+		id, err := e.client.OpenPointInTime(ctx, req.Index, cursorKeepAlive)
+		if err != nil {
+			return "", fmt.Errorf("failed to open point in time: %w", err)
+		}
+		pitID = id
+	}
+
+	search.Size(req.Size).PointInTime(pitID, cursorKeepAlive)
+
+	// _shard_doc breaks ties so search_after never skips or repeats a hit
+	if !search.HasSort() {
+		search.Sort(query.SortBy("_score", "desc"))
+	}
+	search.Sort(query.SortBy("_shard_doc", "asc"))
+
+	// a cursor made for other sort fields, as those from before cursors kept
+	// their sort, can't continue this search
+	if searchAfter != nil && len(searchAfter) != len(search.Sorts()) {
+		return "", ginext.NewError(http.StatusBadRequest, "cursor doesn't match the sort of this search, start again without a cursor")
+	}
+
+	return pitID, nil
+}
+
+// nextCursor builds the token for the following page and closes the point
+// in time once the last page has been served
+func (e *EsService) nextCursor(ctx context.Context, req es.SearchRequest, pitID string, result *es.SearchResult) string {
+	if pitID == "" {
+		return ""
+	}
+	log := logger.WithCtx(ctx, "esService.nextCursor")
+
+	// ES may hand back a newer pit id, always continue with the latest one
+	if result.PitID != "" {
+		pitID = result.PitID
+	}
+
+	hits := result.Hits.Hits
+	if len(hits) < req.Size {
+		e.closePointInTime(ctx, pitID)
+		return ""
+	}
+
+	token, err := es.EncodeCursor(es.Cursor{
+		PitID:          pitID,
+		SearchAfter:    hits[len(hits)-1].Sort,
+		Fuzzy:          req.Fuzzy,
+		RankingProfile: req.RankingProfile,
+		Sort:           req.Sort,
+	})
+	if err != nil {
+		log.WithError(err).Error("failed to encode cursor")
+		return ""
+	}
+	return token
+}
+
+// closePointInTime releases a point in time before its keep alive runs out,
+// even when the search that used it was cancelled
+func (e *EsService) closePointInTime(ctx context.Context, pitID string) {
+	if err := e.client.ClosePointInTime(context.WithoutCancel(ctx), pitID); err != nil {
+		logger.WithCtx(ctx, "esService.closePointInTime").WithError(err).Warn("failed to close point in time")
+	}
+}
+
+// withCursorState restores what later pages must keep from the first one:
+// the fuzziness it was found with, the profile it was ranked with and its
+// sort. A later page can't ask for another sort, search_after would not fit.
+func withCursorState(req es.SearchRequest) (es.SearchRequest, error) {
+	if req.Cursor == "" {
+		return req, nil
+	}
+	cursor, err := es.DecodeCursor(req.Cursor)
+	if err != nil {
+		// applyPaging rejects the cursor
+		return req, nil
+	}
+	if req.Fuzzy == nil {
+		req.Fuzzy = cursor.Fuzzy
+	}
+	if req.RankingProfile == "" {
+		req.RankingProfile = cursor.RankingProfile
+	}
+	switch {
+	case len(req.Sort) == 0:
+		req.Sort = cursor.Sort
+	case !slices.Equal(req.Sort, cursor.Sort):
+		return req, ginext.NewError(http.StatusBadRequest, "sort can't change between cursor pages, start again without a cursor")
+	}
+	return req, nil
+}
+
+// searchIndex returns the index to search, point in time searches carry
+// the index inside the pit and must not name one
+func searchIndex(req es.SearchRequest, pitID string) string {
+	if pitID != "" {
+		return ""
+	}
+	return req.Index
+}
+
+// businessIndex defines every business_vN index, the mapping comes from
+// the es tags of model.Business
+func businessIndex() es.IndexDefinition {
+	return es.IndexDefinition{
+		Name: businessAlias,
+		Settings: map[string]interface{}{
+			"analysis": businessAnalysis(),
+		},
+		Model: model.Business{},
+	}
+}
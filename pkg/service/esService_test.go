@@ -0,0 +1,351 @@
+package service
+
+import (
+	"business/pkg/es"
+	"business/pkg/es/esfake"
+	"business/pkg/model"
+	"business/pkg/repo"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"gitlab.com/goxp/cloud0/ginext"
+	"gorm.io/gorm"
+)
+
+// fakeRepo serves businesses, staffs, synonym sets and outbox events from
+// memory. Other methods panic on the nil embedded interface.
+type fakeRepo struct {
+	repo.PGInterface
+	businesses []model.Business
+	staffs     []model.Staff
+	synonyms   []model.SynonymSet
+	events     []model.OutboxEvent
+	// afterPage runs once a page of businesses was read, as a write made
+	// while a reindex or a reconcile goes on
+	afterPage func(r *fakeRepo)
+}
+
+func (r *fakeRepo) GetAllSynonymSets(ctx context.Context, tx *gorm.DB) ([]model.SynonymSet, error) {
+	return r.synonyms, nil
+}
+
+func (r *fakeRepo) GetOneBusiness(ctx context.Context, businessID uuid.UUID, tx *gorm.DB) (*model.Business, error) {
+	for i := range r.businesses {
+		if r.businesses[i].ID == businessID {
+			b := r.businesses[i]
+			return &b, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *fakeRepo) GetOneBusiness_v2(ctx context.Context, businessID uuid.UUID, tx *gorm.DB) (*model.Business, error) {
+	return r.GetOneBusiness(ctx, businessID, tx)
+}
+
+func (r *fakeRepo) GetBusinessPageAfter(ctx context.Context, afterID uuid.UUID, limit int, tx *gorm.DB) ([]model.Business, error) {
+	var page []model.Business
+	for _, b := range r.sortedBusinesses() {
+		if b.ID.String() > afterID.String() && len(page) < limit {
+			page = append(page, b)
+		}
+	}
+	if r.afterPage != nil {
+		r.afterPage(r)
+	}
+	return page, nil
+}
+
+func (r *fakeRepo) GetOneStaff(ctx context.Context, staffID uuid.UUID, tx *gorm.DB) (*model.Staff, error) {
+	for i := range r.staffs {
+		if r.staffs[i].ID == staffID {
+			s := r.staffs[i]
+			return &s, nil
+		}
+	}
+	return nil, ginext.NewError(http.StatusNotFound, "staff not found")
+}
+
+func (r *fakeRepo) GetStaffPageAfter(ctx context.Context, afterID uuid.UUID, limit int, tx *gorm.DB) ([]model.Staff, error) {
+	var page []model.Staff
+	for _, s := range r.staffs {
+		if s.ID.String() > afterID.String() && len(page) < limit {
+			page = append(page, s)
+		}
+	}
+	return page, nil
+}
+
+func (r *fakeRepo) GetOutboxEventsSince(ctx context.Context, aggregates []string, since time.Time, tx *gorm.DB) ([]model.OutboxEvent, error) {
+	var events []model.OutboxEvent
+	for _, event := range r.events {
+		for _, aggregate := range aggregates {
+			if event.Aggregate == aggregate && !event.CreateAt.Before(since) {
+				events = append(events, event)
+			}
+		}
+	}
+	return events, nil
+}
+
+func (r *fakeRepo) sortedBusinesses() []model.Business {
+	sorted := append([]model.Business{}, r.businesses...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].ID.String() < sorted[j].ID.String() })
+	return sorted
+}
+
+// testBusiness is an active business created on the given day of 2024,
+// its ID sorts by n
+func testBusiness(n int, name string) model.Business {
+	return model.Business{
+		ID:           uuid.MustParse(fmtID(n)),
+		Name:         name,
+		BusinessType: "cafe",
+		Status:       "active",
+		CreateAt:     time.Date(2024, 1, n, 0, 0, 0, 0, time.UTC),
+	}
+}
+
+func fmtID(n int) string {
+	return fmt.Sprintf("00000000-0000-0000-0000-%012d", n)
+}
+
+// newTestEsService returns a service over an in-memory cluster whose
+// business index holds docs, and a repo holding businesses
+func newTestEsService(t *testing.T, businesses []model.Business, docs ...model.Business) (*EsService, *esfake.Client, *fakeRepo) {
+	t.Helper()
+	ctx := context.Background()
+	client := esfake.New()
+	rp := &fakeRepo{businesses: businesses}
+	e := NewEsService(rp, client, nil)
+	if err := e.ensureBusinessIndex(ctx); err != nil {
+		t.Fatalf("ensureBusinessIndex: %v", err)
+	}
+	for _, d := range docs {
+		if err := client.IndexDocument(ctx, businessAlias, d.ID.String(), d); err != nil {
+			t.Fatalf("IndexDocument: %v", err)
+		}
+	}
+	return e, client, rp
+}
+
+func hitIDs(rs *model.GetListBusinessResponse) []string {
+	ids := make([]string, 0, len(rs.Data))
+	for _, b := range rs.Data {
+		ids = append(ids, b.ID.String())
+	}
+	return ids
+}
+
+func TestSearchWithFieldPaging(t *testing.T) {
+	docs := []model.Business{
+		testBusiness(1, "Cafe Sua"), testBusiness(2, "Cafe Den"), testBusiness(3, "Tra Sua"),
+	}
+	byCreated := es.SortSpec{{Field: fieldCreatedAt, Order: sortDesc}}
+
+	tests := []struct {
+		name      string
+		req       es.SearchRequest
+		wantIDs   []string
+		wantTotal int64
+		wantCode  int
+	}{
+		{
+			name:      "first page",
+			req:       es.SearchRequest{Page: 1, Size: 2, Sort: byCreated},
+			wantIDs:   []string{fmtID(3), fmtID(2)},
+			wantTotal: 3,
+		},
+		{
+			name:      "second page",
+			req:       es.SearchRequest{Page: 2, Size: 2, Sort: byCreated},
+			wantIDs:   []string{fmtID(1)},
+			wantTotal: 3,
+		},
+		{
+			name:      "page past the end",
+			req:       es.SearchRequest{Page: 3, Size: 2, Sort: byCreated},
+			wantIDs:   []string{},
+			wantTotal: 3,
+		},
+		{
+			name:      "filtered",
+			req:       es.SearchRequest{Page: 1, Size: 10, Filters: es.BusinessFilter{Name: "sua"}, Sort: byCreated},
+			wantIDs:   []string{fmtID(3), fmtID(1)},
+			wantTotal: 2,
+		},
+		{
+			name:     "beyond the result window",
+			req:      es.SearchRequest{Page: 1001, Size: 10},
+			wantCode: http.StatusBadRequest,
+		},
+	}
+
+	e, _, _ := newTestEsService(t, nil, docs...)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.req.Index = businessAlias
+			rs, err := e.SearchWithField(context.Background(), tt.req)
+			if tt.wantCode != 0 {
+				if code := statusOf(err); code != tt.wantCode {
+					t.Fatalf("status = %d, want %d (err %v)", code, tt.wantCode, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("err = %v", err)
+			}
+			if got := hitIDs(rs); !slices.Equal(got, tt.wantIDs) {
+				t.Errorf("hits = %v, want %v", got, tt.wantIDs)
+			}
+			if total := rs.Meta["total"]; total != tt.wantTotal {
+				t.Errorf("total = %v, want %d", total, tt.wantTotal)
+			}
+			if cursor := rs.Meta["next_cursor"]; cursor != "" {
+				t.Errorf("next_cursor = %q without cursor paging", cursor)
+			}
+		})
+	}
+}
+
+func TestSearchWithFieldCursor(t *testing.T) {
+	ctx := context.Background()
+	docs := []model.Business{
+		testBusiness(1, "Cafe Sua"), testBusiness(2, "Cafe Den"), testBusiness(3, "Tra Sua"),
+	}
+	e, client, _ := newTestEsService(t, nil, docs...)
+	byCreated := es.SortSpec{{Field: fieldCreatedAt, Order: sortAsc}}
+
+	// walk every page, the sort is only given on the first one
+	req := es.SearchRequest{Index: businessAlias, Size: 2, UseCursor: true, Sort: byCreated}
+	var pages [][]string
+	for {
+		rs, err := e.SearchWithField(ctx, req)
+		if err != nil {
+			t.Fatalf("page %d: %v", len(pages)+1, err)
+		}
+		pages = append(pages, hitIDs(rs))
+		cursor, _ := rs.Meta["next_cursor"].(string)
+		if cursor == "" {
+			break
+		}
+		if len(pages) > 3 {
+			t.Fatal("cursor paging never ends")
+		}
+		req = es.SearchRequest{Index: businessAlias, Size: 2, Cursor: cursor}
+	}
+	want := [][]string{{fmtID(1), fmtID(2)}, {fmtID(3)}}
+	if len(pages) != len(want) {
+		t.Fatalf("pages = %v, want %v", pages, want)
+	}
+	for i := range want {
+		if !slices.Equal(pages[i], want[i]) {
+			t.Errorf("page %d = %v, want %v", i+1, pages[i], want[i])
+		}
+	}
+
+	first, err := e.SearchWithField(ctx, es.SearchRequest{Index: businessAlias, Size: 2, UseCursor: true, Sort: byCreated})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cursor := first.Meta["next_cursor"].(string)
+	shortAfter, err := es.EncodeCursor(es.Cursor{PitID: "fake-pit-1", SearchAfter: []json.RawMessage{json.RawMessage(`1`)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the cluster dropped the point in time of this cursor
+	expired, err := e.SearchWithField(ctx, es.SearchRequest{Index: businessAlias, Size: 2, UseCursor: true, Sort: byCreated})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expiredCursor := expired.Meta["next_cursor"].(string)
+	state, err := es.DecodeCursor(expiredCursor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.ClosePointInTime(ctx, state.PitID); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		req  es.SearchRequest
+	}{
+		{name: "expired", req: es.SearchRequest{Cursor: expiredCursor, Size: 2}},
+		{name: "sort changed", req: es.SearchRequest{Cursor: cursor, Size: 2, Sort: es.SortSpec{{Field: fieldCreatedAt, Order: sortDesc}}}},
+		{name: "search_after of another sort", req: es.SearchRequest{Cursor: shortAfter, Size: 2}},
+		{name: "not a cursor", req: es.SearchRequest{Cursor: "not-a-cursor", Size: 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.req.Index = businessAlias
+			_, err := e.SearchWithField(ctx, tt.req)
+			if code := statusOf(err); code != http.StatusBadRequest {
+				t.Fatalf("status = %d, want 400 (err %v)", code, err)
+			}
+		})
+	}
+}
+
+func TestSearchWithFieldFuzzyFallback(t *testing.T) {
+	docs := []model.Business{testBusiness(1, "Cafe Sua"), testBusiness(2, "Tra Dao")}
+
+	tests := []struct {
+		name         string
+		req          es.SearchRequest
+		wantIDs      []string
+		wantFallback bool
+	}{
+		{
+			name:    "strict match",
+			req:     es.SearchRequest{Filters: es.BusinessFilter{Name: "cafe"}},
+			wantIDs: []string{fmtID(1)},
+		},
+		{
+			name:         "typo retried with fuzziness",
+			req:          es.SearchRequest{Filters: es.BusinessFilter{Name: "cofe"}},
+			wantIDs:      []string{fmtID(1)},
+			wantFallback: true,
+		},
+		{
+			name:    "fallback disabled",
+			req:     es.SearchRequest{Filters: es.BusinessFilter{Name: "cofe"}, DisableFuzzyFallback: true},
+			wantIDs: []string{},
+		},
+		{
+			name:    "explicit fuzziness is not a fallback",
+			req:     es.SearchRequest{Filters: es.BusinessFilter{Name: "cofe"}, Fuzzy: &es.FuzzySpec{Fuzziness: "1"}},
+			wantIDs: []string{fmtID(1)},
+		},
+		{
+			name:    "exact fields are never retried",
+			req:     es.SearchRequest{Filters: es.BusinessFilter{Status: "actve"}},
+			wantIDs: []string{},
+		},
+	}
+
+	e, _, _ := newTestEsService(t, nil, docs...)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.req.Index, tt.req.Page, tt.req.Size = businessAlias, 1, 10
+			rs, err := e.SearchWithField(context.Background(), tt.req)
+			if err != nil {
+				t.Fatalf("err = %v", err)
+			}
+			if got := hitIDs(rs); !slices.Equal(got, tt.wantIDs) {
+				t.Errorf("hits = %v, want %v", got, tt.wantIDs)
+			}
+			if fallback := rs.Meta["fuzzy_fallback"]; fallback != tt.wantFallback {
+				t.Errorf("fuzzy_fallback = %v, want %v", fallback, tt.wantFallback)
+			}
+		})
+	}
+}
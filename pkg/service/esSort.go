@@ -0,0 +1,135 @@
+package service
+
+import (
+	"business/pkg/es"
+	"business/pkg/es/query"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"gitlab.com/goxp/cloud0/ginext"
+)
+
+const (
+	sortScore = "_score"
+	sortAsc   = "asc"
+	sortDesc  = "desc"
+)
+
+var (
+	sortMissing = map[string]bool{"_first": true, "_last": true}
+	sortModes   = map[string]bool{"min": true, "max": true, "sum": true, "avg": true, "median": true}
+	// sum, avg and median only make sense on numbers
+	numericSortModes = map[string]bool{"sum": true, "avg": true, "median": true}
+)
+
+// businessSortFields are the sortable fields of the business mapping, derived
+// once since the mapping only changes with the model
+var businessSortFields = sync.OnceValues(func() (map[string]es.SortableField, error) {
+	return businessIndex().SortableFields()
+})
+
+// businessSort validates the requested sort against the business mapping
+func businessSort(spec es.SortSpec) ([]query.Sort, error) {
+	sortable, err := businessSortFields()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sortable fields: %w", err)
+	}
+	return buildSort(spec, sortable, fieldID)
+}
+
+// buildSort turns the requested fields into sort clauses. Every field must be
+// sortable in the mapping; _score is added when missing and tiebreaker goes
+// last so hits with equal values keep a stable order between pages.
+func buildSort(spec es.SortSpec, sortable map[string]es.SortableField, tiebreaker string) ([]query.Sort, error) {
+	sorts := make([]query.Sort, 0, len(spec)+2)
+	seen := map[string]bool{}
+	for i, f := range spec {
+		s, err := sortClause(f, sortable)
+		if err != nil {
+			return nil, ginext.NewError(http.StatusBadRequest, fmt.Sprintf("sort[%d]: %s", i, err))
+		}
+		if seen[s.Field] {
+			return nil, ginext.NewError(http.StatusBadRequest, fmt.Sprintf("sort[%d]: %s is sorted on twice", i, s.Field))
+		}
+		seen[s.Field] = true
+		sorts = append(sorts, s)
+	}
+
+	if !seen[sortScore] {
+		sorts = append(sorts, query.SortBy(sortScore, sortDesc))
+	}
+	if !seen[tiebreaker] {
+		sorts = append(sorts, query.SortBy(tiebreaker, sortAsc))
+	}
+	return sorts, nil
+}
+
+func sortClause(f es.SortField, sortable map[string]es.SortableField) (query.Sort, error) {
+	field := strings.TrimSpace(f.Field)
+	order := strings.ToLower(strings.TrimSpace(f.Order))
+	if field == "" {
+		return query.Sort{}, fmt.Errorf("field is required")
+	}
+	if order != "" && order != sortAsc && order != sortDesc {
+		return query.Sort{}, fmt.Errorf("invalid order %q, use asc or desc", f.Order)
+	}
+
+	if field == sortScore {
+		if f.Missing != "" || f.Mode != "" || f.NestedPath != "" {
+			return query.Sort{}, fmt.Errorf("_score takes only an order")
+		}
+		if order == "" {
+			order = sortDesc
+		}
+		return query.SortBy(sortScore, order), nil
+	}
+
+	def, ok := sortable[field]
+	if !ok {
+		return query.Sort{}, fmt.Errorf("can't sort on %q, sortable fields are %s", field, strings.Join(sortableNames(sortable), ", "))
+	}
+	if order == "" {
+		order = sortAsc
+	}
+	s := query.SortBy(field, order)
+
+	if f.Missing != "" {
+		if !sortMissing[f.Missing] {
+			return query.Sort{}, fmt.Errorf("invalid missing %q, use _first or _last", f.Missing)
+		}
+		s.Missing = f.Missing
+	}
+	if f.Mode != "" {
+		if !sortModes[f.Mode] {
+			return query.Sort{}, fmt.Errorf("invalid mode %q, use min, max, sum, avg or median", f.Mode)
+		}
+		if numericSortModes[f.Mode] && !def.IsNumeric() {
+			return query.Sort{}, fmt.Errorf("mode %s needs a numeric field, %s is %s", f.Mode, field, def.Type)
+		}
+		s.Mode = f.Mode
+	}
+
+	switch {
+	case f.NestedPath != "" && f.NestedPath != def.NestedPath:
+		if def.NestedPath == "" {
+			return query.Sort{}, fmt.Errorf("%s is not inside a nested field", field)
+		}
+		return query.Sort{}, fmt.Errorf("nested_path of %s must be %s", field, def.NestedPath)
+	case def.NestedPath != "":
+		s.NestedPath = def.NestedPath
+	}
+	return s, nil
+}
+
+func sortableNames(sortable map[string]es.SortableField) []string {
+	names := make([]string, 0, len(sortable)+1)
+	names = append(names, sortScore)
+	for name := range sortable {
+		names = append(names, name)
+	}
+	sort.Strings(names[1:])
+	return names
+}
@@ -0,0 +1,236 @@
+package service
+
+import (
+	"business/pkg/es"
+	"business/pkg/es/query"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gitlab.com/goxp/cloud0/ginext"
+)
+
+// statusOf returns the HTTP status of the client error err wraps, 500 when
+// there is none
+func statusOf(err error) int {
+	var apiErr interface {
+		error
+		ginext.ApiError
+	}
+	if errors.As(err, &apiErr) {
+		return apiErr.Code()
+	}
+	return http.StatusInternalServerError
+}
+
+func TestBuildSort(t *testing.T) {
+	sortable := map[string]es.SortableField{
+		"ID":          {Type: "keyword"},
+		"CreateAt":    {Type: "date"},
+		"rating":      {Type: "double"},
+		"Staffs.role": {Type: "keyword", NestedPath: "Staffs"},
+		"Staffs.age":  {Type: "integer", NestedPath: "Staffs"},
+	}
+
+	tests := []struct {
+		name    string
+		spec    es.SortSpec
+		want    []query.Sort
+		wantErr string
+	}{
+		{
+			name: "empty spec sorts on score then the tiebreaker",
+			want: []query.Sort{query.SortBy("_score", "desc"), query.SortBy("ID", "asc")},
+		},
+		{
+			name: "score and tiebreaker appended after the fields",
+			spec: es.SortSpec{{Field: "CreateAt", Order: "DESC", Missing: "_last"}, {Field: "rating"}},
+			want: []query.Sort{
+				{Field: "CreateAt", Order: "desc", Missing: "_last"},
+				query.SortBy("rating", "asc"),
+				query.SortBy("_score", "desc"),
+				query.SortBy("ID", "asc"),
+			},
+		},
+		{
+			name: "explicit score and tiebreaker kept in place",
+			spec: es.SortSpec{{Field: "ID", Order: "desc"}, {Field: "_score", Order: "asc"}},
+			want: []query.Sort{query.SortBy("ID", "desc"), query.SortBy("_score", "asc")},
+		},
+		{
+			name: "nested path auto filled",
+			spec: es.SortSpec{{Field: "Staffs.role", Mode: "min"}},
+			want: []query.Sort{
+				{Field: "Staffs.role", Order: "asc", Mode: "min", NestedPath: "Staffs"},
+				query.SortBy("_score", "desc"),
+				query.SortBy("ID", "asc"),
+			},
+		},
+		{
+			name: "sum on a numeric nested field",
+			spec: es.SortSpec{{Field: "Staffs.age", Order: "desc", Mode: "sum", NestedPath: "Staffs"}},
+			want: []query.Sort{
+				{Field: "Staffs.age", Order: "desc", Mode: "sum", NestedPath: "Staffs"},
+				query.SortBy("_score", "desc"),
+				query.SortBy("ID", "asc"),
+			},
+		},
+		{
+			name:    "duplicate field",
+			spec:    es.SortSpec{{Field: "CreateAt"}, {Field: "CreateAt", Order: "desc"}},
+			wantErr: "sort[1]: CreateAt is sorted on twice",
+		},
+		{
+			name:    "field missing from the mapping",
+			spec:    es.SortSpec{{Field: "name"}},
+			wantErr: `sort[0]: can't sort on "name", sortable fields are _score, CreateAt, ID, Staffs.age, Staffs.role, rating`,
+		},
+		{
+			name:    "sum on a keyword",
+			spec:    es.SortSpec{{Field: "Staffs.role", Mode: "sum"}},
+			wantErr: "sort[0]: mode sum needs a numeric field, Staffs.role is keyword",
+		},
+		{
+			name:    "unknown mode",
+			spec:    es.SortSpec{{Field: "rating", Mode: "first"}},
+			wantErr: `sort[0]: invalid mode "first"`,
+		},
+		{
+			name:    "bad order",
+			spec:    es.SortSpec{{Field: "rating", Order: "up"}},
+			wantErr: `sort[0]: invalid order "up", use asc or desc`,
+		},
+		{
+			name:    "bad missing",
+			spec:    es.SortSpec{{Field: "rating", Missing: "first"}},
+			wantErr: `sort[0]: invalid missing "first"`,
+		},
+		{
+			name:    "nested path on a top level field",
+			spec:    es.SortSpec{{Field: "rating", NestedPath: "Staffs"}},
+			wantErr: "sort[0]: rating is not inside a nested field",
+		},
+		{
+			name:    "wrong nested path",
+			spec:    es.SortSpec{{Field: "Staffs.role", NestedPath: "Owners"}},
+			wantErr: "sort[0]: nested_path of Staffs.role must be Staffs",
+		},
+		{
+			name:    "score with options",
+			spec:    es.SortSpec{{Field: "_score", Missing: "_last"}},
+			wantErr: "sort[0]: _score takes only an order",
+		},
+		{
+			name:    "empty field",
+			spec:    es.SortSpec{{Order: "asc"}},
+			wantErr: "sort[0]: field is required",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := buildSort(tt.spec, sortable, "ID")
+			if tt.wantErr != "" {
+				if err == nil {
+					t.Fatalf("got %v, want error %q", got, tt.wantErr)
+				}
+				if !strings.HasPrefix(err.Error(), tt.wantErr) {
+					t.Errorf("err = %q, want %q", err, tt.wantErr)
+				}
+				if code := statusOf(err); code != http.StatusBadRequest {
+					t.Errorf("status = %d, want 400", code)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("err = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBusinessSort(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    es.SortSpec
+		want    query.Sort
+		wantErr bool
+	}{
+		{name: "keyword", spec: es.SortSpec{{Field: "status"}}, want: query.SortBy("status", "asc")},
+		{name: "date", spec: es.SortSpec{{Field: "CreateAt", Order: "desc"}}, want: query.SortBy("CreateAt", "desc")},
+		{
+			name: "nested staff keyword",
+			spec: es.SortSpec{{Field: "Staffs.role"}},
+			want: query.Sort{Field: "Staffs.role", Order: "asc", NestedPath: "Staffs"},
+		},
+		{name: "text field", spec: es.SortSpec{{Field: "name"}}, wantErr: true},
+		{name: "nested text field", spec: es.SortSpec{{Field: "Staffs.fullname"}}, wantErr: true},
+		{name: "sum on a keyword", spec: es.SortSpec{{Field: "type", Mode: "sum"}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := businessSort(tt.spec)
+			if tt.wantErr {
+				if statusOf(err) != http.StatusBadRequest {
+					t.Fatalf("got %v, %v, want a 400 error", got, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("err = %v", err)
+			}
+			want := []query.Sort{tt.want, query.SortBy("_score", "desc"), query.SortBy(fieldID, "asc")}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("got %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestWithCursorStateSort(t *testing.T) {
+	sorted := es.SortSpec{{Field: "CreateAt", Order: "desc"}}
+	cursor, err := es.EncodeCursor(es.Cursor{
+		PitID:       "pit",
+		SearchAfter: []json.RawMessage{json.RawMessage(`1`), json.RawMessage(`2`), json.RawMessage(`"a"`), json.RawMessage(`3`)},
+		Sort:        sorted,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		sort     es.SortSpec
+		want     es.SortSpec
+		wantCode int
+	}{
+		{name: "sort restored from the cursor", want: sorted},
+		{name: "same sort", sort: es.SortSpec{{Field: "CreateAt", Order: "desc"}}, want: sorted},
+		{name: "sort changed", sort: es.SortSpec{{Field: "CreateAt", Order: "asc"}}, wantCode: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := withCursorState(es.SearchRequest{Cursor: cursor, Sort: tt.sort})
+			if tt.wantCode != 0 {
+				if code := statusOf(err); code != tt.wantCode {
+					t.Fatalf("status = %d, want %d (err %v)", code, tt.wantCode, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("err = %v", err)
+			}
+			if !reflect.DeepEqual(got.Sort, tt.want) {
+				t.Errorf("sort = %+v, want %+v", got.Sort, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,227 @@
+package service
+
+import (
+	"business/pkg/es"
+	"business/pkg/es/query"
+	"business/pkg/model"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gitlab.com/goxp/cloud0/ginext"
+)
+
+const (
+	// staffAlias points to one staff_vN index
+	staffAlias = "staff"
+
+	defaultStaffPageSize = 30
+	maxStaffPageSize     = 1000
+)
+
+// Field names as they appear in the indexed model.Staff documents
+const (
+	staffFieldFullname     = "fullname"
+	staffFieldUsername     = "username"
+	staffFieldUsernameText = "username.text"
+	staffFieldEmail        = "email"
+	staffFieldRole         = "role"
+	staffFieldBusinessID   = "business_id"
+	staffFieldCreatedAt    = "created_at"
+)
+
+// staffSortFields are the keyword and date fields a staff search can sort on
+var staffSortFields = map[string]bool{
+	staffFieldUsername:   true,
+	staffFieldEmail:      true,
+	staffFieldRole:       true,
+	staffFieldBusinessID: true,
+	staffFieldCreatedAt:  true,
+}
+
+// staffIndex defines every staff_vN index, the mapping comes from the es
+// tags of model.Staff
+func staffIndex() es.IndexDefinition {
+	return es.IndexDefinition{
+		Name:     staffAlias,
+		Settings: map[string]interface{}{"analysis": vietnameseAnalysis()},
+		Model:    model.StaffDocument{},
+	}
+}
+
+func (e *EsService) ensureStaffIndex(ctx context.Context) error {
+	return e.ensureIndex(ctx, staffIndex())
+}
+
+// ReindexStaff builds the next staff_vN index from Postgres and swaps the
+// staff alias to it, like ReindexBusiness
+func (e *EsService) ReindexStaff(ctx context.Context) (*ReindexReport, error) {
+	started := time.Now()
+	report, err := e.reindex(ctx, staffIndex(), e.fillStaffs)
+	if err != nil {
+		return report, err
+	}
+	return e.replayChanges(ctx, report, started, model.OutboxAggregateStaff)
+}
+
+func (e *EsService) fillStaffs(ctx context.Context, indexer es.BulkIndexer) (int64, error) {
+	var expected int64
+	after := uuid.Nil
+	for {
+		staffs, err := e.repo.GetStaffPageAfter(ctx, after, reindexPageSize, nil)
+		if err != nil {
+			return expected, fmt.Errorf("failed to read staffs after %s: %w", after, err)
+		}
+		for _, s := range staffs {
+			if err := indexer.Add(ctx, es.BulkDocument{ID: s.ID.String(), Data: s}); err != nil {
+				return expected, fmt.Errorf("failed to queue staff %s: %w", s.ID, err)
+			}
+		}
+		expected += int64(len(staffs))
+		if len(staffs) < reindexPageSize {
+			return expected, nil
+		}
+		after = staffs[len(staffs)-1].ID
+	}
+}
+
+// SearchStaff runs the filters of req as exact filters and its keyword as
+// full-text search over fullname and username
+func (e *EsService) SearchStaff(ctx context.Context, req *model.GetListStaffRequest) (model.GetListStaffResponse, error) {
+	var rs model.GetListStaffResponse
+
+	page, pageSize := req.Page, req.PageSize
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 {
+		pageSize = defaultStaffPageSize
+	}
+	if pageSize > maxStaffPageSize {
+		pageSize = maxStaffPageSize
+	}
+	from := (page - 1) * pageSize
+	if from+pageSize > maxResultWindow {
+		return rs, ginext.NewError(http.StatusBadRequest, fmt.Sprintf("page is beyond the first %d results", maxResultWindow))
+	}
+
+	search, err := buildStaffSearch(req)
+	if err != nil {
+		return rs, err
+	}
+	search.From(from).Size(pageSize)
+
+	result, err := e.client.Search(ctx, staffAlias, search)
+	if err != nil {
+		return rs, fmt.Errorf("staff search failed: %w", err)
+	}
+
+	rs.Data = make([]model.Staff, 0, len(result.Hits.Hits))
+	for _, hit := range result.Hits.Hits {
+		var s model.Staff
+		src, _ := json.Marshal(hit.Source)
+		if err := json.Unmarshal(src, &s); err == nil {
+			rs.Data = append(rs.Data, s)
+		}
+	}
+	total := result.Hits.Total.Value
+	rs.Meta = map[string]interface{}{
+		"page":        page,
+		"page_size":   pageSize,
+		"total_pages": int(math.Ceil(float64(total) / float64(pageSize))),
+		"total_rows":  total,
+	}
+	return rs, nil
+}
+
+func buildStaffSearch(req *model.GetListStaffRequest) (*query.SearchSource, error) {
+	boolQuery := query.Bool()
+
+	if req.Username != nil && *req.Username != "" {
+		boolQuery.Filter(query.Term(staffFieldUsername, *req.Username))
+	}
+	if req.Email != nil && *req.Email != "" {
+		boolQuery.Filter(query.Term(staffFieldEmail, *req.Email))
+	}
+	if req.Role != nil && *req.Role != "" {
+		boolQuery.Filter(query.Term(staffFieldRole, *req.Role))
+	}
+	if req.BusinessID != nil && *req.BusinessID != "" {
+		businessID, err := uuid.Parse(*req.BusinessID)
+		if err != nil {
+			return nil, ginext.NewError(http.StatusBadRequest, "business_id must be a uuid")
+		}
+		boolQuery.Filter(query.Term(staffFieldBusinessID, businessID.String()))
+	}
+
+	keyword := strings.TrimSpace(req.Keyword)
+	if keyword != "" {
+		boolQuery.Must(query.MultiMatch(keyword,
+			staffFieldFullname,
+			fmt.Sprintf("%s.%s^%d", staffFieldFullname, subfieldExact, exactBoost),
+			staffFieldUsernameText,
+		).Type("most_fields"))
+	}
+
+	search := query.NewSearch().Query(boolQuery)
+	if boolQuery.IsEmpty() {
+		search.Query(query.MatchAll())
+	}
+
+	sort, err := staffSort(req.Sort, keyword != "")
+	if err != nil {
+		return nil, err
+	}
+	search.Sort(sort...)
+	return search, nil
+}
+
+// staffSort reads "field order" or "field:order", the format GetListStaff
+// takes. Without one keyword searches rank by score and the rest by
+// created_at, newest first.
+func staffSort(raw string, scored bool) ([]query.Sort, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		if scored {
+			return []query.Sort{query.SortBy("_score", "desc"), query.SortBy(staffFieldCreatedAt, "desc")}, nil
+		}
+		return []query.Sort{query.SortBy(staffFieldCreatedAt, "desc")}, nil
+	}
+
+	field, order, _ := strings.Cut(strings.ReplaceAll(raw, ":", " "), " ")
+	field, order = strings.TrimSpace(field), strings.ToLower(strings.TrimSpace(order))
+	if order == "" {
+		order = "asc"
+	}
+	if !staffSortFields[field] || (order != "asc" && order != "desc") {
+		return nil, ginext.NewError(http.StatusBadRequest, fmt.Sprintf("can't sort staff by %q", raw))
+	}
+	return []query.Sort{query.SortBy(field, order)}, nil
+}
+
+// syncStaff indexes a staff, or deletes its document when the staff is gone
+func (e *EsService) syncStaff(ctx context.Context, staffID uuid.UUID) error {
+	staff, err := e.repo.GetOneStaff(ctx, staffID, nil)
+	if err != nil {
+		var apiErr ginext.ApiError
+		if !errors.As(err, &apiErr) || apiErr.Code() != http.StatusNotFound {
+			return fmt.Errorf("failed to read staff: %w", err)
+		}
+		err = e.client.DeleteDocument(ctx, staffAlias, staffID.String())
+		if err != nil && !errors.Is(err, es.ErrNotFound) {
+			return fmt.Errorf("failed to delete staff document: %w", err)
+		}
+		return nil
+	}
+
+	if err := e.client.IndexDocument(ctx, staffAlias, staffID.String(), staff); err != nil {
+		return fmt.Errorf("failed to index staff document: %w", err)
+	}
+	return nil
+}
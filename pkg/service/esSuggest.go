@@ -0,0 +1,64 @@
+package service
+
+import (
+	"business/pkg/es"
+	"business/pkg/es/query"
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"gitlab.com/goxp/cloud0/ginext"
+)
+
+const (
+	defaultSuggestSize = 5
+	maxSuggestSize     = 20
+)
+
+// SuggestBusiness returns the business names starting with the prefix, it
+// queries the search_as_you_type subfield and its shingles with bool_prefix
+func (e *EsService) SuggestBusiness(ctx context.Context, req es.SuggestRequest) ([]es.Suggestion, error) {
+	prefix := strings.TrimSpace(req.Prefix)
+	if prefix == "" {
+		return nil, ginext.NewError(http.StatusBadRequest, "prefix is required")
+	}
+	size := req.Size
+	if size <= 0 {
+		size = defaultSuggestSize
+	}
+	if size > maxSuggestSize {
+		size = maxSuggestSize
+	}
+
+	boolQuery := query.Bool().Must(
+		query.MultiMatch(prefix,
+			fieldNameSuggest,
+			fieldNameSuggest+"._2gram",
+			fieldNameSuggest+"._3gram",
+		).Type("bool_prefix"),
+	)
+	if req.Type != "" {
+		boolQuery.Filter(query.Term(fieldBusinessType, req.Type))
+	}
+	if req.Status != "" {
+		boolQuery.Filter(query.Term(fieldStatus, req.Status))
+	}
+
+	search := query.NewSearch().
+		Query(boolQuery).
+		Size(size).
+		FetchSource(fieldName)
+
+	result, err := e.client.Search(ctx, businessAlias, search)
+	if err != nil {
+		return nil, fmt.Errorf("suggest failed: %w", err)
+	}
+
+	suggestions := make([]es.Suggestion, 0, len(result.Hits.Hits))
+	for _, hit := range result.Hits.Hits {
+		name, _ := hit.Source[fieldName].(string)
+		suggestions = append(suggestions, es.Suggestion{ID: hit.ID, Name: name})
+	}
+	return suggestions, nil
+}
@@ -0,0 +1,117 @@
+package service
+
+import (
+	"business/pkg/es"
+	"business/pkg/model"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// EsSync is the outbox sink keeping the business and staff indices and the
+// business synonyms in line with Postgres. It doesn't index the event
+// payload: the business is read again with its staffs, so a late or replayed
+// event never writes stale data.
+type EsSync struct {
+	esService *EsService
+
+	mu      sync.Mutex
+	ensured bool
+}
+
+func NewEsSync(esService *EsService) *EsSync {
+	return &EsSync{esService: esService}
+}
+
+func (s *EsSync) Name() string {
+	return "elasticsearch"
+}
+
+// Deliver syncs the documents an event touched, staffs have their own
+// document and are nested in the document of their business
+func (s *EsSync) Deliver(ctx context.Context, event model.OutboxEvent) error {
+	if err := s.ensureIndices(ctx); err != nil {
+		return err
+	}
+
+	var businessIDs []uuid.UUID
+	switch event.Aggregate {
+	case model.OutboxAggregateSynonym:
+		_, err := s.esService.SyncSynonyms(ctx)
+		return err
+	case model.OutboxAggregateBusiness:
+		businessIDs = append(businessIDs, event.AggregateID)
+	case model.OutboxAggregateStaff:
+		if err := s.esService.syncStaff(ctx, event.AggregateID); err != nil {
+			return err
+		}
+		var payload model.OutboxPayload
+		if err := json.Unmarshal(event.Payload, &payload); err != nil {
+			return fmt.Errorf("failed to decode payload: %w", err)
+		}
+		if payload.Staff != nil {
+			businessIDs = append(businessIDs, payload.Staff.BusinessID)
+		}
+		if payload.PreviousBusinessID != nil {
+			businessIDs = append(businessIDs, *payload.PreviousBusinessID)
+		}
+	}
+
+	for _, id := range businessIDs {
+		// a staff without a business has no document to update
+		if id == uuid.Nil {
+			continue
+		}
+		if err := s.SyncBusiness(ctx, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SyncBusiness indexes the business with its staffs, or deletes its document
+// when the business is gone from Postgres
+func (s *EsSync) SyncBusiness(ctx context.Context, businessID uuid.UUID) error {
+	business, err := s.esService.repo.GetOneBusiness_v2(ctx, businessID, nil)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		err = s.esService.client.DeleteDocument(ctx, businessAlias, businessID.String())
+		if err != nil && !errors.Is(err, es.ErrNotFound) {
+			return fmt.Errorf("failed to delete business document: %w", err)
+		}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read business: %w", err)
+	}
+
+	if err := s.esService.client.IndexDocument(ctx, businessAlias, businessID.String(), business); err != nil {
+		return fmt.Errorf("failed to index business document: %w", err)
+	}
+	return nil
+}
+
+// ensureIndices creates the business and staff indices once, writing to a
+// missing alias would create a concrete index with a dynamic mapping
+func (s *EsSync) ensureIndices(ctx context.Context) error {
+	s.mu.Lock()
+	ensured := s.ensured
+	s.mu.Unlock()
+	if ensured {
+		return nil
+	}
+	if err := s.esService.ensureBusinessIndex(ctx); err != nil {
+		return err
+	}
+	if err := s.esService.ensureStaffIndex(ctx); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.ensured = true
+	s.mu.Unlock()
+	return nil
+}
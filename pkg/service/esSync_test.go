@@ -0,0 +1,129 @@
+package service
+
+import (
+	"business/pkg/es"
+	"business/pkg/model"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestEsSyncDeliver(t *testing.T) {
+	current, moved, deleted := testBusiness(1, "Cafe Sua"), testBusiness(2, "Tra Dao"), testBusiness(3, "Gone")
+	staff := model.Staff{ID: uuid.MustParse(fmtID(11)), Username: "lan", Email: "lan@example.com", Role: "admin", BusinessID: current.ID}
+	current.Staffs = []model.Staff{staff}
+	staffless := model.Staff{ID: uuid.MustParse(fmtID(12)), Username: "minh", Email: "minh@example.com", Role: "staff"}
+	goneStaff := uuid.MustParse(fmtID(13))
+
+	payload := func(p model.OutboxPayload) json.RawMessage {
+		data, err := json.Marshal(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+
+	tests := []struct {
+		name  string
+		event model.OutboxEvent
+		// documents expected after the delivery, by alias, and those expected gone
+		wantDocs map[string][]uuid.UUID
+		wantGone map[string][]uuid.UUID
+		// staffs expected nested in the document of current
+		wantStaffs int
+		wantRules  int
+	}{
+		{
+			name:       "business saved",
+			event:      model.OutboxEvent{Aggregate: model.OutboxAggregateBusiness, AggregateID: current.ID},
+			wantDocs:   map[string][]uuid.UUID{businessAlias: {current.ID}},
+			wantStaffs: 1,
+		},
+		{
+			name:     "business deleted",
+			event:    model.OutboxEvent{Aggregate: model.OutboxAggregateBusiness, AggregateID: deleted.ID},
+			wantGone: map[string][]uuid.UUID{businessAlias: {deleted.ID}},
+		},
+		{
+			name: "staff moved to another business",
+			event: model.OutboxEvent{Aggregate: model.OutboxAggregateStaff, AggregateID: staff.ID, Payload: payload(model.OutboxPayload{
+				Staff: &staff, PreviousBusinessID: &moved.ID,
+			})},
+			wantDocs:   map[string][]uuid.UUID{staffAlias: {staff.ID}, businessAlias: {current.ID, moved.ID}},
+			wantStaffs: 1,
+		},
+		{
+			name: "staff without a business",
+			event: model.OutboxEvent{Aggregate: model.OutboxAggregateStaff, AggregateID: staffless.ID, Payload: payload(model.OutboxPayload{
+				Staff: &staffless,
+			})},
+			wantDocs: map[string][]uuid.UUID{staffAlias: {staffless.ID}},
+		},
+		{
+			name: "staff deleted",
+			event: model.OutboxEvent{Aggregate: model.OutboxAggregateStaff, AggregateID: goneStaff, Payload: payload(model.OutboxPayload{
+				Staff: &model.Staff{ID: goneStaff, BusinessID: deleted.ID},
+			})},
+			wantGone: map[string][]uuid.UUID{staffAlias: {goneStaff}, businessAlias: {deleted.ID}},
+		},
+		{
+			name:      "synonyms changed",
+			event:     model.OutboxEvent{Aggregate: model.OutboxAggregateSynonym, AggregateID: uuid.New()},
+			wantRules: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			e, client, rp := newTestEsService(t, []model.Business{current, moved}, deleted)
+			rp.staffs = []model.Staff{staff, staffless}
+			if err := e.ensureStaffIndex(ctx); err != nil {
+				t.Fatal(err)
+			}
+			if err := client.IndexDocument(ctx, staffAlias, goneStaff.String(), model.Staff{ID: goneStaff}); err != nil {
+				t.Fatal(err)
+			}
+			rp.synonyms = []model.SynonymSet{{ID: uuid.New(), Name: "cafe", Synonyms: "cafe, ca phe"}}
+
+			tt.event.ID = uuid.New()
+			if err := NewEsSync(e).Deliver(ctx, tt.event); err != nil {
+				t.Fatalf("err = %v", err)
+			}
+
+			for alias, ids := range tt.wantDocs {
+				for _, id := range ids {
+					if _, err := client.GetDocument(ctx, alias, id.String()); err != nil {
+						t.Errorf("%s document %s: %v", alias, id, err)
+					}
+				}
+			}
+			for alias, ids := range tt.wantGone {
+				for _, id := range ids {
+					if _, err := client.GetDocument(ctx, alias, id.String()); !errors.Is(err, es.ErrNotFound) {
+						t.Errorf("%s document %s: err = %v, want not found", alias, id, err)
+					}
+				}
+			}
+			if tt.wantStaffs > 0 {
+				doc, err := client.GetDocument(ctx, businessAlias, current.ID.String())
+				if err != nil {
+					t.Fatal(err)
+				}
+				var b model.Business
+				if err := json.Unmarshal(doc.Source, &b); err != nil {
+					t.Fatal(err)
+				}
+				if len(b.Staffs) != tt.wantStaffs {
+					t.Errorf("%d nested staffs, want %d", len(b.Staffs), tt.wantStaffs)
+				}
+			}
+			if got := len(client.SynonymsSet(businessSynonymsSet)); tt.wantRules > 0 && got != tt.wantRules {
+				t.Errorf("%d synonym rules, want %d", got, tt.wantRules)
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package service
+
+import (
+	"business/pkg/es"
+	"context"
+	"fmt"
+	"time"
+
+	"gitlab.com/goxp/cloud0/logger"
+)
+
+// SynonymSyncReport describes a push of the synonym sets to Elasticsearch
+type SynonymSyncReport struct {
+	SynonymsSet string    `json:"synonyms_set"`
+	Rules       int       `json:"rules"`
+	Reloaded    bool      `json:"reloaded"`
+	SyncedAt    time.Time `json:"synced_at"`
+}
+
+// SyncSynonyms replaces the business synonyms set with the synonym sets of
+// Postgres and reloads the search analyzers of the business index, new
+// synonyms apply to the next searches without a reindex
+func (e *EsService) SyncSynonyms(ctx context.Context) (*SynonymSyncReport, error) {
+	log := logger.WithCtx(ctx, "esService.SyncSynonyms")
+
+	rules, err := e.putSynonyms(ctx)
+	if err != nil {
+		return nil, err
+	}
+	report := &SynonymSyncReport{SynonymsSet: businessSynonymsSet, Rules: rules}
+
+	exists, err := e.client.IndexExists(ctx, businessAlias)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check index existence: %w", err)
+	}
+	// a missing index reads the synonyms set when it is created
+	if exists {
+		if err := e.client.ReloadSearchAnalyzers(ctx, businessAlias); err != nil {
+			return nil, fmt.Errorf("failed to reload search analyzers: %w", err)
+		}
+		report.Reloaded = true
+	}
+
+	report.SyncedAt = time.Now()
+	log.Infof("pushed %d synonym rules to %s", rules, businessSynonymsSet)
+	return report, nil
+}
+
+// putSynonyms writes every synonym set as one rule of the business synonyms set
+func (e *EsService) putSynonyms(ctx context.Context) (int, error) {
+	sets, err := e.repo.GetAllSynonymSets(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read synonym sets: %w", err)
+	}
+	rules := make([]es.SynonymRule, 0, len(sets))
+	for _, set := range sets {
+		rules = append(rules, es.SynonymRule{ID: set.ID.String(), Synonyms: set.Synonyms})
+	}
+	if err := e.client.PutSynonymsSet(ctx, businessSynonymsSet, rules); err != nil {
+		return 0, fmt.Errorf("failed to put synonyms set: %w", err)
+	}
+	return len(rules), nil
+}
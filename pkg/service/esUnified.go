@@ -0,0 +1,179 @@
+package service
+
+import (
+	"business/pkg/es"
+	"business/pkg/es/query"
+	"business/pkg/model"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"gitlab.com/goxp/cloud0/ginext"
+	"gitlab.com/goxp/cloud0/logger"
+)
+
+// Entity types of the unified search
+const (
+	entityBusiness = "business"
+	entityStaff    = "staff"
+
+	defaultUnifiedPageSize = 20
+	maxUnifiedPageSize     = 100
+)
+
+// unifiedEntity is one index of the unified search. Its boost applies to
+// the whole index and multiplies the field boosts, so a business name match
+// outranks a staff email match.
+type unifiedEntity struct {
+	name   string
+	alias  string
+	boost  float64
+	fields []string
+	decode func(source []byte) (interface{}, error)
+}
+
+var unifiedEntities = []unifiedEntity{
+	{
+		name:  entityBusiness,
+		alias: businessAlias,
+		boost: 2,
+		fields: []string{
+			fieldName + "^3",
+			fmt.Sprintf("%s.%s^%d", fieldName, subfieldExact, 3*exactBoost),
+			fieldAddress,
+			fieldDescription,
+		},
+		decode: func(source []byte) (interface{}, error) {
+			var b model.Business
+			err := json.Unmarshal(source, &b)
+			return b, err
+		},
+	},
+	{
+		name:  entityStaff,
+		alias: staffAlias,
+		boost: 1,
+		fields: []string{
+			staffFieldFullname + "^2",
+			fmt.Sprintf("%s.%s^%d", staffFieldFullname, subfieldExact, 2*exactBoost),
+			staffFieldUsernameText,
+			staffFieldEmail,
+		},
+		decode: func(source []byte) (interface{}, error) {
+			var s model.Staff
+			err := json.Unmarshal(source, &s)
+			return s, err
+		},
+	},
+}
+
+// UnifiedSearch searches the business and staff indices in one request.
+// Each hit is tagged with its type and the counts are per type.
+func (e *EsService) UnifiedSearch(ctx context.Context, req es.UnifiedSearchRequest) (*es.UnifiedSearchResult, error) {
+	keyword := strings.TrimSpace(req.Keyword)
+	if keyword == "" {
+		return nil, ginext.NewError(http.StatusBadRequest, "keyword is required")
+	}
+	entities, err := selectEntities(req.Types)
+	if err != nil {
+		return nil, err
+	}
+
+	page, pageSize := req.Page, req.PageSize
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 {
+		pageSize = defaultUnifiedPageSize
+	}
+	if pageSize > maxUnifiedPageSize {
+		pageSize = maxUnifiedPageSize
+	}
+	from := (page - 1) * pageSize
+	if from+pageSize > maxResultWindow {
+		return nil, ginext.NewError(http.StatusBadRequest, fmt.Sprintf("page is beyond the first %d results", maxResultWindow))
+	}
+
+	// fields missing from an index match nothing there, one query serves all
+	var fields []string
+	indices := make([]es.IndexBoost, 0, len(entities))
+	for _, entity := range entities {
+		fields = append(fields, entity.fields...)
+		indices = append(indices, es.IndexBoost{Index: entity.alias, Boost: entity.boost})
+	}
+	search := query.NewSearch().
+		Query(query.MultiMatch(keyword, fields...)).
+		From(from).
+		Size(pageSize)
+
+	result, err := e.client.SearchIndices(ctx, indices, search)
+	if err != nil {
+		return nil, fmt.Errorf("unified search failed: %w", err)
+	}
+
+	rs := &es.UnifiedSearchResult{
+		Hits:     make([]es.UnifiedHit, 0, len(result.Hits.Hits)),
+		Page:     page,
+		PageSize: pageSize,
+		Total:    result.Hits.Total.Value,
+		Counts:   make(map[string]int64, len(entities)),
+	}
+	for _, entity := range entities {
+		rs.Counts[entity.name] = result.IndexTotals[entity.alias]
+	}
+	for _, hit := range result.Hits.Hits {
+		entity, ok := entityOf(entities, hit.Index)
+		if !ok {
+			continue
+		}
+		src, _ := json.Marshal(hit.Source)
+		data, err := entity.decode(src)
+		if err != nil {
+			logger.WithCtx(ctx, "esService.UnifiedSearch").WithError(err).Warnf("skipping %s hit %s that doesn't decode", entity.name, hit.ID)
+			continue
+		}
+		rs.Hits = append(rs.Hits, es.UnifiedHit{Type: entity.name, ID: hit.ID, Score: hit.Score, Data: data})
+	}
+	return rs, nil
+}
+
+// selectEntities returns the entities named by types, all of them when
+// empty. Types may be repeated or comma separated.
+func selectEntities(types []string) ([]unifiedEntity, error) {
+	wanted := map[string]bool{}
+	for _, t := range types {
+		for _, name := range strings.Split(t, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				wanted[name] = true
+			}
+		}
+	}
+	if len(wanted) == 0 {
+		return unifiedEntities, nil
+	}
+
+	var selected []unifiedEntity
+	for _, entity := range unifiedEntities {
+		if wanted[entity.name] {
+			selected = append(selected, entity)
+			delete(wanted, entity.name)
+		}
+	}
+	for name := range wanted {
+		return nil, ginext.NewError(http.StatusBadRequest, fmt.Sprintf("unknown type %q", name))
+	}
+	return selected, nil
+}
+
+// entityOf finds the entity of a concrete index, <alias>_vN or a legacy
+// index named like the alias
+func entityOf(entities []unifiedEntity, index string) (unifiedEntity, bool) {
+	for _, entity := range entities {
+		if index == entity.alias || parseIndexVersion(entity.alias, index) > 0 {
+			return entity, true
+		}
+	}
+	return unifiedEntity{}, false
+}
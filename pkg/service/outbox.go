@@ -0,0 +1,291 @@
+package service
+
+import (
+	"business/pkg/model"
+	"business/pkg/repo"
+	"business/pkg/utils"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gitlab.com/goxp/cloud0/ginext"
+	"gitlab.com/goxp/cloud0/logger"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultOutboxPollInterval = 2 * time.Second
+	defaultOutboxBatchSize    = 20
+	defaultOutboxMaxAttempts  = 10
+	defaultOutboxLease        = 2 * time.Minute
+	defaultOutboxMinBackoff   = 5 * time.Second
+	defaultOutboxMaxBackoff   = 30 * time.Minute
+)
+
+// OutboxSink receives outbox events. Events are delivered at least once,
+// a sink has to tolerate seeing the same event ID again.
+type OutboxSink interface {
+	Name() string
+	Deliver(ctx context.Context, event model.OutboxEvent) error
+}
+
+// OutboxNotifier is told that events were committed, so they are relayed
+// without waiting for the next poll
+type OutboxNotifier interface {
+	Notify()
+}
+
+type OutboxInterface interface {
+	GetListOutboxEvent(ctx context.Context, req *model.GetListOutboxEventRequest) (model.GetListOutboxEventResponse, error)
+	GetOneOutboxEvent(ctx context.Context, eventID uuid.UUID) (*model.OutboxEvent, error)
+	RetryOutboxEvent(ctx context.Context, eventID uuid.UUID) (*model.OutboxEvent, error)
+}
+
+// OutboxConfig tunes the relay, zero values take the defaults
+type OutboxConfig struct {
+	PollInterval time.Duration
+	BatchSize    int
+	// MaxAttempts before an event goes to the dead state
+	MaxAttempts int
+	// Lease is how long claimed events are left to one relay
+	Lease      time.Duration
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+}
+
+func (c OutboxConfig) withDefaults() OutboxConfig {
+	if c.PollInterval <= 0 {
+		c.PollInterval = defaultOutboxPollInterval
+	}
+	if c.BatchSize <= 0 {
+		c.BatchSize = defaultOutboxBatchSize
+	}
+	if c.MaxAttempts <= 0 {
+		c.MaxAttempts = defaultOutboxMaxAttempts
+	}
+	if c.Lease <= 0 {
+		c.Lease = defaultOutboxLease
+	}
+	if c.MinBackoff <= 0 {
+		c.MinBackoff = defaultOutboxMinBackoff
+	}
+	if c.MaxBackoff <= 0 {
+		c.MaxBackoff = defaultOutboxMaxBackoff
+	}
+	return c
+}
+
+// OutboxRelay delivers outbox events to its sinks. Several relays can run
+// against the same table: each claims a batch with FOR UPDATE SKIP LOCKED
+// and leases it, then delivers outside of the claiming transaction.
+type OutboxRelay struct {
+	repo  repo.PGInterface
+	sinks []OutboxSink
+	cfg   OutboxConfig
+	wake  chan struct{}
+	now   func() time.Time
+}
+
+func NewOutboxRelay(repo repo.PGInterface, cfg OutboxConfig, sinks ...OutboxSink) *OutboxRelay {
+	return &OutboxRelay{repo: repo, sinks: sinks, cfg: cfg.withDefaults(), wake: make(chan struct{}, 1), now: time.Now}
+}
+
+// recordOutboxEvent adds an event to the outbox, rp must be the repo of the
+// transaction making the change
+func recordOutboxEvent(ctx context.Context, rp repo.PGInterface, aggregate string, aggregateID uuid.UUID, eventType string, payload model.OutboxPayload) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode outbox payload: %w", err)
+	}
+	return rp.CreateOutboxEvent(ctx, &model.OutboxEvent{
+		Aggregate:   aggregate,
+		AggregateID: aggregateID,
+		EventType:   eventType,
+		Payload:     data,
+	}, nil)
+}
+
+func (r *OutboxRelay) Notify() {
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run relays events until ctx is done
+func (r *OutboxRelay) Run(ctx context.Context) {
+	log := logger.WithCtx(ctx, "OutboxRelay.Run")
+	ticker := time.NewTicker(r.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		// a full batch means more events are probably due
+		for {
+			n, err := r.relayBatch(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					log.WithError(err).Error("Error when relay outbox events")
+				}
+				break
+			}
+			if n < r.cfg.BatchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-r.wake:
+		}
+	}
+}
+
+func (r *OutboxRelay) relayBatch(ctx context.Context) (int, error) {
+	var events []model.OutboxEvent
+	leaseEnd := r.now().Add(r.cfg.Lease)
+	err := r.repo.Transaction(ctx, func(rp repo.PGInterface) error {
+		var err error
+		events, err = rp.LockDueOutboxEvents(ctx, r.cfg.BatchSize, nil)
+		if err != nil || len(events) == 0 {
+			return err
+		}
+		ids := make([]uuid.UUID, 0, len(events))
+		for _, event := range events {
+			ids = append(ids, event.ID)
+		}
+		return rp.LeaseOutboxEvents(ctx, ids, leaseEnd, nil)
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to claim outbox events: %w", err)
+	}
+
+	// events still undelivered when the lease ends are left to whoever claims them next
+	deliverCtx, cancel := context.WithDeadline(ctx, leaseEnd)
+	defer cancel()
+	for i := range events {
+		if deliverCtx.Err() != nil {
+			break
+		}
+		r.deliver(ctx, deliverCtx, &events[i])
+	}
+	return len(events), nil
+}
+
+// deliver sends an event to the sinks that haven't accepted it yet and
+// records the outcome
+func (r *OutboxRelay) deliver(ctx, deliverCtx context.Context, event *model.OutboxEvent) {
+	log := logger.WithCtx(ctx, "OutboxRelay.deliver").WithField("EventID", event.ID)
+
+	delivered := map[string]bool{}
+	for _, name := range strings.Split(event.DeliveredSinks, ",") {
+		if name != "" {
+			delivered[name] = true
+		}
+	}
+
+	var failures []string
+	for _, sink := range r.sinks {
+		if delivered[sink.Name()] {
+			continue
+		}
+		if err := sink.Deliver(deliverCtx, *event); err != nil {
+			failures = append(failures, sink.Name()+": "+err.Error())
+			continue
+		}
+		delivered[sink.Name()] = true
+		if event.DeliveredSinks != "" {
+			event.DeliveredSinks += ","
+		}
+		event.DeliveredSinks += sink.Name()
+	}
+	if deliverCtx.Err() != nil && len(failures) > 0 {
+		// cut short by the lease or a shutdown, not the sinks' fault
+		return
+	}
+
+	event.Attempts++
+	now := r.now()
+	switch {
+	case len(failures) == 0:
+		event.Status = model.OutboxStatusDelivered
+		event.DeliveredAt = &now
+		event.LastError = ""
+	case event.Attempts >= r.cfg.MaxAttempts:
+		event.Status = model.OutboxStatusDead
+		event.LastError = strings.Join(failures, "; ")
+		log.WithField("attempts", event.Attempts).Errorf("outbox event dead: %s", event.LastError)
+	default:
+		event.NextAttemptAt = now.Add(r.backoff(event.Attempts))
+		event.LastError = strings.Join(failures, "; ")
+		log.WithField("attempts", event.Attempts).Warnf("outbox event not delivered: %s", event.LastError)
+	}
+
+	if err := r.repo.UpdateOutboxEvent(ctx, event, nil); err != nil {
+		log.WithError(err).Error("Error when update outbox event")
+	}
+}
+
+// backoff doubles from MinBackoff up to MaxBackoff
+func (r *OutboxRelay) backoff(attempts int) time.Duration {
+	d := r.cfg.MinBackoff << uint(attempts-1)
+	if d <= 0 || d > r.cfg.MaxBackoff {
+		return r.cfg.MaxBackoff
+	}
+	return d
+}
+
+func (r *OutboxRelay) GetListOutboxEvent(ctx context.Context, req *model.GetListOutboxEventRequest) (model.GetListOutboxEventResponse, error) {
+	log := logger.WithCtx(ctx, "OutboxRelay.GetListOutboxEvent")
+
+	rs, err := r.repo.GetListOutboxEvent(ctx, req, nil)
+	if err != nil {
+		log.WithError(err).Error("Error when call func GetListOutboxEvent")
+		return rs, ginext.NewError(http.StatusInternalServerError, utils.MessageError()[http.StatusInternalServerError])
+	}
+	return rs, nil
+}
+
+func (r *OutboxRelay) GetOneOutboxEvent(ctx context.Context, eventID uuid.UUID) (*model.OutboxEvent, error) {
+	log := logger.WithCtx(ctx, "OutboxRelay.GetOneOutboxEvent")
+
+	event, err := r.repo.GetOneOutboxEvent(ctx, eventID, nil)
+	if err != nil {
+		log.WithError(err).WithField("EventID", eventID).Error("Error when call func GetOneOutboxEvent")
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ginext.NewError(http.StatusNotFound, utils.MessageError()[http.StatusNotFound])
+		}
+		return nil, ginext.NewError(http.StatusInternalServerError, utils.MessageError()[http.StatusInternalServerError])
+	}
+	return event, nil
+}
+
+// RetryOutboxEvent puts a dead event back in the queue with fresh attempts,
+// sinks that accepted it before are not called again
+func (r *OutboxRelay) RetryOutboxEvent(ctx context.Context, eventID uuid.UUID) (*model.OutboxEvent, error) {
+	log := logger.WithCtx(ctx, "OutboxRelay.RetryOutboxEvent")
+
+	event, err := r.GetOneOutboxEvent(ctx, eventID)
+	if err != nil {
+		return nil, err
+	}
+	if event.Status != model.OutboxStatusDead {
+		return nil, ginext.NewError(http.StatusBadRequest, "only dead events can be retried")
+	}
+
+	event.Status = model.OutboxStatusPending
+	event.Attempts = 0
+	event.NextAttemptAt = r.now()
+	if err := r.repo.UpdateOutboxEvent(ctx, event, nil); err != nil {
+		log.WithError(err).WithField("EventID", eventID).Error("Error when update outbox event")
+		return nil, ginext.NewError(http.StatusInternalServerError, utils.MessageError()[http.StatusInternalServerError])
+	}
+	r.Notify()
+	return event, nil
+}
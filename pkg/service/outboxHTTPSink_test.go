@@ -0,0 +1,64 @@
+package service
+
+import (
+	"business/pkg/model"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestHTTPSinkDeliver(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		wantErr bool
+	}{
+		{name: "accepted", status: http.StatusOK},
+		{name: "accepted without content", status: http.StatusNoContent},
+		{name: "rejected", status: http.StatusBadRequest, wantErr: true},
+		{name: "consumer failing", status: http.StatusServiceUnavailable, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event := model.OutboxEvent{
+				ID: uuid.New(), Aggregate: model.OutboxAggregateBusiness, AggregateID: uuid.New(),
+				EventType: "updated", Payload: json.RawMessage(`{"business":{"name":"Cafe Sua"}}`),
+			}
+
+			var received model.OutboxEvent
+			var eventID, contentType string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				eventID, contentType = r.Header.Get("X-Event-ID"), r.Header.Get("Content-Type")
+				if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+					t.Errorf("decode body: %v", err)
+				}
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			err := NewHTTPSink(server.URL, 0).Deliver(context.Background(), event)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if eventID != event.ID.String() || contentType != "application/json" {
+				t.Errorf("headers X-Event-ID %q and Content-Type %q", eventID, contentType)
+			}
+			if received.ID != event.ID || received.AggregateID != event.AggregateID || string(received.Payload) != string(event.Payload) {
+				t.Errorf("received %+v, want %+v", received, event)
+			}
+		})
+	}
+
+	t.Run("unreachable", func(t *testing.T) {
+		server := httptest.NewServer(http.NotFoundHandler())
+		server.Close()
+		if err := NewHTTPSink(server.URL, 0).Deliver(context.Background(), model.OutboxEvent{ID: uuid.New()}); err == nil {
+			t.Fatal("err = nil for a closed consumer")
+		}
+	})
+}
@@ -0,0 +1,248 @@
+package service
+
+import (
+	"business/pkg/model"
+	"business/pkg/repo"
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// outboxRepo keeps the outbox table of the fake repo, due events are those
+// pending with a next attempt before now()
+type outboxRepo struct {
+	*fakeRepo
+	now func() time.Time
+}
+
+func (r *outboxRepo) Transaction(ctx context.Context, f func(rp repo.PGInterface) error) error {
+	return f(r)
+}
+
+func (r *outboxRepo) LockDueOutboxEvents(ctx context.Context, limit int, tx *gorm.DB) ([]model.OutboxEvent, error) {
+	var due []model.OutboxEvent
+	for _, event := range r.events {
+		if event.Status == model.OutboxStatusPending && !event.NextAttemptAt.After(r.now()) {
+			due = append(due, event)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i].NextAttemptAt.Before(due[j].NextAttemptAt) })
+	if len(due) > limit {
+		due = due[:limit]
+	}
+	return due, nil
+}
+
+func (r *outboxRepo) LeaseOutboxEvents(ctx context.Context, ids []uuid.UUID, until time.Time, tx *gorm.DB) error {
+	for _, id := range ids {
+		r.event(id).NextAttemptAt = until
+	}
+	return nil
+}
+
+func (r *outboxRepo) UpdateOutboxEvent(ctx context.Context, event *model.OutboxEvent, tx *gorm.DB) error {
+	*r.event(event.ID) = *event
+	return nil
+}
+
+func (r *outboxRepo) GetOneOutboxEvent(ctx context.Context, eventID uuid.UUID, tx *gorm.DB) (*model.OutboxEvent, error) {
+	if event := r.event(eventID); event != nil {
+		e := *event
+		return &e, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *outboxRepo) event(id uuid.UUID) *model.OutboxEvent {
+	for i := range r.events {
+		if r.events[i].ID == id {
+			return &r.events[i]
+		}
+	}
+	return nil
+}
+
+// fakeSink fails every delivery while err is set
+type fakeSink struct {
+	name      string
+	err       error
+	delivered int
+	// during runs inside Deliver, while the relay holds the event
+	during func(event model.OutboxEvent)
+}
+
+func (s *fakeSink) Name() string { return s.name }
+
+func (s *fakeSink) Deliver(ctx context.Context, event model.OutboxEvent) error {
+	if s.during != nil {
+		s.during(event)
+	}
+	if s.err != nil {
+		return s.err
+	}
+	s.delivered++
+	return nil
+}
+
+func newTestOutboxRelay(events []model.OutboxEvent, sinks ...OutboxSink) (*OutboxRelay, *outboxRepo, *time.Time) {
+	// the clock starts at the real time, the lease is also the deadline of
+	// the deliveries
+	now := time.Now()
+	rp := &outboxRepo{fakeRepo: &fakeRepo{events: events}, now: func() time.Time { return now }}
+	relay := NewOutboxRelay(rp, OutboxConfig{
+		MaxAttempts: 4,
+		Lease:       time.Minute,
+		MinBackoff:  time.Second,
+		MaxBackoff:  3 * time.Second,
+	}, sinks...)
+	relay.now = rp.now
+	return relay, rp, &now
+}
+
+func TestOutboxRelayBackoffToDead(t *testing.T) {
+	ctx := context.Background()
+	eventID := uuid.New()
+	search := &fakeSink{name: "search"}
+	webhook := &fakeSink{name: "webhook", err: errors.New("connection refused")}
+	relay, rp, now := newTestOutboxRelay([]model.OutboxEvent{
+		{ID: eventID, Status: model.OutboxStatusPending, NextAttemptAt: time.Now()},
+	}, search, webhook)
+
+	// another relay on the same table while the event is delivered
+	other := NewOutboxRelay(rp, relay.cfg, &fakeSink{name: "search"}, &fakeSink{name: "webhook"})
+	other.now = rp.now
+	webhook.during = func(event model.OutboxEvent) {
+		if got, want := rp.event(eventID).NextAttemptAt, now.Add(time.Minute); !got.Equal(want) {
+			t.Errorf("attempt %d: leased until %s, want %s", event.Attempts+1, got, want)
+		}
+		if n, err := other.relayBatch(ctx); err != nil || n != 0 {
+			t.Errorf("attempt %d: another relay claimed %d events (err %v) of a leased batch", event.Attempts+1, n, err)
+		}
+	}
+
+	// the backoff doubles from MinBackoff and stops at MaxBackoff, the
+	// fourth attempt is the last
+	for attempt, backoff := range []time.Duration{time.Second, 2 * time.Second, 3 * time.Second} {
+		if n, err := relay.relayBatch(ctx); err != nil || n != 1 {
+			t.Fatalf("attempt %d: relayed %d events, err %v", attempt+1, n, err)
+		}
+		event := rp.event(eventID)
+		if event.Status != model.OutboxStatusPending || event.Attempts != attempt+1 {
+			t.Fatalf("attempt %d: status %s attempts %d", attempt+1, event.Status, event.Attempts)
+		}
+		if want := now.Add(backoff); !event.NextAttemptAt.Equal(want) {
+			t.Errorf("attempt %d: next attempt in %s, want %s", attempt+1, event.NextAttemptAt.Sub(*now), backoff)
+		}
+		if event.LastError != "webhook: connection refused" {
+			t.Errorf("attempt %d: last error %q", attempt+1, event.LastError)
+		}
+
+		*now = now.Add(backoff - time.Millisecond)
+		if n, _ := relay.relayBatch(ctx); n != 0 {
+			t.Errorf("attempt %d: retried before the backoff", attempt+1)
+		}
+		*now = now.Add(time.Millisecond)
+	}
+
+	if n, err := relay.relayBatch(ctx); err != nil || n != 1 {
+		t.Fatalf("last attempt: relayed %d events, err %v", n, err)
+	}
+	event := rp.event(eventID)
+	if event.Status != model.OutboxStatusDead || event.Attempts != 4 || event.LastError != "webhook: connection refused" {
+		t.Errorf("got status %s attempts %d error %q, want dead after 4 attempts", event.Status, event.Attempts, event.LastError)
+	}
+	if event.DeliveredSinks != "search" || search.delivered != 1 {
+		t.Errorf("search sink got the event %d times, delivered sinks %q", search.delivered, event.DeliveredSinks)
+	}
+
+	*now = now.Add(time.Hour)
+	if n, _ := relay.relayBatch(ctx); n != 0 {
+		t.Errorf("relayed a dead event")
+	}
+}
+
+func TestRetryOutboxEvent(t *testing.T) {
+	tests := []struct {
+		name     string
+		event    model.OutboxEvent
+		wantCode int
+	}{
+		{
+			name:  "dead event requeued",
+			event: model.OutboxEvent{Status: model.OutboxStatusDead, Attempts: 4, DeliveredSinks: "search", LastError: "webhook: connection refused"},
+		},
+		{
+			name:     "pending event",
+			event:    model.OutboxEvent{Status: model.OutboxStatusPending, Attempts: 2},
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "delivered event",
+			event:    model.OutboxEvent{Status: model.OutboxStatusDelivered, Attempts: 1},
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "unknown event",
+			wantCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			var events []model.OutboxEvent
+			eventID := uuid.New()
+			if tt.event.Status != "" {
+				tt.event.ID = eventID
+				tt.event.NextAttemptAt = time.Now().Add(-time.Hour)
+				events = append(events, tt.event)
+			}
+			search, webhook := &fakeSink{name: "search"}, &fakeSink{name: "webhook"}
+			relay, rp, now := newTestOutboxRelay(events, search, webhook)
+			*now = now.Add(time.Minute)
+
+			got, err := relay.RetryOutboxEvent(ctx, eventID)
+			if tt.wantCode != 0 {
+				if code := statusOf(err); code != tt.wantCode {
+					t.Fatalf("status = %d (err %v), want %d", code, err, tt.wantCode)
+				}
+				if event := rp.event(eventID); event != nil && !reflect.DeepEqual(*event, tt.event) {
+					t.Errorf("event changed to %+v", *event)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("err = %v", err)
+			}
+			if got.Status != model.OutboxStatusPending || got.Attempts != 0 || !got.NextAttemptAt.Equal(*now) {
+				t.Errorf("got status %s attempts %d next attempt %s, want pending now", got.Status, got.Attempts, got.NextAttemptAt)
+			}
+			if stored := rp.event(eventID); stored.Status != model.OutboxStatusPending || stored.Attempts != 0 {
+				t.Errorf("stored status %s attempts %d", stored.Status, stored.Attempts)
+			}
+			select {
+			case <-relay.wake:
+			default:
+				t.Error("relay not woken up")
+			}
+
+			// the requeued event only goes to the sinks that missed it
+			if n, err := relay.relayBatch(ctx); err != nil || n != 1 {
+				t.Fatalf("relayed %d events, err %v", n, err)
+			}
+			if search.delivered != 0 || webhook.delivered != 1 {
+				t.Errorf("search got %d deliveries, webhook %d, want 0 and 1", search.delivered, webhook.delivered)
+			}
+			if stored := rp.event(eventID); stored.Status != model.OutboxStatusDelivered || stored.DeliveredSinks != "search,webhook" {
+				t.Errorf("stored status %s delivered sinks %q", stored.Status, stored.DeliveredSinks)
+			}
+		})
+	}
+}
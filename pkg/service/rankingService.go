@@ -0,0 +1,279 @@
+package service
+
+import (
+	"business/pkg/model"
+	"business/pkg/repo"
+	"business/pkg/utils"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	"gitlab.com/goxp/cloud0/ginext"
+	"gitlab.com/goxp/cloud0/logger"
+	"gorm.io/gorm"
+)
+
+// rankableFields are the fields of the full-text multi_match a profile can boost
+var rankableFields = map[string]bool{
+	fieldName:         true,
+	fieldDescription:  true,
+	fieldAddress:      true,
+	fieldBusinessType: true,
+	fieldStatus:       true,
+}
+
+var (
+	decayFunctions = map[string]bool{"gauss": true, "exp": true, "linear": true}
+	boostModes     = map[string]bool{"multiply": true, "replace": true, "sum": true, "avg": true, "max": true, "min": true}
+	// durationPattern is a date distance of a decay function, e.g. 30d or 12h
+	durationPattern = regexp.MustCompile(`^[0-9]+(ms|s|m|h|d)$`)
+	// a decay scale can't be zero
+	zeroDurationPattern = regexp.MustCompile(`^0+[a-z]+$`)
+)
+
+// RankingConfig holds the profiles of the config file, Default is the
+// profile of the searches that don't pick one
+type RankingConfig struct {
+	Profiles []model.RankingProfileRequest
+	Default  string
+}
+
+// RankingResolver finds the ranking profile of a search
+type RankingResolver interface {
+	ResolveRankingProfile(ctx context.Context, name string) (*model.RankingProfile, error)
+}
+
+type RankingInterface interface {
+	CreateRankingProfile(ctx context.Context, req model.RankingProfileRequest) (*model.RankingProfile, error)
+	GetOneRankingProfile(ctx context.Context, profileID uuid.UUID) (*model.RankingProfile, error)
+	GetListRankingProfile(ctx context.Context) ([]model.RankingProfile, error)
+	UpdateRankingProfile(ctx context.Context, profileID uuid.UUID, req model.RankingProfileRequest) (*model.RankingProfile, error)
+	DeleteRankingProfile(ctx context.Context, profileID uuid.UUID) error
+}
+
+// RankingService serves the profiles of the config file and the ones stored
+// through the API, a stored profile overrides a config one of the same name
+type RankingService struct {
+	repo        repo.PGInterface
+	configured  map[string]model.RankingProfile
+	defaultName string
+}
+
+// NewRankingService validates the profiles of cfg, an invalid one is a
+// configuration error
+func NewRankingService(repo repo.PGInterface, cfg RankingConfig) (*RankingService, error) {
+	configured := make(map[string]model.RankingProfile, len(cfg.Profiles))
+	for _, req := range cfg.Profiles {
+		profile, err := rankingProfileFromRequest(req)
+		if err != nil {
+			return nil, fmt.Errorf("ranking profile %q: %w", req.Name, err)
+		}
+		if _, ok := configured[profile.Name]; ok {
+			return nil, fmt.Errorf("ranking profile %q is defined twice", profile.Name)
+		}
+		profile.Source = model.RankingSourceConfig
+		configured[profile.Name] = *profile
+	}
+	return &RankingService{repo: repo, configured: configured, defaultName: strings.TrimSpace(cfg.Default)}, nil
+}
+
+// ResolveRankingProfile returns the profile named name, or the default one
+// when name is empty. It returns nil when there is no profile to apply, a
+// missing default profile only logs so searches keep working.
+func (s *RankingService) ResolveRankingProfile(ctx context.Context, name string) (*model.RankingProfile, error) {
+	name = strings.TrimSpace(name)
+	requested := name != ""
+	if !requested {
+		name = s.defaultName
+	}
+	if name == "" {
+		return nil, nil
+	}
+
+	profile, err := s.repo.GetRankingProfileByName(ctx, name, nil)
+	if err == nil {
+		profile.Source = model.RankingSourceStored
+		return profile, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("failed to read ranking profile %s: %w", name, err)
+	}
+	if configured, ok := s.configured[name]; ok {
+		return &configured, nil
+	}
+	if !requested {
+		logger.WithCtx(ctx, "RankingService.ResolveRankingProfile").Warnf("default ranking profile %s doesn't exist", name)
+		return nil, nil
+	}
+	return nil, ginext.NewError(http.StatusBadRequest, fmt.Sprintf("unknown ranking profile %q", name))
+}
+
+func (s *RankingService) CreateRankingProfile(ctx context.Context, req model.RankingProfileRequest) (*model.RankingProfile, error) {
+	log := logger.WithCtx(ctx, "RankingService.CreateRankingProfile")
+
+	profile, err := rankingProfileFromRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkRankingProfileName(ctx, profile.Name, uuid.Nil); err != nil {
+		return nil, err
+	}
+	if err := s.repo.CreateRankingProfile(ctx, profile, nil); err != nil {
+		log.WithError(err).Error("Error when create ranking profile")
+		return nil, ginext.NewError(http.StatusInternalServerError, utils.MessageError()[http.StatusInternalServerError])
+	}
+	profile.Source = model.RankingSourceStored
+	return profile, nil
+}
+
+func (s *RankingService) GetOneRankingProfile(ctx context.Context, profileID uuid.UUID) (*model.RankingProfile, error) {
+	log := logger.WithCtx(ctx, "RankingService.GetOneRankingProfile")
+
+	profile, err := s.repo.GetOneRankingProfile(ctx, profileID, nil)
+	if err != nil {
+		log.WithError(err).WithField("ProfileID", profileID).Error("Error when call func GetOneRankingProfile")
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ginext.NewError(http.StatusNotFound, utils.MessageError()[http.StatusNotFound])
+		}
+		return nil, ginext.NewError(http.StatusInternalServerError, utils.MessageError()[http.StatusInternalServerError])
+	}
+	profile.Source = model.RankingSourceStored
+	return profile, nil
+}
+
+// GetListRankingProfile lists every profile a search can pick by name,
+// config profiles overridden by a stored one are left out
+func (s *RankingService) GetListRankingProfile(ctx context.Context) ([]model.RankingProfile, error) {
+	log := logger.WithCtx(ctx, "RankingService.GetListRankingProfile")
+
+	stored, err := s.repo.GetAllRankingProfiles(ctx, nil)
+	if err != nil {
+		log.WithError(err).Error("Error when call func GetAllRankingProfiles")
+		return nil, ginext.NewError(http.StatusInternalServerError, utils.MessageError()[http.StatusInternalServerError])
+	}
+
+	profiles := make([]model.RankingProfile, 0, len(stored)+len(s.configured))
+	names := make(map[string]bool, len(stored))
+	for _, p := range stored {
+		p.Source = model.RankingSourceStored
+		profiles = append(profiles, p)
+		names[p.Name] = true
+	}
+	for name, p := range s.configured {
+		if !names[name] {
+			profiles = append(profiles, p)
+		}
+	}
+	sort.Slice(profiles, func(i, j int) bool { return profiles[i].Name < profiles[j].Name })
+	return profiles, nil
+}
+
+func (s *RankingService) UpdateRankingProfile(ctx context.Context, profileID uuid.UUID, req model.RankingProfileRequest) (*model.RankingProfile, error) {
+	log := logger.WithCtx(ctx, "RankingService.UpdateRankingProfile")
+
+	profile, err := s.GetOneRankingProfile(ctx, profileID)
+	if err != nil {
+		return nil, err
+	}
+	update, err := rankingProfileFromRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkRankingProfileName(ctx, update.Name, profileID); err != nil {
+		return nil, err
+	}
+
+	profile.Name = update.Name
+	profile.Description = update.Description
+	profile.FieldBoosts = update.FieldBoosts
+	profile.Recency = update.Recency
+	profile.StatusWeights = update.StatusWeights
+	profile.BoostMode = update.BoostMode
+	if err := s.repo.UpdateRankingProfile(ctx, profile, nil); err != nil {
+		log.WithError(err).WithField("ProfileID", profileID).Error("Error when update ranking profile")
+		return nil, ginext.NewError(http.StatusInternalServerError, utils.MessageError()[http.StatusInternalServerError])
+	}
+	return profile, nil
+}
+
+func (s *RankingService) DeleteRankingProfile(ctx context.Context, profileID uuid.UUID) error {
+	log := logger.WithCtx(ctx, "RankingService.DeleteRankingProfile")
+
+	profile, err := s.GetOneRankingProfile(ctx, profileID)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.DeleteRankingProfile(ctx, profile, nil); err != nil {
+		log.WithError(err).WithField("ProfileID", profileID).Error("Error when delete ranking profile")
+		return ginext.NewError(http.StatusInternalServerError, utils.MessageError()[http.StatusInternalServerError])
+	}
+	return nil
+}
+
+func (s *RankingService) checkRankingProfileName(ctx context.Context, name string, profileID uuid.UUID) error {
+	count, err := s.repo.CountRankingProfileByName(ctx, name, profileID, nil)
+	if err != nil {
+		logger.WithCtx(ctx, "RankingService.checkRankingProfileName").WithError(err).Error("Error when count ranking profiles")
+		return ginext.NewError(http.StatusInternalServerError, utils.MessageError()[http.StatusInternalServerError])
+	}
+	if count > 0 {
+		return ginext.NewError(http.StatusConflict, fmt.Sprintf("ranking profile %q already exists", name))
+	}
+	return nil
+}
+
+// rankingProfileFromRequest validates a profile, its field boosts have to
+// be rankableFields and every boost and weight positive
+func rankingProfileFromRequest(req model.RankingProfileRequest) (*model.RankingProfile, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, ginext.NewError(http.StatusBadRequest, "name is required")
+	}
+	for field, boost := range req.FieldBoosts {
+		if !rankableFields[field] {
+			return nil, ginext.NewError(http.StatusBadRequest, fmt.Sprintf("can't boost field %q", field))
+		}
+		if boost <= 0 {
+			return nil, ginext.NewError(http.StatusBadRequest, fmt.Sprintf("boost of %s must be positive", field))
+		}
+	}
+	for status, weight := range req.StatusWeights {
+		if strings.TrimSpace(status) == "" {
+			return nil, ginext.NewError(http.StatusBadRequest, "status_weights can't have an empty status")
+		}
+		if weight <= 0 {
+			return nil, ginext.NewError(http.StatusBadRequest, fmt.Sprintf("weight of status %s must be positive", status))
+		}
+	}
+	if r := req.Recency; r != nil {
+		if r.Function != "" && !decayFunctions[r.Function] {
+			return nil, ginext.NewError(http.StatusBadRequest, "recency.function must be gauss, exp or linear")
+		}
+		if !durationPattern.MatchString(r.Scale) || zeroDurationPattern.MatchString(r.Scale) {
+			return nil, ginext.NewError(http.StatusBadRequest, "recency.scale must be a positive distance like 30d or 12h")
+		}
+		if r.Offset != "" && !durationPattern.MatchString(r.Offset) {
+			return nil, ginext.NewError(http.StatusBadRequest, "recency.offset must be a distance like 7d or 12h")
+		}
+		if r.Decay < 0 || r.Decay >= 1 {
+			return nil, ginext.NewError(http.StatusBadRequest, "recency.decay must be between 0 and 1")
+		}
+	}
+	if req.BoostMode != "" && !boostModes[req.BoostMode] {
+		return nil, ginext.NewError(http.StatusBadRequest, "boost_mode must be multiply, replace, sum, avg, max or min")
+	}
+
+	return &model.RankingProfile{
+		Name:          name,
+		Description:   req.Description,
+		FieldBoosts:   req.FieldBoosts,
+		Recency:       req.Recency,
+		StatusWeights: req.StatusWeights,
+		BoostMode:     req.BoostMode,
+	}, nil
+}
@@ -0,0 +1,258 @@
+package service
+
+import (
+	"business/pkg/es"
+	"business/pkg/model"
+	"business/pkg/repo"
+	"business/pkg/utils"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"gitlab.com/goxp/cloud0/ginext"
+	"gitlab.com/goxp/cloud0/logger"
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	defaultSearchLogBuffer        = 1000
+	defaultSearchLogBatchSize     = 100
+	defaultSearchLogFlushInterval = 2 * time.Second
+	searchLogShutdownTimeout      = 5 * time.Second
+
+	defaultSearchReportDays  = 7
+	defaultSearchReportLimit = 20
+	maxSearchReportLimit     = 100
+	searchReportDateLayout   = "2006-01-02"
+)
+
+// SearchRecorder takes searches to log, it must not slow the search down
+type SearchRecorder interface {
+	Record(entry SearchEntry)
+}
+
+// SearchEntry is one search as seen by the handler that served it
+type SearchEntry struct {
+	Kind    string
+	Request es.SearchRequest
+	Hits    int64
+	Latency time.Duration
+	UserID  *uuid.UUID
+}
+
+type SearchAnalyticsInterface interface {
+	TopQueries(ctx context.Context, req *model.SearchReportRequest) ([]model.SearchQueryStat, error)
+	ZeroResultQueries(ctx context.Context, req *model.SearchReportRequest) ([]model.SearchQueryStat, error)
+	SlowestQueries(ctx context.Context, req *model.SearchReportRequest) ([]model.SearchQueryStat, error)
+	DailyVolume(ctx context.Context, req *model.SearchReportRequest) ([]model.DailySearchVolume, error)
+}
+
+// SearchAnalyticsConfig tunes the search log writer, zero values take the defaults
+type SearchAnalyticsConfig struct {
+	// Buffer is how many searches wait for Run, more are dropped
+	Buffer        int
+	BatchSize     int
+	FlushInterval time.Duration
+	// Location is where the days of the reports start and end, UTC by default
+	Location *time.Location
+}
+
+func (c SearchAnalyticsConfig) withDefaults() SearchAnalyticsConfig {
+	if c.Buffer <= 0 {
+		c.Buffer = defaultSearchLogBuffer
+	}
+	if c.BatchSize <= 0 {
+		c.BatchSize = defaultSearchLogBatchSize
+	}
+	if c.FlushInterval <= 0 {
+		c.FlushInterval = defaultSearchLogFlushInterval
+	}
+	if c.Location == nil {
+		c.Location = time.UTC
+	}
+	return c
+}
+
+// SearchAnalytics logs searches to Postgres in the background and reports on them
+type SearchAnalytics struct {
+	repo    repo.PGInterface
+	cfg     SearchAnalyticsConfig
+	entries chan model.SearchLog
+	dropped int64
+}
+
+func NewSearchAnalytics(repo repo.PGInterface, cfg SearchAnalyticsConfig) *SearchAnalytics {
+	cfg = cfg.withDefaults()
+	return &SearchAnalytics{repo: repo, cfg: cfg, entries: make(chan model.SearchLog, cfg.Buffer)}
+}
+
+// searchLogFilters is what a search log keeps of the request besides its text
+type searchLogFilters struct {
+	Filters      es.BusinessFilter `json:"filters"`
+	FacetFilters es.FacetSelection `json:"facet_filters"`
+	Sort         es.SortSpec       `json:"sort,omitempty"`
+	Page         int               `json:"page,omitempty"`
+	// RankingProfile is the profile the search asked for
+	RankingProfile string `json:"ranking_profile,omitempty"`
+}
+
+// Record queues a search for Run to write, the search is dropped when the
+// buffer is full rather than waiting on Postgres
+func (a *SearchAnalytics) Record(entry SearchEntry) {
+	req := entry.Request
+	filters, _ := json.Marshal(searchLogFilters{
+		Filters:        req.Filters,
+		FacetFilters:   req.FacetFilters,
+		Sort:           req.Sort,
+		Page:           req.Page,
+		RankingProfile: req.RankingProfile,
+	})
+
+	searchLog := model.SearchLog{
+		Kind:      entry.Kind,
+		Index:     req.Index,
+		Query:     normalizeQuery(req.Filters.Name, req.Filters.Description, req.Filters.Address),
+		Filters:   filters,
+		Hits:      entry.Hits,
+		LatencyMs: entry.Latency.Milliseconds(),
+		UserID:    entry.UserID,
+		CreateAt:  time.Now(),
+	}
+	select {
+	case a.entries <- searchLog:
+	default:
+		atomic.AddInt64(&a.dropped, 1)
+	}
+}
+
+// normalizeQuery joins the text a user typed, lowercased and NFC normalized
+// with its spaces collapsed, so the same query is counted once however it
+// was typed. Accents are kept, "ca phe" and "cà phê" are different searches.
+func normalizeQuery(texts ...string) string {
+	return strings.ToLower(utils.RemoveSpace(norm.NFC.String(strings.Join(texts, " "))))
+}
+
+// Run writes queued searches in batches until ctx is done, then writes what
+// is left
+func (a *SearchAnalytics) Run(ctx context.Context) {
+	log := logger.WithCtx(ctx, "SearchAnalytics.Run")
+	ticker := time.NewTicker(a.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]model.SearchLog, 0, a.cfg.BatchSize)
+	flush := func(ctx context.Context) {
+		if dropped := atomic.SwapInt64(&a.dropped, 0); dropped > 0 {
+			log.Warnf("dropped %d search logs, the buffer was full", dropped)
+		}
+		if len(batch) == 0 {
+			return
+		}
+		if err := a.repo.CreateSearchLogs(ctx, batch, nil); err != nil {
+			log.WithError(err).Errorf("failed to write %d search logs", len(batch))
+		}
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case entry := <-a.entries:
+			batch = append(batch, entry)
+			if len(batch) >= a.cfg.BatchSize {
+				flush(ctx)
+			}
+		case <-ticker.C:
+			flush(ctx)
+		case <-ctx.Done():
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), searchLogShutdownTimeout)
+			defer cancel()
+			for {
+				select {
+				case entry := <-a.entries:
+					batch = append(batch, entry)
+					if len(batch) >= a.cfg.BatchSize {
+						flush(shutdownCtx)
+					}
+				default:
+					flush(shutdownCtx)
+					return
+				}
+			}
+		}
+	}
+}
+
+func (a *SearchAnalytics) TopQueries(ctx context.Context, req *model.SearchReportRequest) ([]model.SearchQueryStat, error) {
+	rg, err := searchLogRange(req, a.cfg.Location)
+	if err != nil {
+		return nil, err
+	}
+	return a.repo.GetTopSearchQueries(ctx, rg, nil)
+}
+
+func (a *SearchAnalytics) ZeroResultQueries(ctx context.Context, req *model.SearchReportRequest) ([]model.SearchQueryStat, error) {
+	rg, err := searchLogRange(req, a.cfg.Location)
+	if err != nil {
+		return nil, err
+	}
+	return a.repo.GetZeroResultSearchQueries(ctx, rg, nil)
+}
+
+func (a *SearchAnalytics) SlowestQueries(ctx context.Context, req *model.SearchReportRequest) ([]model.SearchQueryStat, error) {
+	rg, err := searchLogRange(req, a.cfg.Location)
+	if err != nil {
+		return nil, err
+	}
+	return a.repo.GetSlowestSearchQueries(ctx, rg, nil)
+}
+
+func (a *SearchAnalytics) DailyVolume(ctx context.Context, req *model.SearchReportRequest) ([]model.DailySearchVolume, error) {
+	rg, err := searchLogRange(req, a.cfg.Location)
+	if err != nil {
+		return nil, err
+	}
+	rs, err := a.repo.GetDailySearchVolume(ctx, rg, nil)
+	for i := range rs {
+		rs[i].Day = rs[i].Day.In(a.cfg.Location)
+	}
+	return rs, err
+}
+
+// searchLogRange validates a report request, without dates it covers the
+// last seven days including today
+func searchLogRange(req *model.SearchReportRequest, loc *time.Location) (*model.SearchLogRange, error) {
+	now := time.Now().In(loc)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+
+	to := today
+	if req.To != "" {
+		t, err := time.ParseInLocation(searchReportDateLayout, req.To, loc)
+		if err != nil {
+			return nil, ginext.NewError(http.StatusBadRequest, "to must be a yyyy-mm-dd date")
+		}
+		to = t
+	}
+	from := to.AddDate(0, 0, 1-defaultSearchReportDays)
+	if req.From != "" {
+		t, err := time.ParseInLocation(searchReportDateLayout, req.From, loc)
+		if err != nil {
+			return nil, ginext.NewError(http.StatusBadRequest, "from must be a yyyy-mm-dd date")
+		}
+		from = t
+	}
+	if from.After(to) {
+		return nil, ginext.NewError(http.StatusBadRequest, "from is after to")
+	}
+
+	limit := req.Limit
+	if limit <= 0 {
+		limit = defaultSearchReportLimit
+	}
+	if limit > maxSearchReportLimit {
+		limit = maxSearchReportLimit
+	}
+	return &model.SearchLogRange{From: from, To: to.AddDate(0, 0, 1), TimeZone: loc.String(), Index: req.Index, Limit: limit}, nil
+}
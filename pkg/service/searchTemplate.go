@@ -0,0 +1,492 @@
+package service
+
+import (
+	"business/pkg/es"
+	"business/pkg/model"
+	"business/pkg/repo"
+	"business/pkg/utils"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/google/uuid"
+	"gitlab.com/goxp/cloud0/ginext"
+	"gitlab.com/goxp/cloud0/logger"
+	"gorm.io/gorm"
+)
+
+// templateIndices are the aliases a search template can search
+var templateIndices = map[string]bool{
+	businessAlias: true,
+	staffAlias:    true,
+}
+
+var (
+	templateParamName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	// mustacheTag captures the kind and the name of a mustache tag
+	mustacheTag = regexp.MustCompile(`\{\{\{?\s*([#^/]?)\s*([A-Za-z_][A-Za-z0-9_.]*)[^}]*\}?\}\}`)
+	// mustacheHelperArg captures the parameter given to a toJson or join helper
+	mustacheHelperArg = regexp.MustCompile(`\{\{#(?:toJson|join)[^}]*\}\}\s*([A-Za-z_][A-Za-z0-9_.]*)\s*\{\{/(?:toJson|join)\}\}`)
+	mustacheHelpers   = map[string]bool{"toJson": true, "join": true, "url": true}
+)
+
+type SearchTemplateInterface interface {
+	CreateSearchTemplate(ctx context.Context, req model.SearchTemplateRequest, userID *uuid.UUID) (*model.SearchTemplate, error)
+	GetOneSearchTemplate(ctx context.Context, templateID uuid.UUID) (*model.SearchTemplate, error)
+	GetListSearchTemplate(ctx context.Context, req *model.GetListSearchTemplateRequest) (model.GetListSearchTemplateResponse, error)
+	UpdateSearchTemplate(ctx context.Context, templateID uuid.UUID, req model.SearchTemplateRequest, userID *uuid.UUID) (*model.SearchTemplate, error)
+	DeleteSearchTemplate(ctx context.Context, templateID uuid.UUID, userID *uuid.UUID) error
+	GetListSearchTemplateVersion(ctx context.Context, templateID uuid.UUID) ([]model.SearchTemplateVersion, error)
+	RenderSearchTemplate(ctx context.Context, templateID uuid.UUID, req model.RunSearchTemplateRequest) (*model.RenderedSearchTemplate, error)
+	RunSearchTemplate(ctx context.Context, templateID uuid.UUID, req model.RunSearchTemplateRequest) (*es.SearchResult, error)
+}
+
+// SearchTemplateService keeps search templates in Postgres and every version
+// of them as a stored script in Elasticsearch
+type SearchTemplateService struct {
+	repo   repo.PGInterface
+	client es.Client
+}
+
+func NewSearchTemplateService(repo repo.PGInterface, client es.Client) SearchTemplateInterface {
+	return &SearchTemplateService{repo: repo, client: client}
+}
+
+// searchTemplateScriptID is the stored script of a version of a template,
+// versions never overwrite each other
+func searchTemplateScriptID(templateID uuid.UUID, version int) string {
+	return fmt.Sprintf("search-template-%s-v%d", templateID, version)
+}
+
+func (s *SearchTemplateService) CreateSearchTemplate(ctx context.Context, req model.SearchTemplateRequest, userID *uuid.UUID) (*model.SearchTemplate, error) {
+	log := logger.WithCtx(ctx, "SearchTemplateService.CreateSearchTemplate")
+
+	template, err := searchTemplateFromRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	template.Version = 1
+
+	err = s.repo.Transaction(ctx, func(rp repo.PGInterface) error {
+		if err := checkSearchTemplateName(ctx, rp, template.Name, uuid.Nil); err != nil {
+			return err
+		}
+		if err := rp.CreateSearchTemplate(ctx, template, nil); err != nil {
+			return err
+		}
+		return s.recordSearchTemplateVersion(ctx, rp, template, model.SearchTemplateActionCreated, userID)
+	})
+	if err != nil {
+		log.WithError(err).Error("Error when create search template")
+		return nil, searchTemplateError(err)
+	}
+	return template, nil
+}
+
+func (s *SearchTemplateService) GetOneSearchTemplate(ctx context.Context, templateID uuid.UUID) (*model.SearchTemplate, error) {
+	log := logger.WithCtx(ctx, "SearchTemplateService.GetOneSearchTemplate")
+
+	template, err := s.repo.GetOneSearchTemplate(ctx, templateID, nil)
+	if err != nil {
+		log.WithError(err).WithField("TemplateID", templateID).Error("Error when call func GetOneSearchTemplate")
+		return nil, searchTemplateError(err)
+	}
+	return template, nil
+}
+
+func (s *SearchTemplateService) GetListSearchTemplate(ctx context.Context, req *model.GetListSearchTemplateRequest) (model.GetListSearchTemplateResponse, error) {
+	log := logger.WithCtx(ctx, "SearchTemplateService.GetListSearchTemplate")
+
+	rs, err := s.repo.GetListSearchTemplate(ctx, req, nil)
+	if err != nil {
+		log.WithError(err).Error("Error when call func GetListSearchTemplate")
+		return rs, ginext.NewError(http.StatusInternalServerError, utils.MessageError()[http.StatusInternalServerError])
+	}
+	return rs, nil
+}
+
+// UpdateSearchTemplate stores the template as a new version, the stored
+// scripts of the previous versions are kept for clients pinning them
+func (s *SearchTemplateService) UpdateSearchTemplate(ctx context.Context, templateID uuid.UUID, req model.SearchTemplateRequest, userID *uuid.UUID) (*model.SearchTemplate, error) {
+	log := logger.WithCtx(ctx, "SearchTemplateService.UpdateSearchTemplate")
+
+	update, err := searchTemplateFromRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var template *model.SearchTemplate
+	err = s.repo.Transaction(ctx, func(rp repo.PGInterface) error {
+		var err error
+		if template, err = rp.LockSearchTemplate(ctx, templateID, nil); err != nil {
+			return err
+		}
+		if err := checkSearchTemplateName(ctx, rp, update.Name, templateID); err != nil {
+			return err
+		}
+		template.Name = update.Name
+		template.Description = update.Description
+		template.Index = update.Index
+		template.Source = update.Source
+		template.Params = update.Params
+		template.Version++
+		if err := rp.UpdateSearchTemplate(ctx, template, nil); err != nil {
+			return err
+		}
+		return s.recordSearchTemplateVersion(ctx, rp, template, model.SearchTemplateActionUpdated, userID)
+	})
+	if err != nil {
+		log.WithError(err).WithField("TemplateID", templateID).Error("Error when update search template")
+		return nil, searchTemplateError(err)
+	}
+	return template, nil
+}
+
+// DeleteSearchTemplate deletes a template and the stored scripts of its
+// versions, its history stays with a last "deleted" version
+func (s *SearchTemplateService) DeleteSearchTemplate(ctx context.Context, templateID uuid.UUID, userID *uuid.UUID) error {
+	log := logger.WithCtx(ctx, "SearchTemplateService.DeleteSearchTemplate")
+
+	var template *model.SearchTemplate
+	err := s.repo.Transaction(ctx, func(rp repo.PGInterface) error {
+		var err error
+		if template, err = rp.LockSearchTemplate(ctx, templateID, nil); err != nil {
+			return err
+		}
+		if err := rp.DeleteSearchTemplate(ctx, template, nil); err != nil {
+			return err
+		}
+		return rp.CreateSearchTemplateVersion(ctx, &model.SearchTemplateVersion{
+			TemplateID: template.ID,
+			Version:    template.Version + 1,
+			Action:     model.SearchTemplateActionDeleted,
+			Name:       template.Name,
+			Index:      template.Index,
+			Source:     template.Source,
+			Params:     template.Params,
+			UserID:     userID,
+		}, nil)
+	})
+	if err != nil {
+		log.WithError(err).WithField("TemplateID", templateID).Error("Error when delete search template")
+		return searchTemplateError(err)
+	}
+
+	// a script left behind is never run again, the template is gone
+	for version := 1; version <= template.Version; version++ {
+		err := s.client.DeleteSearchTemplate(ctx, searchTemplateScriptID(templateID, version))
+		if err != nil && !errors.Is(err, es.ErrNotFound) {
+			log.WithError(err).WithField("TemplateID", templateID).Warnf("failed to delete stored script of version %d", version)
+		}
+	}
+	return nil
+}
+
+// GetListSearchTemplateVersion lists the versions of a template, latest
+// first, the template may have been deleted since
+func (s *SearchTemplateService) GetListSearchTemplateVersion(ctx context.Context, templateID uuid.UUID) ([]model.SearchTemplateVersion, error) {
+	log := logger.WithCtx(ctx, "SearchTemplateService.GetListSearchTemplateVersion")
+
+	versions, err := s.repo.GetListSearchTemplateVersion(ctx, templateID, nil)
+	if err != nil {
+		log.WithError(err).WithField("TemplateID", templateID).Error("Error when call func GetListSearchTemplateVersion")
+		return nil, ginext.NewError(http.StatusInternalServerError, utils.MessageError()[http.StatusInternalServerError])
+	}
+	if len(versions) == 0 {
+		return nil, ginext.NewError(http.StatusNotFound, utils.MessageError()[http.StatusNotFound])
+	}
+	return versions, nil
+}
+
+// RenderSearchTemplate shows the search a template runs with req.Params,
+// defaults applied, without running it
+func (s *SearchTemplateService) RenderSearchTemplate(ctx context.Context, templateID uuid.UUID, req model.RunSearchTemplateRequest) (*model.RenderedSearchTemplate, error) {
+	version, err := s.templateVersion(ctx, templateID, req.Version)
+	if err != nil {
+		return nil, err
+	}
+	params, err := validateTemplateParams(version.Params, req.Params)
+	if err != nil {
+		return nil, err
+	}
+
+	rendered, err := s.client.RenderSearchTemplate(ctx, searchTemplateScriptID(templateID, version.Version), params)
+	if err != nil {
+		return nil, searchTemplateError(fmt.Errorf("failed to render search template: %w", err))
+	}
+	return &model.RenderedSearchTemplate{
+		TemplateID: templateID,
+		Version:    version.Version,
+		Index:      version.Index,
+		Params:     params,
+		Rendered:   rendered,
+	}, nil
+}
+
+// RunSearchTemplate runs a template against its index with req.Params
+func (s *SearchTemplateService) RunSearchTemplate(ctx context.Context, templateID uuid.UUID, req model.RunSearchTemplateRequest) (*es.SearchResult, error) {
+	version, err := s.templateVersion(ctx, templateID, req.Version)
+	if err != nil {
+		return nil, err
+	}
+	params, err := validateTemplateParams(version.Params, req.Params)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := s.client.SearchTemplate(ctx, version.Index, searchTemplateScriptID(templateID, version.Version), params)
+	if err != nil {
+		return nil, searchTemplateError(fmt.Errorf("search template failed: %w", err))
+	}
+	return result, nil
+}
+
+// templateVersion returns the version of a template to run, the latest
+// when version is 0. Versions of a deleted template can't be run.
+func (s *SearchTemplateService) templateVersion(ctx context.Context, templateID uuid.UUID, version int) (*model.SearchTemplateVersion, error) {
+	log := logger.WithCtx(ctx, "SearchTemplateService.templateVersion")
+
+	template, err := s.repo.GetOneSearchTemplate(ctx, templateID, nil)
+	if err != nil {
+		log.WithError(err).WithField("TemplateID", templateID).Error("Error when call func GetOneSearchTemplate")
+		return nil, searchTemplateError(err)
+	}
+	if version == 0 || version == template.Version {
+		return &model.SearchTemplateVersion{
+			TemplateID: template.ID,
+			Version:    template.Version,
+			Name:       template.Name,
+			Index:      template.Index,
+			Source:     template.Source,
+			Params:     template.Params,
+		}, nil
+	}
+	if version < 0 || version > template.Version {
+		return nil, ginext.NewError(http.StatusBadRequest, fmt.Sprintf("template has no version %d", version))
+	}
+
+	v, err := s.repo.GetSearchTemplateVersion(ctx, templateID, version, nil)
+	if err != nil {
+		log.WithError(err).WithField("TemplateID", templateID).Error("Error when call func GetSearchTemplateVersion")
+		return nil, searchTemplateError(err)
+	}
+	return v, nil
+}
+
+// recordSearchTemplateVersion stores the current version of a template in
+// Elasticsearch and its history. It runs last in the transaction, a script
+// Elasticsearch rejects rolls the change back.
+func (s *SearchTemplateService) recordSearchTemplateVersion(ctx context.Context, rp repo.PGInterface, template *model.SearchTemplate, action string, userID *uuid.UUID) error {
+	err := rp.CreateSearchTemplateVersion(ctx, &model.SearchTemplateVersion{
+		TemplateID: template.ID,
+		Version:    template.Version,
+		Action:     action,
+		Name:       template.Name,
+		Index:      template.Index,
+		Source:     template.Source,
+		Params:     template.Params,
+		UserID:     userID,
+	}, nil)
+	if err != nil {
+		return err
+	}
+	if err := s.client.PutSearchTemplate(ctx, searchTemplateScriptID(template.ID, template.Version), template.Source); err != nil {
+		return fmt.Errorf("failed to store search template: %w", err)
+	}
+	return nil
+}
+
+func checkSearchTemplateName(ctx context.Context, rp repo.PGInterface, name string, templateID uuid.UUID) error {
+	count, err := rp.CountSearchTemplateByName(ctx, name, templateID, nil)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return ginext.NewError(http.StatusConflict, fmt.Sprintf("search template %q already exists", name))
+	}
+	return nil
+}
+
+// searchTemplateError turns a repo or Elasticsearch error into an api error.
+// Elasticsearch rejecting a template or its rendering is a bad request, the
+// client can read why.
+func searchTemplateError(err error) error {
+	var apiErr ginext.ApiError
+	if errors.As(err, &apiErr) {
+		return err
+	}
+	var esErr *es.ResponseError
+	if errors.As(err, &esErr) && esErr.StatusCode == http.StatusBadRequest {
+		return ginext.NewError(http.StatusBadRequest, fmt.Sprintf("%s: %s", esErr.Type, esErr.Reason))
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ginext.NewError(http.StatusNotFound, utils.MessageError()[http.StatusNotFound])
+	}
+	if errors.Is(err, es.ErrSearchUnavailable) || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	return ginext.NewError(http.StatusInternalServerError, utils.MessageError()[http.StatusInternalServerError])
+}
+
+// searchTemplateFromRequest validates a template: its index, its parameter
+// schema and that the source only uses declared parameters
+func searchTemplateFromRequest(req model.SearchTemplateRequest) (*model.SearchTemplate, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, ginext.NewError(http.StatusBadRequest, "name is required")
+	}
+	index := strings.TrimSpace(req.Index)
+	if !templateIndices[index] {
+		return nil, ginext.NewError(http.StatusBadRequest, fmt.Sprintf("a template can't search index %q", req.Index))
+	}
+	if strings.TrimSpace(req.Source) == "" {
+		return nil, ginext.NewError(http.StatusBadRequest, "source is required")
+	}
+
+	declared := make(map[string]model.TemplateParam, len(req.Params))
+	for _, p := range req.Params {
+		if !templateParamName.MatchString(p.Name) {
+			return nil, ginext.NewError(http.StatusBadRequest, fmt.Sprintf("invalid parameter name %q", p.Name))
+		}
+		if _, ok := declared[p.Name]; ok {
+			return nil, ginext.NewError(http.StatusBadRequest, fmt.Sprintf("parameter %s is declared twice", p.Name))
+		}
+		if !templateParamType(p.Type) {
+			return nil, ginext.NewError(http.StatusBadRequest, fmt.Sprintf("parameter %s has an unknown type %q", p.Name, p.Type))
+		}
+		if p.Default != nil && !templateParamMatches(p.Type, p.Default) {
+			return nil, ginext.NewError(http.StatusBadRequest, fmt.Sprintf("default of parameter %s is not of type %s", p.Name, p.Type))
+		}
+		declared[p.Name] = p
+	}
+	if err := checkTemplateSource(req.Source, declared); err != nil {
+		return nil, err
+	}
+
+	params := req.Params
+	if params == nil {
+		params = model.TemplateParams{}
+	}
+	return &model.SearchTemplate{
+		Name:        name,
+		Description: req.Description,
+		Index:       index,
+		Source:      req.Source,
+		Params:      params,
+	}, nil
+}
+
+// checkTemplateSource rejects the mustache tags naming an undeclared
+// parameter. Inside a section of an array or object parameter the tags name
+// fields of its items and are not checked.
+func checkTemplateSource(source string, declared map[string]model.TemplateParam) error {
+	undeclared := func(name string) bool {
+		root, _, _ := strings.Cut(name, ".")
+		_, ok := declared[root]
+		return !ok
+	}
+
+	var sections []string
+	nested := 0
+	for _, m := range mustacheTag.FindAllStringSubmatch(source, -1) {
+		kind, name := m[1], m[2]
+		switch {
+		case kind == "/":
+			if len(sections) == 0 || sections[len(sections)-1] != name {
+				return ginext.NewError(http.StatusBadRequest, fmt.Sprintf("unexpected closing tag {{/%s}}", name))
+			}
+			sections = sections[:len(sections)-1]
+			if p, ok := declared[name]; ok && (p.Type == model.TemplateParamArray || p.Type == model.TemplateParamObject) {
+				nested--
+			}
+			continue
+		case mustacheHelpers[name]:
+		case nested == 0 && undeclared(name):
+			return ginext.NewError(http.StatusBadRequest, fmt.Sprintf("source uses undeclared parameter %s", name))
+		}
+		if kind == "#" || kind == "^" {
+			sections = append(sections, name)
+			if p, ok := declared[name]; ok && (p.Type == model.TemplateParamArray || p.Type == model.TemplateParamObject) {
+				nested++
+			}
+		}
+	}
+	if len(sections) > 0 {
+		return ginext.NewError(http.StatusBadRequest, fmt.Sprintf("section {{#%s}} is not closed", sections[len(sections)-1]))
+	}
+
+	for _, m := range mustacheHelperArg.FindAllStringSubmatch(source, -1) {
+		if undeclared(m[1]) {
+			return ginext.NewError(http.StatusBadRequest, fmt.Sprintf("source uses undeclared parameter %s", m[1]))
+		}
+	}
+	return nil
+}
+
+// validateTemplateParams checks params against the schema of a template and
+// fills in the defaults
+func validateTemplateParams(schema model.TemplateParams, params map[string]interface{}) (map[string]interface{}, error) {
+	declared := make(map[string]bool, len(schema))
+	out := make(map[string]interface{}, len(schema))
+	for _, p := range schema {
+		declared[p.Name] = true
+		value, ok := params[p.Name]
+		if !ok || value == nil {
+			if p.Required {
+				return nil, ginext.NewError(http.StatusBadRequest, fmt.Sprintf("parameter %s is required", p.Name))
+			}
+			if p.Default != nil {
+				out[p.Name] = p.Default
+			}
+			continue
+		}
+		if !templateParamMatches(p.Type, value) {
+			return nil, ginext.NewError(http.StatusBadRequest, fmt.Sprintf("parameter %s must be of type %s", p.Name, p.Type))
+		}
+		out[p.Name] = value
+	}
+	for name := range params {
+		if !declared[name] {
+			return nil, ginext.NewError(http.StatusBadRequest, fmt.Sprintf("unknown parameter %s", name))
+		}
+	}
+	return out, nil
+}
+
+func templateParamType(t string) bool {
+	switch t {
+	case model.TemplateParamString, model.TemplateParamNumber, model.TemplateParamInteger,
+		model.TemplateParamBoolean, model.TemplateParamArray, model.TemplateParamObject:
+		return true
+	}
+	return false
+}
+
+// templateParamMatches checks a value decoded from json against a parameter type
+func templateParamMatches(t string, value interface{}) bool {
+	switch t {
+	case model.TemplateParamString:
+		_, ok := value.(string)
+		return ok
+	case model.TemplateParamNumber:
+		_, ok := value.(float64)
+		return ok
+	case model.TemplateParamInteger:
+		n, ok := value.(float64)
+		return ok && n == math.Trunc(n)
+	case model.TemplateParamBoolean:
+		_, ok := value.(bool)
+		return ok
+	case model.TemplateParamArray:
+		_, ok := value.([]interface{})
+		return ok
+	case model.TemplateParamObject:
+		_, ok := value.(map[string]interface{})
+		return ok
+	}
+	return false
+}
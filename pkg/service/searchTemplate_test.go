@@ -0,0 +1,230 @@
+package service
+
+import (
+	"business/pkg/model"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestCheckTemplateSource(t *testing.T) {
+	declared := map[string]model.TemplateParam{
+		"keyword": {Name: "keyword", Type: model.TemplateParamString},
+		"size":    {Name: "size", Type: model.TemplateParamInteger},
+		"active":  {Name: "active", Type: model.TemplateParamBoolean},
+		"types":   {Name: "types", Type: model.TemplateParamArray},
+		"range":   {Name: "range", Type: model.TemplateParamObject},
+	}
+
+	tests := []struct {
+		name    string
+		source  string
+		wantErr string
+	}{
+		{
+			name:   "declared parameters",
+			source: `{"query": {"match": {"name": "{{keyword}}"}}, "size": {{size}}}`,
+		},
+		{
+			name:   "toJson of a declared parameter",
+			source: `{"terms": {"type": {{#toJson}}types{{/toJson}}}}`,
+		},
+		{
+			name:    "toJson of an undeclared parameter",
+			source:  `{"terms": {"type": {{#toJson}}x{{/toJson}}}}`,
+			wantErr: "source uses undeclared parameter x",
+		},
+		{
+			name:   "join of a declared parameter",
+			source: `{"match": {"type": "{{#join}}types{{/join}}"}}`,
+		},
+		{
+			name:   "url helper",
+			source: `{"term": {"link": "{{#url}}{{keyword}}{{/url}}"}}`,
+		},
+		{
+			name:   "triple tag",
+			source: `{"match": {"name": "{{{keyword}}}"}}`,
+		},
+		{
+			name:    "triple tag of an undeclared parameter",
+			source:  `{"match": {"name": "{{{triple}}}"}}`,
+			wantErr: "source uses undeclared parameter triple",
+		},
+		{
+			name:   "field of an object parameter",
+			source: `{"range": {"rating": {"gte": {{range.gte}}}}}`,
+		},
+		{
+			name:    "field of an undeclared parameter",
+			source:  `{"range": {"rating": {"gte": {{limits.gte}}}}}`,
+			wantErr: "source uses undeclared parameter limits.gte",
+		},
+		{
+			name:   "tags inside an array section name its items",
+			source: `[{{#types}}{"term": {"type": "{{value}}"}}{{/types}}]`,
+		},
+		{
+			name:    "tags inside a scalar section are checked",
+			source:  `{{#active}}{"term": {"status": "{{status}}"}}{{/active}}`,
+			wantErr: "source uses undeclared parameter status",
+		},
+		{
+			name:   "inverted section",
+			source: `{{^active}}{"match_all": {}}{{/active}}`,
+		},
+		{
+			name:    "undeclared section",
+			source:  `{{#filters}}{}{{/filters}}`,
+			wantErr: "source uses undeclared parameter filters",
+		},
+		{
+			name:    "unclosed section",
+			source:  `{{#active}}{"match_all": {}}`,
+			wantErr: "section {{#active}} is not closed",
+		},
+		{
+			name:    "unclosed inner section",
+			source:  `{{#active}}{{#types}}{{value}}{{/active}}`,
+			wantErr: "unexpected closing tag {{/active}}",
+		},
+		{
+			name:    "mismatched sections",
+			source:  `{{#active}}{{^size}}{}{{/active}}{{/size}}`,
+			wantErr: "unexpected closing tag {{/active}}",
+		},
+		{
+			name:    "closing tag without a section",
+			source:  `{"size": {{size}}}{{/size}}`,
+			wantErr: "unexpected closing tag {{/size}}",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkTemplateSource(tt.source, declared)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("err = %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("got no error, want %q", tt.wantErr)
+			}
+			if err.Error() != tt.wantErr {
+				t.Errorf("err = %q, want %q", err, tt.wantErr)
+			}
+			if code := statusOf(err); code != http.StatusBadRequest {
+				t.Errorf("status = %d, want 400", code)
+			}
+		})
+	}
+}
+
+func TestValidateTemplateParams(t *testing.T) {
+	schema := model.TemplateParams{
+		{Name: "keyword", Type: model.TemplateParamString, Required: true},
+		{Name: "size", Type: model.TemplateParamInteger, Default: float64(10)},
+		{Name: "min_rating", Type: model.TemplateParamNumber},
+		{Name: "active", Type: model.TemplateParamBoolean, Default: true},
+		{Name: "types", Type: model.TemplateParamArray},
+		{Name: "range", Type: model.TemplateParamObject},
+	}
+
+	tests := []struct {
+		name    string
+		params  map[string]interface{}
+		want    map[string]interface{}
+		wantErr string
+	}{
+		{
+			name:   "defaults filled in",
+			params: map[string]interface{}{"keyword": "cafe"},
+			want:   map[string]interface{}{"keyword": "cafe", "size": float64(10), "active": true},
+		},
+		{
+			name: "given values kept",
+			params: map[string]interface{}{
+				"keyword":    "cafe",
+				"size":       float64(20),
+				"min_rating": 3.5,
+				"active":     false,
+				"types":      []interface{}{"bar"},
+				"range":      map[string]interface{}{"gte": float64(1)},
+			},
+			want: map[string]interface{}{
+				"keyword":    "cafe",
+				"size":       float64(20),
+				"min_rating": 3.5,
+				"active":     false,
+				"types":      []interface{}{"bar"},
+				"range":      map[string]interface{}{"gte": float64(1)},
+			},
+		},
+		{
+			name:   "null takes the default",
+			params: map[string]interface{}{"keyword": "cafe", "size": nil},
+			want:   map[string]interface{}{"keyword": "cafe", "size": float64(10), "active": true},
+		},
+		{
+			name:   "whole number is a number",
+			params: map[string]interface{}{"keyword": "cafe", "min_rating": float64(4)},
+			want:   map[string]interface{}{"keyword": "cafe", "size": float64(10), "min_rating": float64(4), "active": true},
+		},
+		{
+			name:    "required parameter missing",
+			params:  map[string]interface{}{"size": float64(5)},
+			wantErr: "parameter keyword is required",
+		},
+		{
+			name:    "required parameter null",
+			params:  map[string]interface{}{"keyword": nil},
+			wantErr: "parameter keyword is required",
+		},
+		{
+			name:    "float for an integer",
+			params:  map[string]interface{}{"keyword": "cafe", "size": 2.5},
+			wantErr: "parameter size must be of type integer",
+		},
+		{
+			name:    "string for a number",
+			params:  map[string]interface{}{"keyword": "cafe", "min_rating": "4"},
+			wantErr: "parameter min_rating must be of type number",
+		},
+		{
+			name:    "object for an array",
+			params:  map[string]interface{}{"keyword": "cafe", "types": map[string]interface{}{}},
+			wantErr: "parameter types must be of type array",
+		},
+		{
+			name:    "unknown parameter",
+			params:  map[string]interface{}{"keyword": "cafe", "sort": "name"},
+			wantErr: "unknown parameter sort",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := validateTemplateParams(schema, tt.params)
+			if tt.wantErr != "" {
+				if err == nil {
+					t.Fatalf("got %v, want error %q", got, tt.wantErr)
+				}
+				if err.Error() != tt.wantErr {
+					t.Errorf("err = %q, want %q", err, tt.wantErr)
+				}
+				if code := statusOf(err); code != http.StatusBadRequest {
+					t.Errorf("status = %d, want 400", code)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("err = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
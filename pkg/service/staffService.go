@@ -15,11 +15,12 @@ import (
 )
 
 type StaffService struct {
-	repo repo.PGInterface
+	repo   repo.PGInterface
+	outbox OutboxNotifier
 }
 
-func NewStaffService(repo repo.PGInterface) StaffInterface {
-	return &StaffService{repo: repo}
+func NewStaffService(repo repo.PGInterface, outbox OutboxNotifier) StaffInterface {
+	return &StaffService{repo: repo, outbox: outbox}
 }
 
 type StaffInterface interface {
@@ -36,9 +37,17 @@ func (s *StaffService) CreateStaff(ctx context.Context, req model.StaffRequest)
 
 	copier.Copy(Staff,req)
 
-	if err:= s.repo.CreateStaff(ctx,Staff,nil); err!=nil {
+	err := s.repo.Transaction(ctx, func(rp repo.PGInterface) error {
+		if err := rp.CreateStaff(ctx, Staff, nil); err != nil {
+			return err
+		}
+		return recordOutboxEvent(ctx, rp, model.OutboxAggregateStaff, Staff.ID, model.OutboxEventCreated,
+			model.OutboxPayload{Staff: Staff})
+	})
+	if err != nil {
 		return nil, err
 	}
+	s.outbox.Notify()
 	
 	return Staff, nil
 }
@@ -52,11 +61,25 @@ func (s *StaffService) 	UpdateStaff(ctx context.Context, req model.StaffRequest)
 		return nil, ginext.NewError(http.StatusForbidden, "Error get Business for updating")
 	}
 
+	previousBusinessID := Staff.BusinessID
 	copier.Copy(Staff,req)
 
-	if err:= s.repo.UpdateStaff(ctx, Staff, nil); err!=nil {
+	payload := model.OutboxPayload{Staff: Staff}
+	if previousBusinessID != Staff.BusinessID {
+		// the staff moved, its former business changed too
+		payload.PreviousBusinessID = &previousBusinessID
+	}
+	err = s.repo.Transaction(ctx, func(rp repo.PGInterface) error {
+		if err := rp.UpdateStaff(ctx, Staff, nil); err != nil {
+			return err
+		}
+		return recordOutboxEvent(ctx, rp, model.OutboxAggregateStaff, Staff.ID, model.OutboxEventUpdated, payload)
+	})
+	if err != nil {
 		log.WithError(err).WithField("req",req).Error("Error update Staff")
+		return nil, ginext.NewError(http.StatusInternalServerError, utils.MessageError()[http.StatusInternalServerError])
 	}
+	s.outbox.Notify()
 	return Staff, nil
 }
 
@@ -116,10 +139,18 @@ func (s *StaffService) DeleteStaff(ctx context.Context, StaffID uuid.UUID) error
 		return ginext.NewError(http.StatusNotFound, err.Error())
 	}
 
-	if err = s.repo.DeleteStaff(ctx, Staff, nil); err != nil {
+	err = s.repo.Transaction(ctx, func(rp repo.PGInterface) error {
+		if err := rp.DeleteStaff(ctx, Staff, nil); err != nil {
+			return err
+		}
+		return recordOutboxEvent(ctx, rp, model.OutboxAggregateStaff, Staff.ID, model.OutboxEventDeleted,
+			model.OutboxPayload{Staff: Staff})
+	})
+	if err != nil {
 		log.WithError(err).WithField("Staff", Staff).Error("Error when call func DeleteBusiness")
 		return ginext.NewError(http.StatusInternalServerError, utils.MessageError()[http.StatusInternalServerError])
 	}
+	s.outbox.Notify()
 
 	return nil
 }
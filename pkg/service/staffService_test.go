@@ -0,0 +1,82 @@
+package service
+
+import (
+	"business/pkg/model"
+	"business/pkg/repo"
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// txRepo runs transactions on the fake repo, failing with err when it is set
+type txRepo struct {
+	*fakeRepo
+	err     error
+	updated []model.Staff
+}
+
+func (r *txRepo) Transaction(ctx context.Context, f func(rp repo.PGInterface) error) error {
+	if r.err != nil {
+		return r.err
+	}
+	return f(r)
+}
+
+func (r *txRepo) UpdateStaff(ctx context.Context, staff *model.Staff, tx *gorm.DB) error {
+	r.updated = append(r.updated, *staff)
+	return nil
+}
+
+func (r *txRepo) CreateOutboxEvent(ctx context.Context, event *model.OutboxEvent, tx *gorm.DB) error {
+	r.events = append(r.events, *event)
+	return nil
+}
+
+type countingNotifier struct{ notified int }
+
+func (n *countingNotifier) Notify() { n.notified++ }
+
+func TestUpdateStaff(t *testing.T) {
+	staff := model.Staff{ID: uuid.MustParse(fmtID(11)), Username: "lan", Email: "lan@example.com", Role: "staff", BusinessID: uuid.MustParse(fmtID(1))}
+	req := model.StaffRequest{ID: staff.ID, Username: "lan", Email: "lan@example.com", Role: "admin", BusinessID: staff.BusinessID}
+
+	tests := []struct {
+		name      string
+		txErr     error
+		wantCode  int
+		wantSaved bool
+	}{
+		{name: "saved with its outbox event", wantSaved: true},
+		{name: "transaction failed", txErr: errors.New("connection reset"), wantCode: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rp := &txRepo{fakeRepo: &fakeRepo{staffs: []model.Staff{staff}}, err: tt.txErr}
+			notifier := &countingNotifier{}
+
+			got, err := NewStaffService(rp, notifier).UpdateStaff(context.Background(), req)
+			if tt.wantCode != 0 {
+				if code := statusOf(err); code != tt.wantCode || got != nil {
+					t.Fatalf("got %+v with status %d, want nil and %d (err %v)", got, code, tt.wantCode, err)
+				}
+			} else if err != nil {
+				t.Fatalf("err = %v", err)
+			} else if got.Role != req.Role {
+				t.Errorf("role = %q, want %q", got.Role, req.Role)
+			}
+
+			saved := len(rp.updated) == 1 && len(rp.events) == 1
+			if saved != tt.wantSaved {
+				t.Errorf("saved = %v, want %v", saved, tt.wantSaved)
+			}
+			if notified := notifier.notified == 1; notified != tt.wantSaved {
+				t.Errorf("outbox notified %d times, want it only after a save", notifier.notified)
+			}
+		})
+	}
+}
@@ -0,0 +1,227 @@
+package service
+
+import (
+	"business/pkg/model"
+	"business/pkg/repo"
+	"business/pkg/utils"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"gitlab.com/goxp/cloud0/ginext"
+	"gitlab.com/goxp/cloud0/logger"
+	"gorm.io/gorm"
+)
+
+type SynonymService struct {
+	repo   repo.PGInterface
+	outbox OutboxNotifier
+}
+
+func NewSynonymService(repo repo.PGInterface, outbox OutboxNotifier) SynonymInterface {
+	return &SynonymService{repo: repo, outbox: outbox}
+}
+
+// SynonymInterface manages the synonym sets, every change is kept as a
+// version by the user who made it and pushed to Elasticsearch through the outbox
+type SynonymInterface interface {
+	CreateSynonymSet(ctx context.Context, req model.SynonymSetRequest, userID *uuid.UUID) (*model.SynonymSet, error)
+	GetOneSynonymSet(ctx context.Context, setID uuid.UUID) (*model.SynonymSet, error)
+	GetListSynonymSet(ctx context.Context, req *model.GetListSynonymSetRequest) (model.GetListSynonymSetResponse, error)
+	UpdateSynonymSet(ctx context.Context, setID uuid.UUID, req model.SynonymSetRequest, userID *uuid.UUID) (*model.SynonymSet, error)
+	DeleteSynonymSet(ctx context.Context, setID uuid.UUID, userID *uuid.UUID) error
+	GetListSynonymSetVersion(ctx context.Context, setID uuid.UUID) ([]model.SynonymSetVersion, error)
+}
+
+func (s *SynonymService) CreateSynonymSet(ctx context.Context, req model.SynonymSetRequest, userID *uuid.UUID) (*model.SynonymSet, error) {
+	log := logger.WithCtx(ctx, "SynonymService.CreateSynonymSet")
+
+	name, synonyms, err := validateSynonymSet(req)
+	if err != nil {
+		return nil, err
+	}
+	set := &model.SynonymSet{Name: name, Synonyms: synonyms, Version: 1}
+
+	err = s.repo.Transaction(ctx, func(rp repo.PGInterface) error {
+		if err := checkSynonymSetName(ctx, rp, name, uuid.Nil); err != nil {
+			return err
+		}
+		if err := rp.CreateSynonymSet(ctx, set, nil); err != nil {
+			return err
+		}
+		return recordSynonymChange(ctx, rp, set, model.SynonymActionCreated, model.OutboxEventCreated, userID)
+	})
+	if err != nil {
+		log.WithError(err).Error("Error when create synonym set")
+		return nil, synonymError(err)
+	}
+	s.outbox.Notify()
+	return set, nil
+}
+
+func (s *SynonymService) GetOneSynonymSet(ctx context.Context, setID uuid.UUID) (*model.SynonymSet, error) {
+	log := logger.WithCtx(ctx, "SynonymService.GetOneSynonymSet")
+
+	set, err := s.repo.GetOneSynonymSet(ctx, setID, nil)
+	if err != nil {
+		log.WithError(err).WithField("SetID", setID).Error("Error when call func GetOneSynonymSet")
+		return nil, synonymError(err)
+	}
+	return set, nil
+}
+
+func (s *SynonymService) GetListSynonymSet(ctx context.Context, req *model.GetListSynonymSetRequest) (model.GetListSynonymSetResponse, error) {
+	log := logger.WithCtx(ctx, "SynonymService.GetListSynonymSet")
+
+	rs, err := s.repo.GetListSynonymSet(ctx, req, nil)
+	if err != nil {
+		log.WithError(err).Error("Error when call func GetListSynonymSet")
+		return rs, ginext.NewError(http.StatusInternalServerError, utils.MessageError()[http.StatusInternalServerError])
+	}
+	return rs, nil
+}
+
+func (s *SynonymService) UpdateSynonymSet(ctx context.Context, setID uuid.UUID, req model.SynonymSetRequest, userID *uuid.UUID) (*model.SynonymSet, error) {
+	log := logger.WithCtx(ctx, "SynonymService.UpdateSynonymSet")
+
+	name, synonyms, err := validateSynonymSet(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var set *model.SynonymSet
+	err = s.repo.Transaction(ctx, func(rp repo.PGInterface) error {
+		var err error
+		if set, err = rp.LockSynonymSet(ctx, setID, nil); err != nil {
+			return err
+		}
+		if err := checkSynonymSetName(ctx, rp, name, setID); err != nil {
+			return err
+		}
+		set.Name, set.Synonyms = name, synonyms
+		set.Version++
+		if err := rp.UpdateSynonymSet(ctx, set, nil); err != nil {
+			return err
+		}
+		return recordSynonymChange(ctx, rp, set, model.SynonymActionUpdated, model.OutboxEventUpdated, userID)
+	})
+	if err != nil {
+		log.WithError(err).WithField("SetID", setID).Error("Error when update synonym set")
+		return nil, synonymError(err)
+	}
+	s.outbox.Notify()
+	return set, nil
+}
+
+// DeleteSynonymSet deletes a set, its history stays with a last "deleted" version
+func (s *SynonymService) DeleteSynonymSet(ctx context.Context, setID uuid.UUID, userID *uuid.UUID) error {
+	log := logger.WithCtx(ctx, "SynonymService.DeleteSynonymSet")
+
+	err := s.repo.Transaction(ctx, func(rp repo.PGInterface) error {
+		set, err := rp.LockSynonymSet(ctx, setID, nil)
+		if err != nil {
+			return err
+		}
+		if err := rp.DeleteSynonymSet(ctx, set, nil); err != nil {
+			return err
+		}
+		set.Version++
+		return recordSynonymChange(ctx, rp, set, model.SynonymActionDeleted, model.OutboxEventDeleted, userID)
+	})
+	if err != nil {
+		log.WithError(err).WithField("SetID", setID).Error("Error when delete synonym set")
+		return synonymError(err)
+	}
+	s.outbox.Notify()
+	return nil
+}
+
+// GetListSynonymSetVersion lists the versions of a set, latest first, the
+// set may have been deleted since
+func (s *SynonymService) GetListSynonymSetVersion(ctx context.Context, setID uuid.UUID) ([]model.SynonymSetVersion, error) {
+	log := logger.WithCtx(ctx, "SynonymService.GetListSynonymSetVersion")
+
+	versions, err := s.repo.GetListSynonymSetVersion(ctx, setID, nil)
+	if err != nil {
+		log.WithError(err).WithField("SetID", setID).Error("Error when call func GetListSynonymSetVersion")
+		return nil, ginext.NewError(http.StatusInternalServerError, utils.MessageError()[http.StatusInternalServerError])
+	}
+	if len(versions) == 0 {
+		return nil, ginext.NewError(http.StatusNotFound, utils.MessageError()[http.StatusNotFound])
+	}
+	return versions, nil
+}
+
+// recordSynonymChange writes the version of a set after a change and the
+// outbox event that pushes the synonyms to Elasticsearch
+func recordSynonymChange(ctx context.Context, rp repo.PGInterface, set *model.SynonymSet, action, eventType string, userID *uuid.UUID) error {
+	err := rp.CreateSynonymSetVersion(ctx, &model.SynonymSetVersion{
+		SetID:    set.ID,
+		Version:  set.Version,
+		Action:   action,
+		Name:     set.Name,
+		Synonyms: set.Synonyms,
+		UserID:   userID,
+	}, nil)
+	if err != nil {
+		return err
+	}
+	return recordOutboxEvent(ctx, rp, model.OutboxAggregateSynonym, set.ID, eventType, model.OutboxPayload{SynonymSet: set})
+}
+
+func checkSynonymSetName(ctx context.Context, rp repo.PGInterface, name string, setID uuid.UUID) error {
+	count, err := rp.CountSynonymSetByName(ctx, name, setID, nil)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return ginext.NewError(http.StatusConflict, fmt.Sprintf("synonym set %q already exists", name))
+	}
+	return nil
+}
+
+// synonymError turns a repo error into an api error, api errors are
+// returned as they are
+func synonymError(err error) error {
+	var apiErr ginext.ApiError
+	if errors.As(err, &apiErr) {
+		return err
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ginext.NewError(http.StatusNotFound, utils.MessageError()[http.StatusNotFound])
+	}
+	return ginext.NewError(http.StatusInternalServerError, utils.MessageError()[http.StatusInternalServerError])
+}
+
+// validateSynonymSet checks a rule in the Solr format Elasticsearch takes:
+// comma separated words, optionally mapped with "=>" to other words. The
+// words are returned trimmed, with one space after each comma.
+func validateSynonymSet(req model.SynonymSetRequest) (string, string, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return "", "", ginext.NewError(http.StatusBadRequest, "name is required")
+	}
+
+	sides := strings.Split(req.Synonyms, "=>")
+	if len(sides) > 2 {
+		return "", "", ginext.NewError(http.StatusBadRequest, `synonyms can't have more than one "=>"`)
+	}
+	for i, side := range sides {
+		terms := strings.Split(side, ",")
+		for j, term := range terms {
+			term = utils.RemoveSpace(term)
+			if term == "" {
+				return "", "", ginext.NewError(http.StatusBadRequest, "synonyms can't have an empty word")
+			}
+			terms[j] = term
+		}
+		sides[i] = strings.Join(terms, ", ")
+	}
+	if len(sides) == 1 && !strings.Contains(sides[0], ",") {
+		return "", "", ginext.NewError(http.StatusBadRequest, "synonyms need at least two words")
+	}
+	return name, strings.Join(sides, " => "), nil
+}
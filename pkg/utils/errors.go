@@ -16,6 +16,7 @@ func LoadMessageError() {
 	messageError[http.StatusGatewayTimeout] = "Yêu cầu vượt quá thời gian cho phép"
 	messageError[http.StatusConflict] = "Dữ liệu đầu vào của bạn đã xung đột với một dữ liệu khác"
 	messageError[http.StatusTooManyRequests] = "Thao tác quá nhanh"
+	messageError[http.StatusServiceUnavailable] = "Dịch vụ tạm thời không khả dụng, vui lòng thử lại sau"
 	//messageError[http.StatusOK] = "Successfully"
 	//messageError[http.StatusForbidden] = "Something when wrong, Your request has been rejected"
 	//messageError[http.StatusInternalServerError] = "Internal server error"
@@ -26,6 +27,7 @@ func LoadMessageError() {
 	//messageError[http.StatusGatewayTimeout] = "Gateway time out"
 	//messageError[http.StatusConflict] = "Your input has been conflict with another data"
 	//messageError[http.StatusTooManyRequests] = "Too many request"
+	//messageError[http.StatusServiceUnavailable] = "Service temporarily unavailable, please try again later"
 }
 
 func MessageError() map[int]string {